@@ -63,14 +63,57 @@ func Run(args []string) (int, error) {
 			return cmd.NewGet(
 				newUI(),
 				gocmd,
-				&deptfile.Workspace{},
+				&deptfile.Workspace{Stderr: stderr},
 			), nil
 		},
 		"remove": func() (cli.Command, error) {
 			return cmd.NewRemove(
 				newUI(),
 				gocmd,
-				&deptfile.Workspace{},
+				&deptfile.Workspace{Stderr: stderr},
+				toolcacher,
+			), nil
+		},
+		"tidy": func() (cli.Command, error) {
+			return cmd.NewTidy(
+				newUI(),
+				gocmd,
+				&deptfile.Workspace{Stderr: stderr},
+			), nil
+		},
+		"fmt": func() (cli.Command, error) {
+			return cmd.NewFmt(
+				newUI(),
+				&deptfile.Workspace{Stderr: stderr},
+			), nil
+		},
+		"export": func() (cli.Command, error) {
+			return cmd.NewExport(
+				newUI(),
+				&deptfile.Workspace{
+					DoNotUpdate: true,
+					Stderr:      stderr,
+				},
+			), nil
+		},
+		"import": func() (cli.Command, error) {
+			return cmd.NewImport(
+				newUI(),
+				gocmd,
+				&deptfile.Workspace{Stderr: stderr},
+			), nil
+		},
+		"edit": func() (cli.Command, error) {
+			return cmd.NewEdit(
+				newUI(),
+				&deptfile.Workspace{Stderr: stderr},
+			), nil
+		},
+		"rename": func() (cli.Command, error) {
+			return cmd.NewRename(
+				newUI(),
+				&deptfile.Workspace{Stderr: stderr},
+				toolcacher,
 			), nil
 		},
 		"build": func() (cli.Command, error) {
@@ -79,6 +122,7 @@ func Run(args []string) (int, error) {
 				gocmd,
 				&deptfile.Workspace{
 					DoNotUpdate: true,
+					Stderr:      stderr,
 				},
 				toolcacher,
 			), nil
@@ -88,9 +132,35 @@ func Run(args []string) (int, error) {
 				newUI(),
 				&deptfile.Workspace{
 					DoNotUpdate: true,
+					Stderr:      stderr,
 				},
 			), nil
 		},
+		"outdated": func() (cli.Command, error) {
+			return cmd.NewOutdated(
+				newUI(),
+				gocmd,
+				&deptfile.Workspace{
+					DoNotUpdate: true,
+					Stderr:      stderr,
+				},
+			), nil
+		},
+		"history": func() (cli.Command, error) {
+			return cmd.NewHistory(
+				newUI(),
+				&deptfile.Workspace{
+					DoNotUpdate: true,
+					Stderr:      stderr,
+				},
+			), nil
+		},
+		"undo": func() (cli.Command, error) {
+			return cmd.NewUndo(
+				newUI(),
+				&deptfile.Workspace{Stderr: stderr},
+			), nil
+		},
 		"clean": func() (cli.Command, error) {
 			return cmd.NewClean(
 				newUI(),
@@ -110,13 +180,14 @@ func Run(args []string) (int, error) {
 	f := flag.NewFlagSet("main", flag.ExitOnError)
 	verbose := f.Bool("v", false, "verbose output")
 	version := f.Bool("version", false, "show version")
+	chdir := f.String("C", "", "change to dir before running the command")
 
 	app.HelpWriter = stdout
 	app.HelpFunc = func(c map[string]cli.CommandFactory) string {
 		// Replace basic help header by new one
 		// because it doesn't show optional flags.
 		header := fmt.Sprintf(
-			"Usage: %s [-v] [--version] [--help] <command> [<args>]",
+			"Usage: %s [-v] [-C dir] [--version] [--help] <command> [<args>]",
 			app.Name)
 		s := cli.BasicHelpFunc(app.Name)(c)
 		i := strings.Index(s, "\n")
@@ -133,6 +204,11 @@ func Run(args []string) (int, error) {
 		fmt.Fprintf(app.HelpWriter, "%s v%s\n", appName, appVersion)
 		return 0, nil
 	}
+	if *chdir != "" {
+		if err := os.Chdir(*chdir); err != nil {
+			return 1, errors.Wrapf(err, "failed to change the current dir to %s", *chdir)
+		}
+	}
 
 	app.Args = f.Args()
 
@@ -152,6 +228,7 @@ func Run(args []string) (int, error) {
 				newUI(),
 				&deptfile.Workspace{
 					DoNotUpdate: true,
+					Stderr:      stderr,
 				},
 				toolcacher,
 			), nil
@@ -1,7 +1,11 @@
 package app
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -70,3 +74,39 @@ func Test_isLimitedGoModSupport(t *testing.T) {
 		})
 	}
 }
+
+func TestRunMergesLocalDeptfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"gotool.mod":       "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n",
+		"gotool.local.mod": "module tools\n\nrequire github.com/ktr0731/salias v0.1.0\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get the current dir: %s", err)
+	}
+	defer os.Chdir(cwd)
+	var out bytes.Buffer
+	oldStdout := stdout
+	stdout = &out
+	defer func() { stdout = oldStdout }()
+
+	code, err := Run([]string{"-C", dir, "list"})
+	if err != nil || code != 0 {
+		t.Fatalf("Run must return 0 without an error, but got %d (err = %v)", code, err)
+	}
+	expected := "github.com/ktr0731/salias salias v0.1.0 (gotool.local.mod)\ngithub.com/ktr0731/evans evans v0.1.0\n"
+	if actual := out.String(); actual != expected {
+		t.Errorf("expected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
@@ -5,36 +5,35 @@ import (
 	"flag"
 	"fmt"
 	"path/filepath"
+	"sort"
 
 	"github.com/ktr0731/dept/deptfile"
-	"github.com/ktr0731/dept/fileutil"
 	"github.com/ktr0731/dept/gocmd"
-	"github.com/ktr0731/dept/logger"
+	"github.com/ktr0731/dept/manager"
 	"github.com/ktr0731/dept/toolcacher"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
-	"golang.org/x/sync/errgroup"
 )
 
 type buildFlagSet struct {
 	*flag.FlagSet
 
 	outputDir string
+	all       bool
 }
 
 func newBuildFlagSet() *buildFlagSet {
 	bf := &buildFlagSet{FlagSet: flag.NewFlagSet("build", flag.ExitOnError)}
 	bf.StringVar(&bf.outputDir, "d", "", "Output dir to store built Go tools")
+	bf.BoolVar(&bf.all, "all", false, "Build tools of all gotool.mod under the current dir. With -d, tools of each gotool.mod are stored in the sub dir of the same relative path")
 	return bf
 }
 
 // buildCommand builds Go tools based on gotool.mod.
 type buildCommand struct {
-	f          *buildFlagSet
-	ui         cli.Ui
-	gocmd      gocmd.Command
-	workspace  deptfile.Workspacer
-	toolcacher toolcacher.Cacher
+	f       *buildFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
 }
 
 func (c *buildCommand) UI() cli.Ui {
@@ -42,7 +41,7 @@ func (c *buildCommand) UI() cli.Ui {
 }
 
 func (c *buildCommand) Help() string {
-	return fmt.Sprintf("Usage: dept build\n\n%s", FlagUsage(c.f.FlagSet, false))
+	return fmt.Sprintf("Usage: dept build [-d <dir>] [-all]\n\n%s", FlagUsage(c.f.FlagSet, false))
 }
 
 func (c *buildCommand) Synopsis() string {
@@ -55,50 +54,40 @@ func (c *buildCommand) Run(args []string) int {
 		return 1
 	}
 
-	outputDir := c.f.outputDir
-	if outputDir != "" {
-		outputDir, _ = filepath.Abs(outputDir)
-	}
-
 	return run(c, func(ctx context.Context) error {
-		err := c.workspace.Do(func(projRoot string, df *deptfile.File) error {
-			outputDir = resolveOutputDir(projRoot, outputDir)
-
-			requires := make([]string, 0, len(df.Require))
-			tools := []*tool{}
-			for _, r := range df.Require {
-				forToolsWithOutputName(r, func(path, outputName string) bool {
-					requires = append(requires, path)
-					tools = append(tools, &tool{Path: path, Name: outputName, Version: r.Version})
-					return true
-				})
+		if !c.f.all {
+			_, err := c.manager.Build(ctx, manager.OutputDir(c.f.outputDir))
+			return err
+		}
+		root, err := filepath.Abs(c.manager.Dir)
+		if err != nil {
+			return errors.Wrap(err, "failed to get the abs path of the root dir")
+		}
+		return runAll(ctx, c.ui, c.manager, func(ctx context.Context, m *manager.Manager) error {
+			// Tools of each project are stored in the sub dir which has the same relative path as the project
+			// so that tools which have the same name don't overwrite each other.
+			outputDir := c.f.outputDir
+			if outputDir != "" {
+				rel, err := filepath.Rel(root, m.Dir)
+				if err != nil {
+					return errors.Wrapf(err, "failed to get the relative path of %s", m.Dir)
+				}
+				outputDir = filepath.Join(outputDir, rel)
 			}
-
-			eg, ctx := errgroup.WithContext(ctx)
-			for _, t := range tools {
-				t := t
-				eg.Go(func() error {
-					cachePath, err := c.toolcacher.Get(ctx, t.Path, t.Version)
-					if err != nil {
-						return errors.Wrapf(err, "failed to get cache of %s", t.Path)
-					}
-					var outputName string
-					if t.Name != "" {
-						outputName = t.Name
-					} else {
-						outputName = filepath.Base(t.Path)
-					}
-					binPath := filepath.Join(outputDir, outputName)
-					logger.Printf("copy %s from %s to %s", t.Path, cachePath, binPath)
-					if err := fileutil.Copy(binPath, cachePath); err != nil {
-						return errors.Wrapf(err, "failed to copy %s from %s to %s", t.Path, cachePath, binPath)
-					}
-					return nil
-				})
+			binPaths, err := m.Build(ctx, manager.OutputDir(outputDir))
+			if err != nil {
+				return err
+			}
+			names := make([]string, 0, len(binPaths))
+			for name := range binPaths {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				c.ui.Output(fmt.Sprintf("  %s => %s", name, binPaths[name]))
 			}
-			return eg.Wait()
+			return nil
 		})
-		return err
 	})
 }
 
@@ -110,10 +99,12 @@ func NewBuild(
 	toolcacher toolcacher.Cacher,
 ) cli.Command {
 	return &buildCommand{
-		f:          newBuildFlagSet(),
-		ui:         ui,
-		gocmd:      gocmd,
-		workspace:  workspace,
-		toolcacher: toolcacher,
+		f:  newBuildFlagSet(),
+		ui: ui,
+		manager: &manager.Manager{
+			GoCommand:  gocmd,
+			ToolCacher: toolcacher,
+			Workspace:  workspace,
+		},
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -17,7 +18,7 @@ func TestBuildRun(t *testing.T) {
 	t.Run("Run returns 1 because gotool.mod is not found", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
 				return deptfile.ErrNotFound
 			},
 		}
@@ -74,12 +75,12 @@ func TestBuildRun(t *testing.T) {
 
 				mockUI := newMockUI()
 				mockGoCMD := &gocmd.CommandMock{
-					ModDownloadFunc: func(ctx context.Context) error { return nil },
+					ModDownloadFunc: func(ctx context.Context, dir string) error { return nil },
 				}
 				mockWorkspace := &deptfile.WorkspacerMock{
-					DoFunc: func(f func(projectDir string, df *deptfile.File) error) error {
+					DoFunc: func(f func(projectDir, workDir string, df *deptfile.File) error, opts ...deptfile.Option) error {
 						df := &deptfile.File{Require: c.loadedTools}
-						return f("", df)
+						return f("", "", df)
 					},
 				}
 
@@ -89,7 +90,7 @@ func TestBuildRun(t *testing.T) {
 				}
 				defer f.Close()
 				mockToolCacher := &toolcacher.CacherMock{
-					GetFunc: func(ctx context.Context, pkgName string, version string) (string, error) {
+					GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
 						return f.Name(), nil
 					},
 				}
@@ -106,4 +107,50 @@ func TestBuildRun(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("Run with -all and -d stores tools of each gotool.mod in each sub dir", func(t *testing.T) {
+		const data = "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n"
+		root, cleanup := setupDeptfileDir(t, data)
+		defer cleanup()
+		sub := filepath.Join(root, "services", "api")
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatalf("failed to create a dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(sub, deptfile.FileName), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get the current dir: %s", err)
+		}
+		if err := os.Chdir(root); err != nil {
+			t.Fatalf("failed to change the current dir: %s", err)
+		}
+		defer os.Chdir(cwd)
+
+		f, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err, "failed to create a temp file")
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		mockToolCacher := &toolcacher.CacherMock{
+			GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
+				return f.Name(), nil
+			},
+		}
+
+		mockUI := newMockUI()
+		if code := cmd.NewBuild(mockUI, &gocmd.CommandMock{}, nil, mockToolCacher).Run([]string{"-all", "-d", "bin"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		for _, p := range []string{
+			filepath.Join(root, "bin", "evans"),
+			filepath.Join(root, "bin", "services", "api", "evans"),
+		} {
+			if _, err := os.Stat(p); err != nil {
+				t.Errorf("%s must be built, but got '%s'", p, err)
+			}
+		}
+	})
 }
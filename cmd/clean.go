@@ -3,14 +3,15 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/ktr0731/dept/manager"
 	"github.com/ktr0731/dept/toolcacher"
 	"github.com/mitchellh/cli"
 )
 
 // cleanCommand cleans up all cached binaries.
 type cleanCommand struct {
-	ui         cli.Ui
-	toolcacher toolcacher.Cacher
+	ui      cli.Ui
+	manager *manager.Manager
 }
 
 func (c *cleanCommand) UI() cli.Ui {
@@ -26,7 +27,7 @@ func (c *cleanCommand) Synopsis() string {
 }
 
 func (c *cleanCommand) Run(args []string) int {
-	return run(c, c.toolcacher.Clear)
+	return run(c, c.manager.Clean)
 }
 
 // NewClean returns an initialized cleanCommand instance.
@@ -35,7 +36,7 @@ func NewClean(
 	toolcacher toolcacher.Cacher,
 ) cli.Command {
 	return &cleanCommand{
-		ui:         ui,
-		toolcacher: toolcacher,
+		ui:      ui,
+		manager: &manager.Manager{ToolCacher: toolcacher},
 	}
 }
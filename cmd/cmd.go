@@ -4,14 +4,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net/url"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/ktr0731/dept/deptfile"
 	"github.com/ktr0731/dept/logger"
+	"github.com/ktr0731/dept/manager"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
 )
@@ -46,11 +46,11 @@ func run(c command, f func(ctx context.Context) error) int {
 	}
 
 	switch errors.Cause(err) {
-	case errShowHelp:
+	case errShowHelp, manager.ErrNoTargets:
 		c.UI().Output(c.Help())
 	case context.Canceled:
 		logger.Println("command successfully finished")
-	case deptfile.ErrNotFound:
+	case manager.ErrNotFound:
 		c.UI().Error("deptfile missing. please do 'dept init'")
 	default:
 		c.UI().Error(err.Error())
@@ -99,66 +99,27 @@ func ExcludeFlagUsage(f *flag.FlagSet, repeatable bool, exclude []string) string
 	return FlagUsage(newOne, repeatable)
 }
 
-// normalizePath normalizes a passed path.
-// It trims any schemes like 'https://'.
-// Also, it parse the module version from path.
-// For example,
-//   'https://github.com/ktr0731/itunes-cli/itunes@latest'
-//     Repo = 'github.com/ktr0731/itunes-cli/itunes'
-//     Ver  = '@latest'
-func normalizePath(path string) (repo, ver string, err error) {
-	var u *url.URL
-	u, err = url.Parse(path)
+// runAll runs f for each gotool.mod under the current dir.
+// Results of each gotool.mod are shown after the header which has its path.
+// Even if f fails, runAll continues for remaining ones, then returns all errors.
+func runAll(ctx context.Context, ui cli.Ui, m *manager.Manager, f func(ctx context.Context, m *manager.Manager) error) error {
+	projects, err := m.Projects(ctx)
 	if err != nil {
-		return "", "", errors.Wrap(err, "invalid Repo passed")
+		return err
 	}
-
-	path = filepath.Clean(u.Host + u.Path)
-
-	if i := strings.Index(path, "@"); i != -1 {
-		repo = path[:i]
-		ver = path[i+1:]
-	} else {
-		repo = path
-	}
-	return
-}
-
-// forTools iterates r, then pass each tool path to f.
-// Note that, version are ignored.
-func forTools(r *deptfile.Require, f func(path string) bool) {
-	forToolsWithOutputName(r, func(path, _ string) bool {
-		return f(path)
-	})
-}
-
-// forToolsWithOutputName is like forTools, but also pass outputName of each tool.
-// If out is empty, it means out is the same as filepath.Base(path).
-func forToolsWithOutputName(r *deptfile.Require, f func(path, outputName string) bool) {
-	if r == nil {
-		return
+	if len(projects) == 0 {
+		return errors.Errorf("no %s found", deptfile.FileName)
 	}
-	for _, t := range r.ToolPaths {
-		p := r.Path
-		if t.Path != "/" {
-			p += t.Path
+	var result *multierror.Error
+	for i, p := range projects {
+		header := p.File + ":"
+		if i > 0 {
+			header = "\n" + header
 		}
-		if ok := f(p, t.Name); !ok {
-			return
+		ui.Output(header)
+		if err := f(ctx, p.Manager); err != nil {
+			result = multierror.Append(result, errors.Wrap(err, p.File))
 		}
 	}
-}
-
-func resolveOutputDir(projRoot, flagVal string) string {
-	if flagVal != "" {
-		return flagVal
-	}
-	if b := os.Getenv("GOBIN"); b != "" {
-		logger.Printf("output dir = $GOBIN (%s)", b)
-		return b
-	} else {
-		p := filepath.Join(projRoot, "_tools")
-		logger.Printf("output dir = %s", p)
-		return p
-	}
+	return result.ErrorOrNil()
 }
@@ -44,3 +44,28 @@ func TestExcludeFlagUsage(t *testing.T) {
 		t.Errorf("ExcludeFlagUsage must hide flag 'bar', but found:\n%s", actual)
 	}
 }
+
+func TestRunWithUnknownFlag(t *testing.T) {
+	cases := map[string]func(ui cli.Ui) cli.Command{
+		"remove":   func(ui cli.Ui) cli.Command { return cmd.NewRemove(ui, nil, nil, nil) },
+		"tidy":     func(ui cli.Ui) cli.Command { return cmd.NewTidy(ui, nil, nil) },
+		"fmt":      func(ui cli.Ui) cli.Command { return cmd.NewFmt(ui, nil) },
+		"export":   func(ui cli.Ui) cli.Command { return cmd.NewExport(ui, nil) },
+		"import":   func(ui cli.Ui) cli.Command { return cmd.NewImport(ui, nil, nil) },
+		"outdated": func(ui cli.Ui) cli.Command { return cmd.NewOutdated(ui, nil, nil) },
+		"rename":   func(ui cli.Ui) cli.Command { return cmd.NewRename(ui, nil, nil) },
+		"undo":     func(ui cli.Ui) cli.Command { return cmd.NewUndo(ui, nil) },
+	}
+	for name, newCommand := range cases {
+		newCommand := newCommand
+		t.Run(name, func(t *testing.T) {
+			mockUI := newMockUI()
+			if code := newCommand(mockUI).Run([]string{"-unknown"}); code != 1 {
+				t.Errorf("Run must return 1, but got %d", code)
+			}
+			if eout := mockUI.ErrorWriter().String(); !strings.Contains(eout, "flag provided but not defined: -unknown") {
+				t.Errorf("Run must show the error of the flag, but got '%s'", eout)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+)
+
+// editOp is an edit operation which is passed by a flag of editCommand.
+type editOp func(df *deptfile.File) error
+
+// editFlagValue appends an edit operation to ops each time the flag is passed.
+// Operations are applied in the order of flags.
+type editFlagValue struct {
+	ops   *[]editOp
+	parse func(v string) (editOp, error)
+}
+
+func (v *editFlagValue) Set(s string) error {
+	op, err := v.parse(s)
+	if err != nil {
+		return err
+	}
+	*v.ops = append(*v.ops, op)
+	return nil
+}
+
+func (v *editFlagValue) String() string {
+	return ""
+}
+
+type editFlagSet struct {
+	*flag.FlagSet
+
+	ops  []editOp
+	fmt  bool
+	json bool
+}
+
+func newEditFlagSet() *editFlagSet {
+	ef := &editFlagSet{FlagSet: flag.NewFlagSet("edit", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	ef.SetOutput(ioutil.Discard)
+	ef.Var(&editFlagValue{ops: &ef.ops, parse: parseRenameOp}, "rename", "Rename the output name of a tool (old=new)")
+	ef.Var(&editFlagValue{ops: &ef.ops, parse: parseDropToolOp}, "droptool", "Drop a tool by the import path")
+	ef.Var(&editFlagValue{ops: &ef.ops, parse: parseAddToolOp}, "addtool", "Add a tool (module[:/path][@name])")
+	ef.Var(&editFlagValue{ops: &ef.ops, parse: parseSetVersionOp}, "setversion", "Set the version of a module (module@version)")
+	ef.BoolVar(&ef.fmt, "fmt", false, "Reformat gotool.mod without any other changes")
+	ef.BoolVar(&ef.json, "json", false, "Print the edited gotool.mod in JSON instead of writing it back")
+	return ef
+}
+
+func parseRenameOp(s string) (editOp, error) {
+	i := strings.Index(s, "=")
+	if i == -1 {
+		return nil, errors.Errorf("invalid -rename=%s: must be old=new", s)
+	}
+	oldName, newName := s[:i], s[i+1:]
+	return func(df *deptfile.File) error {
+		return df.RenameTool(oldName, newName)
+	}, nil
+}
+
+func parseDropToolOp(s string) (editOp, error) {
+	return func(df *deptfile.File) error {
+		return df.RemoveTool(s)
+	}, nil
+}
+
+func parseAddToolOp(s string) (editOp, error) {
+	var name string
+	if i := strings.LastIndex(s, "@"); i != -1 {
+		s, name = s[:i], s[i+1:]
+		if name == "" {
+			return nil, errors.Errorf("invalid -addtool=%s@: empty tool name", s)
+		}
+	}
+	modPath, toolPath := s, "/"
+	if i := strings.Index(s, ":"); i != -1 {
+		modPath, toolPath = s[:i], s[i+1:]
+	}
+	return func(df *deptfile.File) error {
+		return df.AddTool(modPath, toolPath, name)
+	}, nil
+}
+
+func parseSetVersionOp(s string) (editOp, error) {
+	i := strings.LastIndex(s, "@")
+	if i == -1 {
+		return nil, errors.Errorf("invalid -setversion=%s: must be module@version", s)
+	}
+	modPath, version := s[:i], s[i+1:]
+	return func(df *deptfile.File) error {
+		return df.SetVersion(modPath, version)
+	}, nil
+}
+
+// editCommand edits gotool.mod without running the go command.
+// See manager.Manager.Edit for details.
+type editCommand struct {
+	f       *editFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *editCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var editHelpTmpl = `Usage: dept edit [flags]
+
+edit provides a command-line interface for editing %s,
+for use primarily by tools or scripts.
+edit reads only %s and never runs the go command, so it works offline.
+Dependencies of added tools are resolved by the next 'dept get' or 'dept build'.
+
+Edit flags can be repeated. The changes are applied in the order given.
+A new module added by -addtool must be given the version by -setversion.
+-fmt reformats %s without making any other changes.
+-json prints the edited %s in JSON format instead of writing it back.
+
+%s
+Examples:
+
+    $ dept edit -rename golangci-lint=lint
+    $ dept edit -droptool honnef.co/go/tools/cmd/unused
+    $ dept edit -addtool honnef.co/go/tools:/cmd/staticcheck@sc
+    $ dept edit -addtool github.com/mitchellh/gox -setversion github.com/mitchellh/gox@v1.0.1
+    $ dept edit -fmt
+    $ dept edit -json
+`
+
+func (c *editCommand) Help() string {
+	fname := deptfile.FileName
+	return fmt.Sprintf(editHelpTmpl, fname, fname, fname, fname, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *editCommand) Synopsis() string {
+	return fmt.Sprintf("Edit %s from tools or scripts", deptfile.FileName)
+}
+
+func (c *editCommand) Run(args []string) int {
+	desc := strings.TrimSpace("edit " + strings.Join(args, " "))
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if c.f.NArg() != 0 || (len(c.f.ops) == 0 && !c.f.fmt && !c.f.json) {
+			return errShowHelp
+		}
+
+		opts := []manager.EditOption{manager.Description(desc)}
+		if c.f.json {
+			opts = append(opts, manager.DryRun())
+		}
+
+		var edited *deptfile.File
+		err := c.manager.Edit(ctx, func(df *deptfile.File) error {
+			for _, op := range c.f.ops {
+				if err := op(df); err != nil {
+					return err
+				}
+			}
+			edited = df
+			return nil
+		}, opts...)
+		if err != nil {
+			return err
+		}
+
+		if c.f.json {
+			b, err := json.MarshalIndent(edited, "", "\t")
+			if err != nil {
+				return errors.Wrap(err, "failed to encode the deptfile to JSON")
+			}
+			c.ui.Output(string(b))
+		}
+		return nil
+	})
+}
+
+// NewEdit returns an initialized editCommand instance.
+func NewEdit(
+	ui cli.Ui,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &editCommand{
+		f:       newEditFlagSet(),
+		ui:      ui,
+		manager: &manager.Manager{Workspace: workspace},
+	}
+}
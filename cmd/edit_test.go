@@ -0,0 +1,159 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+const editTestDeptfile = `module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2
+)
+`
+
+func TestEditRun(t *testing.T) {
+	t.Run("Run edits gotool.mod in the order of flags", func(t *testing.T) {
+		cases := map[string]struct {
+			args     []string
+			expected []string
+		}{
+			"rename": {
+				args:     []string{"-rename", "staticcheck=sc"},
+				expected: []string{"honnef.co/go/tools:/cmd/staticcheck@sc,/cmd/unused v0.0.2"},
+			},
+			"droptool": {
+				args:     []string{"-droptool", "github.com/ktr0731/evans"},
+				expected: []string{"honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2"},
+			},
+			"addtool and setversion": {
+				args: []string{"-addtool", "github.com/mitchellh/gox@gx", "-setversion", "github.com/mitchellh/gox@v1.0.1"},
+				expected: []string{
+					"github.com/ktr0731/evans v0.1.0",
+					"github.com/mitchellh/gox@gx v1.0.1",
+				},
+			},
+			"add and rename": {
+				args:     []string{"-addtool", "honnef.co/go/tools:/cmd/keyify", "-rename", "keyify=kf"},
+				expected: []string{"honnef.co/go/tools:/cmd/keyify@kf,/cmd/unused,/cmd/staticcheck v0.0.2"},
+			},
+			"fmt": {
+				args:     []string{"-fmt"},
+				expected: []string{"github.com/ktr0731/evans v0.1.0"},
+			},
+		}
+
+		for name, c := range cases {
+			c := c
+			t.Run(name, func(t *testing.T) {
+				dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+				defer cleanup()
+
+				mockUI := newMockUI()
+				cmd := cmd.NewEdit(mockUI, &deptfile.Workspace{SourcePath: dir})
+				if code := cmd.Run(c.args); code != 0 {
+					t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+				}
+
+				b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+				if err != nil {
+					t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+				}
+				for _, e := range c.expected {
+					if !strings.Contains(string(b), e) {
+						t.Errorf("%s must contain '%s', but got:\n%s", deptfile.FileName, e, string(b))
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Run prints JSON without updating gotool.mod", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		cmd := cmd.NewEdit(mockUI, &deptfile.Workspace{SourcePath: dir})
+		if code := cmd.Run([]string{"-json", "-rename", "evans=ev"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if out := mockUI.Writer().String(); !strings.Contains(out, `"Name": "ev"`) {
+			t.Errorf("Run must print the edited deptfile, but got:\n%s", out)
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if string(b) != editTestDeptfile {
+			t.Errorf("%s must not be updated, but got:\n%s", deptfile.FileName, string(b))
+		}
+	})
+
+	t.Run("Run returns code 1", func(t *testing.T) {
+		cases := map[string][]string{
+			"no flags":              nil,
+			"invalid rename syntax": {"-rename", "evans"},
+			"conflicted names":      {"-rename", "evans=unused"},
+			"tool not found":        {"-droptool", "github.com/ktr0731/salias"},
+			"no version":            {"-addtool", "github.com/mitchellh/gox"},
+		}
+
+		for name, args := range cases {
+			args := args
+			t.Run(name, func(t *testing.T) {
+				dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+				defer cleanup()
+
+				mockUI := newMockUI()
+				cmd := cmd.NewEdit(mockUI, &deptfile.Workspace{SourcePath: dir})
+				if code := cmd.Run(args); code != 1 {
+					t.Errorf("Run must return 1, but got %d", code)
+				}
+				b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+				if err != nil {
+					t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+				}
+				if string(b) != editTestDeptfile {
+					t.Errorf("%s must not be updated, but got:\n%s", deptfile.FileName, string(b))
+				}
+			})
+		}
+	})
+
+	t.Run("Run reports malformed tool entries", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, "module tools\n\nrequire github.com/ktr0731/evans@ v0.1.0\n")
+		defer cleanup()
+
+		mockUI := newMockUI()
+		cmd := cmd.NewEdit(mockUI, &deptfile.Workspace{SourcePath: dir})
+		if code := cmd.Run([]string{"-fmt"}); code != 1 {
+			t.Errorf("Run must return 1, but got %d", code)
+		}
+		if out := mockUI.ErrorWriter().String(); !strings.Contains(out, "gotool.mod:3:34: empty tool name") {
+			t.Errorf("Run must report the position of the malformed entry, but got:\n%s", out)
+		}
+	})
+}
+
+// setupDeptfileDir creates a temp dir which has gotool.mod with data.
+// Callers must call returned function (cleanup function) at end of each test.
+func setupDeptfileDir(t *testing.T, data string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, deptfile.FileName), []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
 	"github.com/ktr0731/dept/toolcacher"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
@@ -19,10 +19,9 @@ var (
 )
 
 type execCommand struct {
-	args       []string
-	ui         cli.Ui
-	workspace  deptfile.Workspacer
-	toolcacher toolcacher.Cacher
+	args    []string
+	ui      cli.Ui
+	manager *manager.Manager
 }
 
 func (c *execCommand) UI() cli.Ui {
@@ -63,30 +62,12 @@ func (c *execCommand) Run([]string) int {
 
 		toolName := args[0]
 
-		var toolPkgName, toolVersion string
-		var cachePath string
-		err := c.workspace.Do(func(projRoot string, df *deptfile.File) error {
-			for _, r := range df.Require {
-				forToolsWithOutputName(r, func(path, outputName string) bool {
-					if outputName == toolName || (outputName == "" && filepath.Base(path) == toolName) {
-						toolPkgName = path
-						toolVersion = r.Version
-						return false
-					}
-					return true
-				})
-			}
-			if toolPkgName == "" || toolVersion == "" {
-				return errors.Errorf(`command '%s' is not in %s (available tools can be see 'dept list -f "{{ .Name }}"')`, toolName, deptfile.FileName)
-			}
-
-			var err error
-			cachePath, err = c.toolcacher.Get(ctx, toolPkgName, toolVersion)
-			if err != nil {
-				return errors.Wrap(err, "failed to get a cached tool path")
-			}
-			return nil
-		})
+		// Replace the process by the tool instead of manager.Manager.Exec
+		// to pass signals and the exit code through.
+		cachePath, err := c.manager.Resolve(ctx, toolName)
+		if _, ok := errors.Cause(err).(*manager.ToolNotFoundErr); ok {
+			return errors.Errorf(`command '%s' is not in %s (available tools can be see 'dept list -f "{{ .Name }}"')`, toolName, deptfile.FileName)
+		}
 		if err != nil {
 			return err
 		}
@@ -106,9 +87,11 @@ func NewExec(
 	toolcacher toolcacher.Cacher,
 ) cli.Command {
 	return &execCommand{
-		args:       args,
-		ui:         ui,
-		workspace:  workspace,
-		toolcacher: toolcacher,
+		args: args,
+		ui:   ui,
+		manager: &manager.Manager{
+			ToolCacher: toolcacher,
+			Workspace:  workspace,
+		},
 	}
 }
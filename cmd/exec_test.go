@@ -51,9 +51,9 @@ func TestExecRun(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				mockUI := newMockUI()
 				mockWorkspace := &deptfile.WorkspacerMock{
-					DoFunc: func(f func(projectDir string, df *deptfile.File) error) error {
+					DoFunc: func(f func(projectDir, workDir string, df *deptfile.File) error, opts ...deptfile.Option) error {
 						df := &deptfile.File{Require: []*deptfile.Require{c.loadedTool}}
-						return f("", df)
+						return f("", "", df)
 					},
 				}
 				cmd := cmd.NewExec([]string{"salias"}, mockUI, mockWorkspace, nil)
@@ -112,13 +112,13 @@ func TestExecRun(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				mockUI := newMockUI()
 				mockWorkspace := &deptfile.WorkspacerMock{
-					DoFunc: func(f func(projectDir string, df *deptfile.File) error) error {
+					DoFunc: func(f func(projectDir, workDir string, df *deptfile.File) error, opts ...deptfile.Option) error {
 						df := &deptfile.File{Require: []*deptfile.Require{c.loadedTool}}
-						return f("", df)
+						return f("", "", df)
 					},
 				}
 				mockToolcacher := &toolcacher.CacherMock{
-					GetFunc: func(ctx context.Context, pkgName string, version string) (string, error) {
+					GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
 						return "", nil
 					},
 				}
@@ -140,12 +140,12 @@ func TestExecRun(t *testing.T) {
 
 	t.Run("syscall.Exec must be called in first working dir", func(t *testing.T) {
 		mockUI := newMockUI()
-		cwd1 := filepath.Join(getWorkDir(t), "testdata")
+		cwd1 := getWorkDir(t)
 		workspace := &deptfile.Workspace{
-			SourcePath: cwd1,
+			SourcePath: filepath.Join(cwd1, "testdata"),
 		}
 		mockToolcacher := &toolcacher.CacherMock{
-			GetFunc: func(ctx context.Context, pkgName string, version string) (string, error) {
+			GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
 				return "", nil
 			},
 		}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+)
+
+type exportFlagSet struct {
+	*flag.FlagSet
+
+	format    string
+	outputDir string
+	write     bool
+}
+
+func newExportFlagSet() *exportFlagSet {
+	ef := &exportFlagSet{FlagSet: flag.NewFlagSet("export", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	ef.SetOutput(ioutil.Discard)
+	ef.StringVar(&ef.format, "format", manager.ExportGoModTool, "Export format")
+	ef.StringVar(&ef.outputDir, "d", "", "Default dir to install tools by makefile and sh formats (default \"_tools\")")
+	ef.BoolVar(&ef.write, "w", false, "Write the result into the project instead of printing it")
+	return ef
+}
+
+// exportCommand converts gotool.mod into another format.
+// See manager.Manager.Export for details.
+type exportCommand struct {
+	f       *exportFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *exportCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var exportHelpTmpl = `Usage: dept export [-format=<format>] [-d <dir>] [-w]
+
+export converts %s into another format and prints it.
+Available formats are:
+
+    gomod-tool    a go.mod which has tool directives and requirements equivalent to %s.
+                  With -w flag, go.mod and go.sum in the project root are patched instead.
+                  'go tool' doesn't support output names, so renamed tools are warned.
+    makefile      a Makefile fragment which has a target per tool. Each target installs
+                  the tool by 'go install path@version' to $(TOOLS_DIR).
+    sh            a shell script which is equivalent to makefile.
+                  'go install' ignores replace directives, so replaced tools are warned.
+    tools-go      a tools.go which imports all tools with the 'tools' build constraint.
+                  With -w flag, it is written to tools/tools.go, and go.mod and go.sum
+                  in the project root are patched.
+
+%s`
+
+func (c *exportCommand) Help() string {
+	return fmt.Sprintf(exportHelpTmpl, deptfile.FileName, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *exportCommand) Synopsis() string {
+	return fmt.Sprintf("Convert %s into another format", deptfile.FileName)
+}
+
+func (c *exportCommand) Run(args []string) int {
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+
+	opts := []manager.ExportOption{manager.OutputDir(c.f.outputDir)}
+	if c.f.write {
+		opts = append(opts, manager.Write())
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if c.f.NArg() != 0 {
+			return errShowHelp
+		}
+		b, warnings, err := c.manager.Export(ctx, c.f.format, opts...)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			c.ui.Warn(w)
+		}
+		if len(b) != 0 {
+			c.ui.Output(strings.TrimSuffix(string(b), "\n"))
+		}
+		return nil
+	})
+}
+
+// NewExport returns an initialized exportCommand instance.
+func NewExport(
+	ui cli.Ui,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &exportCommand{
+		f:       newExportFlagSet(),
+		ui:      ui,
+		manager: &manager.Manager{Workspace: workspace},
+	}
+}
@@ -0,0 +1,178 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+const exportTestDeptfile = `module tools
+
+require (
+	github.com/ktr0731/evans@ev v0.1.0
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2
+)
+`
+
+func TestExportRun(t *testing.T) {
+	t.Run("Run prints go.mod which has tool directives", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, exportTestDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewExport(mockUI, &deptfile.Workspace{SourcePath: dir, DoNotUpdate: true}).Run([]string{"-format", "gomod-tool"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		out := mockUI.Writer().String()
+		for _, s := range []string{
+			"\tgithub.com/ktr0731/evans v0.1.0\n",
+			"\tgithub.com/urfave/cli v1.20.0 // indirect\n",
+			"\thonnef.co/go/tools/cmd/staticcheck\n",
+			"\thonnef.co/go/tools/cmd/unused\n",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("Run must print '%s', but missing:\n%s", s, out)
+			}
+		}
+		if warn := mockUI.ErrorWriter().String(); !strings.Contains(warn, "github.com/ktr0731/evans is renamed to ev") {
+			t.Errorf("Run must warn the renamed tool, but got '%s'", warn)
+		}
+	})
+
+	t.Run("Run with -w patches go.mod", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, exportTestDeptfile)
+		defer cleanup()
+		gomod := "module example.com/project\n\ngo 1.25.0\n\nrequire honnef.co/go/tools v0.0.1\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+			t.Fatalf("failed to write go.mod: %s", err)
+		}
+
+		mockUI := newMockUI()
+		if code := cmd.NewExport(mockUI, &deptfile.Workspace{SourcePath: dir, DoNotUpdate: true}).Run([]string{"-w"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if out := mockUI.Writer().String(); out != "" {
+			t.Errorf("Run with -w must not print go.mod, but got '%s'", out)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			t.Fatalf("failed to read go.mod: %s", err)
+		}
+		for _, s := range []string{
+			"module example.com/project\n",
+			"\thonnef.co/go/tools v0.0.2\n",
+			"\thonnef.co/go/tools/cmd/unused\n",
+		} {
+			if !strings.Contains(string(b), s) {
+				t.Errorf("go.mod must have '%s', but missing:\n%s", s, string(b))
+			}
+		}
+	})
+
+	t.Run("Run prints a Makefile fragment", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, exportTestDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewExport(mockUI, &deptfile.Workspace{SourcePath: dir, DoNotUpdate: true}).Run([]string{"-format", "makefile", "-d", "bin"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		const expected = `# Code generated by dept export. DO NOT EDIT.
+
+TOOLS_DIR ?= bin
+DEPTFILE ?= gotool.mod
+
+.PHONY: tools
+tools: $(TOOLS_DIR)/ev $(TOOLS_DIR)/staticcheck $(TOOLS_DIR)/unused
+
+$(TOOLS_DIR)/ev: $(DEPTFILE)
+	GOBIN=$(abspath $(TOOLS_DIR)/.ev) go install github.com/ktr0731/evans@v0.1.0
+	cp $(TOOLS_DIR)/.ev/evans $@
+
+$(TOOLS_DIR)/staticcheck: $(DEPTFILE)
+	GOBIN=$(abspath $(dir $@)) go install honnef.co/go/tools/cmd/staticcheck@v0.0.2
+	@touch $@
+
+$(TOOLS_DIR)/unused: $(DEPTFILE)
+	GOBIN=$(abspath $(dir $@)) go install honnef.co/go/tools/cmd/unused@v0.0.2
+	@touch $@
+`
+		if diff := cmp.Diff(expected, mockUI.Writer().String()); diff != "" {
+			t.Errorf("Run printed unexpected Makefile:\n%s", diff)
+		}
+	})
+
+	t.Run("Run prints a shell script", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, exportTestDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewExport(mockUI, &deptfile.Workspace{SourcePath: dir, DoNotUpdate: true}).Run([]string{"-format", "sh"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		out := mockUI.Writer().String()
+		for _, s := range []string{
+			"TOOLS_DIR=\"${TOOLS_DIR:-_tools}\"\n",
+			"GOBIN=\"$GOBIN/.ev\" go install github.com/ktr0731/evans@v0.1.0\nmv \"$GOBIN/.ev/evans\" \"$GOBIN/ev\"\nrmdir \"$GOBIN/.ev\"\n",
+			"go install honnef.co/go/tools/cmd/unused@v0.0.2\n",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("Run must print '%s', but missing:\n%s", s, out)
+			}
+		}
+	})
+
+	t.Run("Run with -w writes tools.go", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, exportTestDeptfile)
+		defer cleanup()
+		gomod := "module example.com/project\n\ngo 1.21\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+			t.Fatalf("failed to write go.mod: %s", err)
+		}
+
+		mockUI := newMockUI()
+		if code := cmd.NewExport(mockUI, &deptfile.Workspace{SourcePath: dir, DoNotUpdate: true}).Run([]string{"-format", "tools-go", "-w"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		const expected = `//go:build tools
+
+package tools
+
+import (
+	_ "github.com/ktr0731/evans"
+	_ "honnef.co/go/tools/cmd/staticcheck"
+	_ "honnef.co/go/tools/cmd/unused"
+)
+`
+		b, err := ioutil.ReadFile(filepath.Join(dir, "tools", "tools.go"))
+		if err != nil {
+			t.Fatalf("failed to read tools.go: %s", err)
+		}
+		if diff := cmp.Diff(expected, string(b)); diff != "" {
+			t.Errorf("Run wrote unexpected tools.go:\n%s", diff)
+		}
+		b, err = ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			t.Fatalf("failed to read go.mod: %s", err)
+		}
+		if s := string(b); !strings.Contains(s, "\thonnef.co/go/tools v0.0.2\n") || strings.Contains(s, "\ntool") {
+			t.Errorf("go.mod must require tool modules without tool directives, but got:\n%s", s)
+		}
+	})
+
+	t.Run("Run returns 1 if the format is unknown", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, exportTestDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewExport(mockUI, &deptfile.Workspace{SourcePath: dir, DoNotUpdate: true}).Run([]string{"-format", "unknown"}); code != 1 {
+			t.Errorf("Run must return 1, but got %d", code)
+		}
+	})
+}
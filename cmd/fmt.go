@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+)
+
+type fmtFlagSet struct {
+	*flag.FlagSet
+
+	list bool
+	diff bool
+}
+
+func newFmtFlagSet() *fmtFlagSet {
+	ff := &fmtFlagSet{FlagSet: flag.NewFlagSet("fmt", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	ff.SetOutput(ioutil.Discard)
+	ff.BoolVar(&ff.list, "l", false, "Print the file name if it is not formatted without updating it")
+	ff.BoolVar(&ff.diff, "d", false, "Print the diff without updating the file")
+	return ff
+}
+
+// fmtCommand formats gotool.mod in the canonical form.
+// See manager.Manager.Fmt for details.
+type fmtCommand struct {
+	f       *fmtFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *fmtCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var fmtHelpTmpl = `Usage: dept fmt
+
+fmt formats %s in the canonical form.
+It merges duplicated module entries and sorts tool paths.
+Comments are kept as it is.
+Like gofmt, -l flag prints the file name and -d flag prints the diff
+if %s is not formatted. Both of them don't update the file.
+
+%s`
+
+func (c *fmtCommand) Help() string {
+	return fmt.Sprintf(fmtHelpTmpl, deptfile.FileName, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *fmtCommand) Synopsis() string {
+	return fmt.Sprintf("Format %s", deptfile.FileName)
+}
+
+func (c *fmtCommand) Run(args []string) int {
+	desc := strings.TrimSpace("fmt " + strings.Join(args, " "))
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+
+	opts := []manager.EditOption{manager.Description(desc)}
+	if c.f.list || c.f.diff {
+		opts = append(opts, manager.DryRun())
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if c.f.NArg() != 0 {
+			return errShowHelp
+		}
+		diff, err := c.manager.Fmt(ctx, opts...)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			return nil
+		}
+		if c.f.list {
+			c.ui.Output(deptfile.FileName)
+		}
+		if c.f.diff {
+			c.ui.Output(strings.TrimSuffix(diff, "\n"))
+		}
+		return nil
+	})
+}
+
+// NewFmt returns an initialized fmtCommand instance.
+func NewFmt(
+	ui cli.Ui,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &fmtCommand{
+		f:       newFmtFlagSet(),
+		ui:      ui,
+		manager: &manager.Manager{Workspace: workspace},
+	}
+}
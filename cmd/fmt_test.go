@@ -0,0 +1,94 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+const unformattedDeptfile = `// tools for development
+module tools
+
+require (
+	// linters
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2 // pinned
+	github.com/ktr0731/evans v0.1.0
+	github.com/urfave/cli v1.20.0 // indirect
+)
+
+require github.com/ktr0731/salias:/ v0.1.0
+`
+
+const formattedDeptfile = `// tools for development
+module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/urfave/cli v1.20.0 // indirect
+	// linters
+	honnef.co/go/tools:/cmd/unused,/cmd/staticcheck v0.0.2 // pinned
+)
+
+require github.com/ktr0731/salias v0.1.0
+`
+
+func TestFmtRun(t *testing.T) {
+	readDeptfile := func(t *testing.T, dir string) string {
+		t.Helper()
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		return string(b)
+	}
+
+	t.Run("Run formats gotool.mod with keeping comments", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, unformattedDeptfile)
+		defer cleanup()
+
+		for i := 0; i < 2; i++ {
+			mockUI := newMockUI()
+			if code := cmd.NewFmt(mockUI, &deptfile.Workspace{SourcePath: dir}).Run(nil); code != 0 {
+				t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+			}
+			if actual := readDeptfile(t, dir); actual != formattedDeptfile {
+				t.Errorf("expected:\n%s\nactual:\n%s", formattedDeptfile, actual)
+			}
+		}
+
+		mockUI := newMockUI()
+		if code := cmd.NewFmt(mockUI, &deptfile.Workspace{SourcePath: dir}).Run([]string{"-l", "-d"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if out := mockUI.Writer().String(); out != "" {
+			t.Errorf("formatted gotool.mod must not be listed, but got '%s'", out)
+		}
+	})
+
+	t.Run("Run with -l and -d doesn't update gotool.mod", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, unformattedDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewFmt(mockUI, &deptfile.Workspace{SourcePath: dir}).Run([]string{"-l", "-d"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		out := mockUI.Writer().String()
+		for _, s := range []string{
+			deptfile.FileName + "\n",
+			"-require github.com/ktr0731/salias:/ v0.1.0",
+			"+require github.com/ktr0731/salias v0.1.0",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("Run must show '%s', but missing:\n%s", s, out)
+			}
+		}
+		if actual := readDeptfile(t, dir); actual != unformattedDeptfile {
+			t.Errorf("%s must not be updated, but got:\n%s", deptfile.FileName, actual)
+		}
+	})
+}
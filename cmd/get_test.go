@@ -17,7 +17,9 @@ import (
 )
 
 func TestGetRun(t *testing.T) {
-	doNothing := func(f func(projectDir string, gomod *deptfile.File) error) error { return f("", nil) }
+	doNothing := func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+		return f("", "", nil)
+	}
 	emptyReader := strings.NewReader("")
 
 	assertBuild := func(t *testing.T, expected *deptfile.Require, cmd *gocmd.CommandMock) {
@@ -56,12 +58,12 @@ func TestGetRun(t *testing.T) {
 	t.Run("Run returns 1 because gotool.mod is not found", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockGoCMD := &gocmd.CommandMock{
-			ListFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 				return emptyReader, nil
 			},
 		}
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
 				return deptfile.ErrNotFound
 			},
 		}
@@ -82,12 +84,12 @@ func TestGetRun(t *testing.T) {
 	t.Run("Run returns 1 because some flags put after args", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockGoCMD := &gocmd.CommandMock{
-			ListFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 				return emptyReader, nil
 			},
 		}
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
 				return deptfile.ErrNotFound
 			},
 		}
@@ -203,20 +205,20 @@ func TestGetRun(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				mockUI := newMockUI()
 				mockGoCMD := &gocmd.CommandMock{
-					GetFunc: func(ctx context.Context, pkgs ...string) error {
+					GetFunc: func(ctx context.Context, dir string, pkgs ...string) error {
 						return nil
 					},
-					BuildFunc: func(ctx context.Context, pkgs ...string) error {
+					BuildFunc: func(ctx context.Context, dir string, pkgs ...string) error {
 						return nil
 					},
-					ListFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+					ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 						return strings.NewReader(c.root), nil
 					},
 				}
 				mockWorkspace := &deptfile.WorkspacerMock{
-					DoFunc: func(f func(projectDir string, df *deptfile.File) error) error {
+					DoFunc: func(f func(projectDir, workDir string, df *deptfile.File) error, opts ...deptfile.Option) error {
 						df := &deptfile.File{Require: c.loadedTools}
-						if err := f("", df); err != nil {
+						if err := f("", "", df); err != nil {
 							return err
 						}
 
@@ -257,16 +259,65 @@ func TestGetRun(t *testing.T) {
 		}
 	})
 
+	t.Run("Run shows changes without building tools in dry-run mode", func(t *testing.T) {
+		mockUI := newMockUI()
+		mockGoCMD := &gocmd.CommandMock{
+			GetFunc: func(ctx context.Context, dir string, pkgs ...string) error {
+				return nil
+			},
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+				return strings.NewReader("github.com/ktr0731/evans"), nil
+			},
+		}
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, df *deptfile.File) error, opts ...deptfile.Option) error {
+				o := deptfile.NewOptions(opts...)
+				if !o.DryRun {
+					t.Error("DryRun option must be passed")
+				}
+				if err := f("", "", &deptfile.File{}); err != nil {
+					return err
+				}
+				o.OnChange(&deptfile.Changes{
+					Tools: []*deptfile.Change{{Path: "github.com/ktr0731/evans", NewVersion: "v0.1.0"}},
+					Indirect: []*deptfile.Change{
+						{Path: "github.com/foo/bar", OldVersion: "v0.1.0", NewVersion: "v0.2.0", RequiredBy: []string{"github.com/ktr0731/evans"}},
+					},
+				})
+				return nil
+			},
+		}
+
+		cmd := cmd.NewGet(mockUI, mockGoCMD, mockWorkspace)
+		code := cmd.Run([]string{"-dry-run", "github.com/ktr0731/evans"})
+		if code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+
+		if n := len(mockGoCMD.BuildCalls()); n != 0 {
+			t.Errorf("Build must not be called in dry-run mode, but actual %d", n)
+		}
+		out := mockUI.Writer().String()
+		for _, s := range []string{
+			"+ github.com/ktr0731/evans v0.1.0",
+			"~ github.com/foo/bar v0.1.0 => v0.2.0 (required by github.com/ktr0731/evans)",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("Run must show '%s', but missing:\n%s", s, out)
+			}
+		}
+	})
+
 	t.Run("deptfile is not modified when command failed", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockGoCMD := &gocmd.CommandMock{
-			ListFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 				return nil, errors.New("an error")
 			},
 		}
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
-				return f("", &deptfile.File{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f("", "", &deptfile.File{
 					Require: []*deptfile.Require{},
 				})
 			},
@@ -307,19 +358,19 @@ func TestGetRun(t *testing.T) {
 
 				mockUI := newMockUI()
 				mockGoCMD := &gocmd.CommandMock{
-					GetFunc: func(ctx context.Context, pkgs ...string) error {
+					GetFunc: func(ctx context.Context, dir string, pkgs ...string) error {
 						return nil
 					},
-					BuildFunc: func(ctx context.Context, pkgs ...string) error {
+					BuildFunc: func(ctx context.Context, dir string, pkgs ...string) error {
 						return nil
 					},
-					ListFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+					ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 						return strings.NewReader(repo), nil
 					},
 				}
 				mockWorkspace := &deptfile.WorkspacerMock{
-					DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
-						return f("", &deptfile.File{
+					DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+						return f("", "", &deptfile.File{
 							Require: []*deptfile.Require{
 								{Path: "github.com/ktr0731/evans", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
 							},
@@ -3,6 +3,8 @@ package cmd
 import (
 	"flag"
 	"syscall"
+
+	"github.com/ktr0731/dept/manager"
 )
 
 func NewOutputFlagValue(f *flag.FlagSet) *outputFlagValue {
@@ -10,7 +12,7 @@ func NewOutputFlagValue(f *flag.FlagSet) *outputFlagValue {
 }
 
 func NormalizePath(path string) (repo, ver string, err error) {
-	return normalizePath(path)
+	return manager.ParsePath(path)
 }
 
 func ChangeSyscallExec(f func(argv0 string, argv []string, envv []string) (err error)) func() {
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+)
+
+// historyCommand lists up journal entries which can be restored by undoCommand.
+type historyCommand struct {
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *historyCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var historyHelpTmpl = `Usage: dept history
+
+history lists up snapshots of %s and %s which are taken
+before each command updated them, newest first.
+Each snapshot can be restored by 'dept undo <id>'.
+`
+
+func (c *historyCommand) Help() string {
+	return fmt.Sprintf(historyHelpTmpl, deptfile.FileName, deptfile.FileSumName)
+}
+
+func (c *historyCommand) Synopsis() string {
+	return fmt.Sprintf("Show the change history of %s", deptfile.FileName)
+}
+
+func (c *historyCommand) Run(args []string) int {
+	return run(c, func(ctx context.Context) error {
+		entries, err := c.manager.History(ctx)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			c.ui.Output("no history")
+			return nil
+		}
+
+		var b strings.Builder
+		w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tCOMMAND")
+		for _, e := range entries {
+			desc := e.Description
+			if desc == "" {
+				desc = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"), desc)
+		}
+		w.Flush()
+		c.ui.Output(strings.TrimSuffix(b.String(), "\n"))
+		return nil
+	})
+}
+
+// NewHistory returns an initialized historyCommand instance.
+func NewHistory(
+	ui cli.Ui,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &historyCommand{
+		ui:      ui,
+		manager: &manager.Manager{Workspace: workspace},
+	}
+}
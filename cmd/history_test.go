@@ -0,0 +1,81 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+func TestHistoryRun(t *testing.T) {
+	t.Run("Run shows journal entries newest first", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		for id, desc := range map[string]string{"1": "get github.com/ktr0731/evans", "2": "remove github.com/ktr0731/evans"} {
+			entryDir := filepath.Join(dir, deptfile.JournalDir, id)
+			if err := os.MkdirAll(entryDir, 0755); err != nil {
+				t.Fatalf("failed to create a journal entry dir: %s", err)
+			}
+			b := []byte(`{"time":"2018-12-01T00:00:00Z","description":"` + desc + `"}`)
+			if err := ioutil.WriteFile(filepath.Join(entryDir, "entry.json"), b, 0644); err != nil {
+				t.Fatalf("failed to write a journal entry: %s", err)
+			}
+		}
+
+		mockUI := newMockUI()
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f(dir, "", &deptfile.File{})
+			},
+		}
+		cmd := cmd.NewHistory(mockUI, mockWorkspace)
+
+		code := cmd.Run(nil)
+		if code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+
+		sp := strings.Split(mockUI.Writer().String(), "\n")
+		if len(sp) < 3 {
+			t.Fatalf("Run must show a header and 2 entries, but got:\n%s", mockUI.Writer().String())
+		}
+		if !strings.HasPrefix(sp[1], "2 ") || !strings.Contains(sp[1], "remove github.com/ktr0731/evans") {
+			t.Errorf("the newest entry must be shown first, but got '%s'", sp[1])
+		}
+		if !strings.HasPrefix(sp[2], "1 ") || !strings.Contains(sp[2], "get github.com/ktr0731/evans") {
+			t.Errorf("the oldest entry must be shown last, but got '%s'", sp[2])
+		}
+	})
+
+	t.Run("Run shows a message if there is no history", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		mockUI := newMockUI()
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f(dir, "", &deptfile.File{})
+			},
+		}
+		cmd := cmd.NewHistory(mockUI, mockWorkspace)
+
+		code := cmd.Run(nil)
+		if code != 0 {
+			t.Fatalf("Run must return 0, but got %d", code)
+		}
+		if out := strings.TrimSpace(mockUI.Writer().String()); out != "no history" {
+			t.Errorf("Run must show 'no history', but got '%s'", out)
+		}
+	})
+}
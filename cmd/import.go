@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+)
+
+type importFlagSet struct {
+	*flag.FlagSet
+
+	from string
+}
+
+func newImportFlagSet() *importFlagSet {
+	imf := &importFlagSet{FlagSet: flag.NewFlagSet("import", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	imf.SetOutput(ioutil.Discard)
+	imf.StringVar(&imf.from, "from", "", "Import source format. If it is omitted, it is detected by the file extension")
+	return imf
+}
+
+// importCommand adds tools which are managed in another way to gotool.mod.
+// See manager.Manager.Import for details.
+type importCommand struct {
+	f       *importFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *importCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var importHelpTmpl = `Usage: dept import [-from=<format>] [file]
+
+import adds tools which are managed in another way to %s.
+If %s doesn't exist, import creates it.
+Available formats are:
+
+    gomod       tool directives in a go.mod.
+                If file is omitted, go.mod in the project root is used.
+    tools-go    blank imports in a Go file like tools.go. Build constraints are ignored.
+                Versions are resolved by the nearest go.mod from the file.
+                If file is omitted, tools.go or tools/tools.go in the project root is used.
+
+Versions are the same as the go.mod, and nothing is upgraded.
+If a tool module is already required at another version, import fails.
+import keeps requirements of the go.mod which imported tools depend on as indirect ones,
+but doesn't resolve missing ones. Please run 'dept tidy' after that.
+
+%s`
+
+func (c *importCommand) Help() string {
+	return fmt.Sprintf(importHelpTmpl, deptfile.FileName, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *importCommand) Synopsis() string {
+	return fmt.Sprintf("Import tools which are managed in another way to %s", deptfile.FileName)
+}
+
+func (c *importCommand) Run(args []string) int {
+	desc := strings.TrimSpace("import " + strings.Join(args, " "))
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if c.f.NArg() > 1 {
+			return errShowHelp
+		}
+		tools, err := c.manager.Import(ctx, c.f.from, c.f.Arg(0), manager.Description(desc))
+		if err != nil {
+			return err
+		}
+		if len(tools) == 0 {
+			c.ui.Warn("no tools found")
+		}
+		return nil
+	})
+}
+
+// NewImport returns an initialized importCommand instance.
+func NewImport(
+	ui cli.Ui,
+	gocmd gocmd.Command,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &importCommand{
+		f:  newImportFlagSet(),
+		ui: ui,
+		manager: &manager.Manager{
+			GoCommand: gocmd,
+			Workspace: workspace,
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package cmd_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/gocmd"
+)
+
+func TestImportRun(t *testing.T) {
+	t.Run("Run imports tool directives in go.mod", testImportGoMod)
+	t.Run("Run imports blank imports in tools.go", testImportToolsGo)
+	t.Run("Run doesn't change versions of required tools", testImportVersionConflict)
+}
+
+// newModGraphMock returns a gocmd.Command which returns graph as the result of 'go mod graph'.
+func newModGraphMock(graph string) *gocmd.CommandMock {
+	return &gocmd.CommandMock{
+		ModGraphFunc: func(ctx context.Context, dir string) (io.Reader, error) {
+			return strings.NewReader(graph), nil
+		},
+	}
+}
+
+func testImportGoMod(t *testing.T) {
+	const gomod = `module example.com/project
+
+go 1.24
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	golang.org/x/tools v0.1.1-0.20190101000000-abcdefabcdef
+	golang.org/x/tools/gopls v0.15.0
+)
+
+replace github.com/ktr0731/evans => ../evans
+
+tool (
+	github.com/ktr0731/evans
+	golang.org/x/tools/cmd/stringer
+	golang.org/x/tools/gopls
+)
+`
+	dir, cleanup := setupDeptfileDir(t, "module tools\n")
+	defer cleanup()
+	if err := os.Mkdir(filepath.Join(dir, "project"), 0755); err != nil {
+		t.Fatalf("failed to create a dir: %s", err)
+	}
+	fname := filepath.Join(dir, "project", "go.mod")
+	if err := ioutil.WriteFile(fname, []byte(gomod), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %s", err)
+	}
+
+	const graph = `example.com/project github.com/ktr0731/evans@v0.1.0
+example.com/project golang.org/x/tools@v0.1.1-0.20190101000000-abcdefabcdef
+example.com/project golang.org/x/tools/gopls@v0.15.0
+golang.org/x/tools/gopls@v0.15.0 golang.org/x/tools@v0.1.1-0.20190101000000-abcdefabcdef
+`
+	mockUI := newMockUI()
+	if code := cmd.NewImport(mockUI, newModGraphMock(graph), &deptfile.Workspace{SourcePath: dir}).Run([]string{"-from", "gomod", fname}); code != 0 {
+		t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+	}
+
+	// Versions are kept as it is, and the local dir is rebased on the dir which has gotool.mod.
+	const expected = `module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	golang.org/x/tools/gopls v0.15.0
+	golang.org/x/tools:/cmd/stringer v0.1.1-0.20190101000000-abcdefabcdef
+)
+
+replace github.com/ktr0731/evans => ./evans
+`
+	b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+	}
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("Run wrote unexpected %s:\n%s", deptfile.FileName, diff)
+	}
+}
+
+func testImportToolsGo(t *testing.T) {
+	const gomod = `module example.com/project
+
+go 1.21
+
+require (
+	github.com/google/go-cmp v0.5.0
+	github.com/pkg/errors v0.8.0
+	golang.org/x/tools v0.1.1-0.20190101000000-abcdefabcdef
+	honnef.co/go/tools v0.0.1
+)
+`
+	const toolsGo = `//go:build tools
+
+package tools
+
+import (
+	_ "golang.org/x/tools/cmd/stringer"
+	_ "honnef.co/go/tools/cmd/staticcheck"
+)
+`
+	const gosum = "github.com/pkg/errors v0.8.0 h1:WdK/asTD0HN+q6hsWO3/vpuAkAr+tw6aNJNDFFf0+qw=\n"
+
+	dir, cleanup := setupDeptfileDir(t, "module tools\n")
+	defer cleanup()
+	for name, data := range map[string]string{
+		"go.mod":   gomod,
+		"go.sum":   gosum,
+		"tools.go": toolsGo,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	const graph = `example.com/project github.com/google/go-cmp@v0.5.0
+example.com/project github.com/pkg/errors@v0.8.0
+example.com/project golang.org/x/tools@v0.1.1-0.20190101000000-abcdefabcdef
+example.com/project honnef.co/go/tools@v0.0.1
+honnef.co/go/tools@v0.0.1 github.com/pkg/errors@v0.8.0
+`
+	mockUI := newMockUI()
+	if code := cmd.NewImport(mockUI, newModGraphMock(graph), &deptfile.Workspace{SourcePath: dir}).Run([]string{filepath.Join(dir, "tools.go")}); code != 0 {
+		t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+	}
+
+	// Requirements which tools depend on are kept as indirect ones to prevent upgrading.
+	// Requirements which only the project depends on are not imported.
+	const expected = `module tools
+
+require (
+	github.com/pkg/errors v0.8.0 // indirect
+	golang.org/x/tools:/cmd/stringer v0.1.1-0.20190101000000-abcdefabcdef
+	honnef.co/go/tools:/cmd/staticcheck v0.0.1
+)
+`
+	b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+	}
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("Run wrote unexpected %s:\n%s", deptfile.FileName, diff)
+	}
+	sum, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileSumName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.FileSumName, err)
+	}
+	if !strings.Contains(string(sum), gosum) {
+		t.Errorf("%s must have entries of go.sum, but got:\n%s", deptfile.FileSumName, string(sum))
+	}
+}
+
+func testImportVersionConflict(t *testing.T) {
+	const gomod = `module example.com/project
+
+go 1.24
+
+require github.com/ktr0731/evans v0.2.0
+
+tool github.com/ktr0731/evans
+`
+	const gotoolMod = "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n"
+	dir, cleanup := setupDeptfileDir(t, gotoolMod)
+	defer cleanup()
+	fname := filepath.Join(dir, "go.mod")
+	if err := ioutil.WriteFile(fname, []byte(gomod), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %s", err)
+	}
+
+	mockUI := newMockUI()
+	if code := cmd.NewImport(mockUI, newModGraphMock(""), &deptfile.Workspace{SourcePath: dir}).Run([]string{fname}); code != 1 {
+		t.Errorf("Run must return 1, but got %d", code)
+	}
+	if !strings.Contains(mockUI.ErrorWriter().String(), "github.com/ktr0731/evans is required at v0.1.0") {
+		t.Errorf("Run must report the version conflict, but got '%s'", mockUI.ErrorWriter().String())
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+	}
+	if string(b) != gotoolMod {
+		t.Errorf("%s must not be changed, but got '%s'", deptfile.FileName, b)
+	}
+}
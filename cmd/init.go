@@ -3,14 +3,15 @@ package cmd
 import (
 	"context"
 
-	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
 )
 
 // initCommand create a new deptfile.
 type initCommand struct {
-	ui cli.Ui
+	ui      cli.Ui
+	manager *manager.Manager
 }
 
 func (c *initCommand) UI() cli.Ui {
@@ -27,7 +28,7 @@ func (c *initCommand) Synopsis() string {
 
 func (c *initCommand) Run(args []string) int {
 	return run(c, func(ctx context.Context) error {
-		if err := deptfile.Create(ctx); err != nil {
+		if err := c.manager.Init(ctx); err != nil {
 			return errors.Wrap(err, "failed to create a new deptfile")
 		}
 		return nil
@@ -36,5 +37,5 @@ func (c *initCommand) Run(args []string) int {
 
 // NewInit returns an initialized init command instance.
 func NewInit(ui cli.Ui) cli.Command {
-	return &initCommand{ui: ui}
+	return &initCommand{ui: ui, manager: &manager.Manager{}}
 }
@@ -5,10 +5,10 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"path/filepath"
 	"text/template"
 
 	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
 )
@@ -17,39 +17,49 @@ type listFlagSet struct {
 	*flag.FlagSet
 
 	format string
+	all    bool
 }
 
 func newListFlagSet() *listFlagSet {
 	lf := &listFlagSet{FlagSet: flag.NewFlagSet("list", flag.ExitOnError)}
-	lf.StringVar(&lf.format, "f", "{{.Path}} {{.Name}} {{.Version}}", "output format")
+	lf.StringVar(&lf.format, "f", "{{.Path}} {{.Name}} {{.Version}}{{with .Replace}} => {{.}}{{end}}{{with .File}} ({{.}}){{end}}", "output format")
+	lf.BoolVar(&lf.all, "all", false, "List tools of all gotool.mod under the current dir")
 	return lf
 }
 
 // listCommand lists up managed dependencies.
 type listCommand struct {
-	f         *listFlagSet
-	ui        cli.Ui
-	workspace deptfile.Workspacer
+	f       *listFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
 }
 
 func (c *listCommand) UI() cli.Ui {
 	return c.ui
 }
 
-var listHelpTmpl = `Usage: dept list <path [path ...]>
+var listHelpTmpl = `Usage: dept list [-all] <path [path ...]>
 
 list lists up tool information with some attributes.
 -f formats output based on the passed format string.
+-all lists up tools of all %s under the current dir for each file.
+Tools of gotool.local.mod and included deptfiles are also listed with the file name.
 Each item is represents as the following structure.
 
-type tool struct {
+type Tool struct {
 	Path, Name, Version string
+	// Replace is the replacement of the module by a replace directive.
+	// It is empty if the module is not replaced.
+	Replace string
+	// File is the deptfile which has the tool like gotool.local.mod.
+	// It is empty if the tool is in %s.
+	File string
 }
 
 %s`
 
 func (c *listCommand) Help() string {
-	return fmt.Sprintf(listHelpTmpl, FlagUsage(c.f.FlagSet, false))
+	return fmt.Sprintf(listHelpTmpl, deptfile.FileName, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
 }
 
 func (c *listCommand) Synopsis() string {
@@ -64,45 +74,20 @@ func (c *listCommand) Run(args []string) int {
 
 	args = c.f.Args()
 
-	passed := map[string]interface{}{}
-	for _, arg := range args {
-		passed[arg] = nil
-	}
-	listAll := len(passed) == 0
 	tmpl := `{{range .}}` + c.f.format + `{{"\n"}}{{end}}`
-	return run(c, func(context.Context) error {
+	return run(c, func(ctx context.Context) error {
 		t, err := template.New("list").Parse(tmpl)
 		if err != nil {
 			return errors.Wrapf(err, "failed to parse -f value '%s'", c.f.format)
 		}
-		err = c.workspace.Do(func(projRoot string, df *deptfile.File) error {
-			requires := make([]*tool, 0, len(df.Require))
-			for _, r := range df.Require {
-				if !listAll {
-					// If module roots passed, filter by that modules.
-					if _, found := passed[r.Path]; found {
-						forToolsWithOutputName(r, func(path, out string) bool {
-							requires = appendListItem(requires, path, out, r.Version)
-							return true
-						})
-						continue
-					}
-
-					// If module roots not found, step into each tool.
-				}
-
-				forToolsWithOutputName(r, func(path, out string) bool {
-					if listAll {
-						requires = appendListItem(requires, path, out, r.Version)
-					} else if _, found := passed[path]; found {
-						requires = appendListItem(requires, path, out, r.Version)
-					}
-					return true
-				})
+		list := func(ctx context.Context, m *manager.Manager) error {
+			tools, err := m.List(ctx, args...)
+			if err != nil {
+				return err
 			}
 
 			var buf bytes.Buffer
-			if err := t.Execute(&buf, requires); err != nil {
+			if err := t.Execute(&buf, tools); err != nil {
 				return err
 			}
 			if buf.Len() > 0 {
@@ -110,27 +95,22 @@ func (c *listCommand) Run(args []string) int {
 				c.ui.Output(buf.String()[:buf.Len()-1])
 			}
 			return nil
-		})
-		return err
+		}
+		if c.f.all {
+			return runAll(ctx, c.ui, c.manager, list)
+		}
+		return list(ctx, c.manager)
 	})
 }
 
-func appendListItem(requires []*tool, path, out, version string) []*tool {
-	t := &tool{Path: path, Name: out, Version: version}
-	if out == "" {
-		t.Name = filepath.Base(path)
-	}
-	return append(requires, t)
-}
-
 // NewList returns an initialized listCommand instance.
 func NewList(
 	ui cli.Ui,
 	workspace deptfile.Workspacer,
 ) cli.Command {
 	return &listCommand{
-		f:         newListFlagSet(),
-		ui:        ui,
-		workspace: workspace,
+		f:       newListFlagSet(),
+		ui:      ui,
+		manager: &manager.Manager{Workspace: workspace},
 	}
 }
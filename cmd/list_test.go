@@ -1,6 +1,9 @@
 package cmd_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -12,8 +15,8 @@ func TestListRun(t *testing.T) {
 	t.Run("Run shows direction packages with code 0 normally", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
-				return f("", &deptfile.File{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f("", "", &deptfile.File{
 					Require: []*deptfile.Require{
 						{Path: "github.com/ktr0731/evans", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
 						{Path: "github.com/ktr0731/itunes-cli", ToolPaths: []*deptfile.Tool{{Path: "/itunes"}}},
@@ -37,8 +40,8 @@ func TestListRun(t *testing.T) {
 
 	t.Run("Run shows only specified tools", func(t *testing.T) {
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
-				return f("", &deptfile.File{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f("", "", &deptfile.File{
 					Require: []*deptfile.Require{
 						{Path: "github.com/ktr0731/evans", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
 						{Path: "github.com/ktr0731/itunes-cli", ToolPaths: []*deptfile.Tool{{Path: "/itunes"}}},
@@ -88,8 +91,8 @@ func TestListRun(t *testing.T) {
 
 	t.Run("Run shows tools with -f based format", func(t *testing.T) {
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
-				return f("", &deptfile.File{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f("", "", &deptfile.File{
 					Require: []*deptfile.Require{
 						{Path: "github.com/ktr0731/evans", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
 						{Path: "github.com/ktr0731/itunes-cli", ToolPaths: []*deptfile.Tool{{Path: "/itunes", Name: "it"}}},
@@ -137,4 +140,91 @@ func TestListRun(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("Run shows replacements of tools", func(t *testing.T) {
+		mockUI := newMockUI()
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return f("", "", &deptfile.File{
+					Require: []*deptfile.Require{
+						{Path: "github.com/ktr0731/evans", Version: "v0.1.0", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
+						{Path: "honnef.co/go/tools", Version: "v0.2.0", ToolPaths: []*deptfile.Tool{{Path: "/cmd/unused"}}},
+					},
+					Replace: []*deptfile.Replace{
+						{Path: "github.com/ktr0731/evans", NewPath: "github.com/foo/evans", NewVersion: "v0.1.1"},
+					},
+				})
+			},
+		}
+		cmd := cmd.NewList(mockUI, mockWorkspace)
+		if code := cmd.Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d", code)
+		}
+
+		expected := "github.com/ktr0731/evans evans v0.1.0 => github.com/foo/evans v0.1.1\nhonnef.co/go/tools/cmd/unused unused v0.2.0\n"
+		if actual := mockUI.Writer().String(); expected != actual {
+			t.Errorf("expected: %s, but got %s", expected, actual)
+		}
+	})
+
+	t.Run("Run with -all shows tools of all gotool.mod for each file", func(t *testing.T) {
+		root, cleanup := setupDeptfileDir(t, "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n")
+		defer cleanup()
+		sub := filepath.Join(root, "services", "api")
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			t.Fatalf("failed to create a dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(sub, deptfile.FileName), []byte("module tools\n\nrequire github.com/ktr0731/salias@sa v0.1.0\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get the current dir: %s", err)
+		}
+		if err := os.Chdir(root); err != nil {
+			t.Fatalf("failed to change the current dir: %s", err)
+		}
+		defer os.Chdir(cwd)
+
+		mockUI := newMockUI()
+		if code := cmd.NewList(mockUI, nil).Run([]string{"-all"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		expected := `gotool.mod:
+github.com/ktr0731/evans evans v0.1.0
+
+services/api/gotool.mod:
+github.com/ktr0731/salias sa v0.1.0
+`
+		if actual := mockUI.Writer().String(); actual != expected {
+			t.Errorf("expected:\n%s\nactual:\n%s", expected, actual)
+		}
+	})
+
+	t.Run("Run shows tools of gotool.local.mod with the file name", func(t *testing.T) {
+		root, cleanup := setupDeptfileDir(t, "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n")
+		defer cleanup()
+		if err := ioutil.WriteFile(filepath.Join(root, deptfile.LocalFileName), []byte("module tools\n\nrequire github.com/ktr0731/salias v0.1.0\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", deptfile.LocalFileName, err)
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get the current dir: %s", err)
+		}
+		if err := os.Chdir(root); err != nil {
+			t.Fatalf("failed to change the current dir: %s", err)
+		}
+		defer os.Chdir(cwd)
+
+		mockUI := newMockUI()
+		if code := cmd.NewList(mockUI, nil).Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		expected := `github.com/ktr0731/salias salias v0.1.0 (gotool.local.mod)
+github.com/ktr0731/evans evans v0.1.0
+`
+		if actual := mockUI.Writer().String(); actual != expected {
+			t.Errorf("expected:\n%s\nactual:\n%s", expected, actual)
+		}
+	})
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+)
+
+type outdatedFlagSet struct {
+	*flag.FlagSet
+
+	all bool
+}
+
+func newOutdatedFlagSet() *outdatedFlagSet {
+	of := &outdatedFlagSet{FlagSet: flag.NewFlagSet("outdated", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	of.SetOutput(ioutil.Discard)
+	of.BoolVar(&of.all, "all", false, "Check tools of all gotool.mod under the current dir")
+	return of
+}
+
+// outdatedCommand shows tools which have newer versions.
+// See manager.Manager.Outdated for details.
+type outdatedCommand struct {
+	f       *outdatedFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *outdatedCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var outdatedHelpTmpl = `Usage: dept outdated [-all]
+
+outdated shows modules of tools which have newer versions.
+It never updates %s. Please use 'dept get -u' to update them.
+-all checks all %s under the current dir for each file.
+
+%s`
+
+func (c *outdatedCommand) Help() string {
+	return fmt.Sprintf(outdatedHelpTmpl, deptfile.FileName, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *outdatedCommand) Synopsis() string {
+	return "Show tools which have newer versions"
+}
+
+func (c *outdatedCommand) Run(args []string) int {
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if c.f.NArg() != 0 {
+			return errShowHelp
+		}
+		if c.f.all {
+			return runAll(ctx, c.ui, c.manager, c.outdated)
+		}
+		return c.outdated(ctx, c.manager)
+	})
+}
+
+func (c *outdatedCommand) outdated(ctx context.Context, m *manager.Manager) error {
+	mods, err := m.Outdated(ctx)
+	if err != nil {
+		return err
+	}
+	if len(mods) == 0 {
+		c.ui.Output("all tools are up to date")
+		return nil
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tCURRENT\tLATEST\tTOOLS")
+	for _, mod := range mods {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mod.Path, mod.Version, mod.Latest, strings.Join(mod.Tools, ","))
+	}
+	w.Flush()
+	c.ui.Output(strings.TrimSuffix(b.String(), "\n"))
+	return nil
+}
+
+// NewOutdated returns an initialized outdatedCommand instance.
+func NewOutdated(
+	ui cli.Ui,
+	gocmd gocmd.Command,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &outdatedCommand{
+		f:  newOutdatedFlagSet(),
+		ui: ui,
+		manager: &manager.Manager{
+			GoCommand: gocmd,
+			Workspace: workspace,
+		},
+	}
+}
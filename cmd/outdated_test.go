@@ -0,0 +1,88 @@
+package cmd_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/gocmd"
+)
+
+func TestOutdatedRun(t *testing.T) {
+	const data = `module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/ktr0731/itunes-cli:/itunes v0.1.0
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused@u v0.0.2
+)
+
+replace github.com/ktr0731/itunes-cli => ./itunes-cli
+`
+	newGoCMD := func(out string) *gocmd.CommandMock {
+		return &gocmd.CommandMock{
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+				return strings.NewReader(out), nil
+			},
+		}
+	}
+
+	t.Run("Run shows modules which have newer versions", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, data)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		mockGoCMD := newGoCMD(`{"Path": "github.com/ktr0731/evans", "Version": "v0.1.0"}
+{"Path": "honnef.co/go/tools", "Version": "v0.0.2", "Update": {"Version": "v0.5.0"}}
+`)
+		if code := cmd.NewOutdated(mockUI, mockGoCMD, &deptfile.Workspace{SourcePath: dir}).Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+
+		calls := mockGoCMD.ListCalls()
+		if len(calls) != 1 {
+			t.Fatalf("List must be called once, but actual %d", len(calls))
+		}
+		// Modules which are replaced by local dirs are skipped.
+		if args := strings.Join(calls[0].Args, " "); args != "-m -u -json github.com/ktr0731/evans honnef.co/go/tools" {
+			t.Errorf("unexpected args of List: '%s'", args)
+		}
+
+		expected := `MODULE              CURRENT  LATEST  TOOLS
+honnef.co/go/tools  v0.0.2   v0.5.0  staticcheck,u
+`
+		if actual := mockUI.Writer().String(); actual != expected {
+			t.Errorf("expected:\n%s\nactual:\n%s", expected, actual)
+		}
+	})
+
+	t.Run("Run shows a message if all tools are up to date", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, data)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewOutdated(mockUI, newGoCMD(""), &deptfile.Workspace{SourcePath: dir}).Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if actual := mockUI.Writer().String(); actual != "all tools are up to date\n" {
+			t.Errorf("unexpected output: '%s'", actual)
+		}
+	})
+
+	t.Run("Run returns 1 if arguments are passed", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, data)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		mockGoCMD := newGoCMD("")
+		if code := cmd.NewOutdated(mockUI, mockGoCMD, &deptfile.Workspace{SourcePath: dir}).Run([]string{"evans"}); code != 1 {
+			t.Errorf("Run must return 1, but got %d", code)
+		}
+		if n := len(mockGoCMD.ListCalls()); n != 0 {
+			t.Errorf("List must not be called, but actual %d", n)
+		}
+	})
+}
@@ -2,37 +2,70 @@ package cmd
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"os"
+	"io/ioutil"
+	"strings"
 
-	"github.com/hashicorp/go-multierror"
 	"github.com/ktr0731/dept/deptfile"
-	"github.com/ktr0731/dept/filegen"
 	"github.com/ktr0731/dept/gocmd"
-	"github.com/ktr0731/dept/logger"
+	"github.com/ktr0731/dept/manager"
+	"github.com/ktr0731/dept/toolcacher"
 	"github.com/mitchellh/cli"
 	"github.com/pkg/errors"
 )
 
-// removeCommand removes a passed Go tool from gotool.mod as follows.
-//
-//   1. load deptfile.
-//   2. check whether the passed tool is vendored, or not.
-//   3. generate Go code from updated deptfile.
-//   4. run 'go mod tidy' to remove unnecessary dependencies.
-//
+type removeFlagSet struct {
+	*flag.FlagSet
+
+	outputDir string
+	module    bool
+	fullTidy  bool
+	dryRun    bool
+}
+
+func newRemoveFlagSet() *removeFlagSet {
+	rf := &removeFlagSet{FlagSet: flag.NewFlagSet("remove", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	rf.SetOutput(ioutil.Discard)
+	rf.StringVar(&rf.outputDir, "d", "", "Output dir which stores built Go tools")
+	rf.BoolVar(&rf.module, "module", false, "Remove all tools of the modules which passed tools belong to")
+	rf.BoolVar(&rf.fullTidy, "tidy", false, "Allow 'go mod tidy' to change versions of remaining requirements")
+	rf.BoolVar(&rf.dryRun, "dry-run", false, "Show changes without updating gotool.mod")
+	rf.BoolVar(&rf.dryRun, "n", false, "Same as -dry-run")
+	return rf
+}
+
+// removeCommand removes a passed Go tool from gotool.mod.
+// See manager.Manager.Remove for details.
 type removeCommand struct {
-	ui        cli.Ui
-	gocmd     gocmd.Command
-	workspace deptfile.Workspacer
+	f       *removeFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
 }
 
 func (c *removeCommand) UI() cli.Ui {
 	return c.ui
 }
 
+var removeHelpTmpl = `Usage: dept remove <path or name [path or name ...]>
+
+remove removes the passed Go tools from %s.
+Each tool is specified by the import path or the output name.
+-module flag removes all tools of the modules which the passed tools belong to.
+Binaries which dept installed in the output dir and cached binaries of removed tools are also deleted.
+A binary of the same name which dept didn't install is kept as it is.
+remove fails if removing tools changes versions of requirements which are still used
+by remaining tools. -tidy flag allows it, just like 'go mod tidy'.
+After that, remove shows changes of tools and indirect requirements, and deleted binaries.
+-dry-run (or -n) flag shows these changes, the diff of %s and
+the summary of %s changes without updating these files.
+
+%s`
+
 func (c *removeCommand) Help() string {
-	return "Usage: dept remove <path [path ...]>"
+	return fmt.Sprintf(removeHelpTmpl, deptfile.FileName, deptfile.FileName, deptfile.FileSumName, FlagUsage(c.f.FlagSet, false))
 }
 
 func (c *removeCommand) Synopsis() string {
@@ -40,60 +73,47 @@ func (c *removeCommand) Synopsis() string {
 }
 
 func (c *removeCommand) Run(args []string) int {
+	desc := strings.TrimSpace("remove " + strings.Join(args, " "))
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+	args = c.f.Args()
+
+	dryRun := c.f.dryRun
+	opts := []manager.RemoveOption{
+		manager.OutputDir(c.f.outputDir),
+		manager.Description(desc),
+		manager.OnChange(func(changes *deptfile.Changes) {
+			reportChanges(c.ui, changes, dryRun)
+		}),
+	}
+	if c.f.module {
+		opts = append(opts, manager.Module())
+	}
+	if c.f.fullTidy {
+		opts = append(opts, manager.FullTidy())
+	}
+	if dryRun {
+		opts = append(opts, manager.DryRun())
+	}
+
 	return run(c, func(ctx context.Context) error {
 		if len(args) < 1 {
 			return errShowHelp
 		}
-
-		err := c.workspace.Do(func(projRoot string, df *deptfile.File) error {
-			repoMap := map[string]bool{}
-			for _, path := range args {
-				repo, _, err := normalizePath(path)
-				if err != nil {
-					return err
-				}
-				if _, found := repoMap[repo]; found {
-					continue
-				}
-				repoMap[repo] = false
-			}
-
-			requires := make([]string, 0, len(df.Require))
-			for _, r := range df.Require {
-				forTools(r, func(path string) bool {
-					if _, found := repoMap[path]; found {
-						repoMap[path] = true
-					} else {
-						requires = append(requires, path)
-					}
-					return true
-				})
-			}
-			var err error
-			for repo, found := range repoMap {
-				if !found {
-					err = multierror.Append(err, errors.Errorf("%s not found in gotool.mod", repo))
-				}
-			}
-			if err != nil {
-				return err
+		deleted, err := c.manager.Remove(ctx, args, opts...)
+		if len(deleted) > 0 {
+			var b strings.Builder
+			b.WriteString("deleted binaries:\n")
+			for _, p := range deleted {
+				fmt.Fprintf(&b, "  %s\n", p)
 			}
-
-			f, err := os.Create("tools.go")
-			if err != nil {
-				return errors.Wrap(err, "failed to create a temp file which contains required Go tools in the import statement")
-			}
-			defer os.Remove("tools.go")
-			defer f.Close()
-			filegen.Generate(f, requires)
-
-			logger.Println("removing unnecessary tools and indirection dependencies")
-			if err := c.gocmd.ModTidy(ctx); err != nil {
-				return errors.Wrap(err, "failed to remove the tool from gotool.mod")
-			}
-
-			return nil
-		})
+			c.ui.Output(strings.TrimSuffix(b.String(), "\n"))
+		}
+		if _, ok := errors.Cause(err).(*manager.VersionChangedErr); ok {
+			return errors.Wrapf(err, "%s is not updated. pass -tidy to allow these changes", deptfile.FileName)
+		}
 		return err
 	})
 }
@@ -103,10 +123,15 @@ func NewRemove(
 	ui cli.Ui,
 	gocmd gocmd.Command,
 	workspace deptfile.Workspacer,
+	toolcacher toolcacher.Cacher,
 ) cli.Command {
 	return &removeCommand{
-		ui:        ui,
-		gocmd:     gocmd,
-		workspace: workspace,
+		f:  newRemoveFlagSet(),
+		ui: ui,
+		manager: &manager.Manager{
+			GoCommand:  gocmd,
+			ToolCacher: toolcacher,
+			Workspace:  workspace,
+		},
 	}
 }
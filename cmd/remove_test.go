@@ -2,23 +2,36 @@ package cmd_test
 
 import (
 	"context"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/ktr0731/dept/cmd"
 	"github.com/ktr0731/dept/deptfile"
 	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/manager"
+	"github.com/ktr0731/dept/toolcacher"
 )
 
+func newRemoveToolCacher() *toolcacher.CacherMock {
+	return &toolcacher.CacherMock{
+		RemoveAllFunc: func(ctx context.Context, pkgName string) ([]string, error) {
+			return nil, nil
+		},
+	}
+}
+
 func TestRemoveRun(t *testing.T) {
-	doNothing := func(f func(projectDir string, gomod *deptfile.File) error) error { return f("", nil) }
+	doNothing := func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+		return f("", "", nil)
+	}
 
 	t.Run("Run returns code 1 because no arguments passed", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockWorkspace := &deptfile.WorkspacerMock{
 			DoFunc: doNothing,
 		}
-		cmd := cmd.NewRemove(mockUI, nil, mockWorkspace)
+		cmd := cmd.NewRemove(mockUI, nil, mockWorkspace, nil)
 
 		code := cmd.Run(nil)
 		if code != 1 {
@@ -32,12 +45,12 @@ func TestRemoveRun(t *testing.T) {
 	t.Run("Run returns 1 because gotool.mod is not found", func(t *testing.T) {
 		mockUI := newMockUI()
 		mockWorkspace := &deptfile.WorkspacerMock{
-			DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
 				return deptfile.ErrNotFound
 			},
 		}
 
-		cmd := cmd.NewRemove(mockUI, nil, mockWorkspace)
+		cmd := cmd.NewRemove(mockUI, nil, mockWorkspace, nil)
 
 		repo := "github.com/ktr0731/go-modules-test"
 		code := cmd.Run([]string{repo})
@@ -85,18 +98,21 @@ func TestRemoveRun(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				mockUI := newMockUI()
 				mockGoCMD := &gocmd.CommandMock{
-					ModTidyFunc: func(ctx context.Context) error {
+					ModTidyFunc: func(ctx context.Context, dir string) error {
 						return nil
 					},
+					ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+						return strings.NewReader("tools\n"), nil
+					},
 				}
 				mockWorkspace := &deptfile.WorkspacerMock{
-					DoFunc: func(f func(projectDir string, gomod *deptfile.File) error) error {
-						return f("", &deptfile.File{
+					DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+						return f("", "", &deptfile.File{
 							Require: c.requires,
 						})
 					},
 				}
-				cmd := cmd.NewRemove(mockUI, mockGoCMD, mockWorkspace)
+				cmd := cmd.NewRemove(mockUI, mockGoCMD, mockWorkspace, newRemoveToolCacher())
 
 				code := cmd.Run([]string{c.repo})
 				if c.hasErr {
@@ -116,4 +132,120 @@ func TestRemoveRun(t *testing.T) {
 		}
 	})
 
+	t.Run("Run shows removed tools", func(t *testing.T) {
+		mockUI := newMockUI()
+		mockGoCMD := &gocmd.CommandMock{
+			ModTidyFunc: func(ctx context.Context, dir string) error {
+				return nil
+			},
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+				return strings.NewReader("tools\n"), nil
+			},
+		}
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				o := deptfile.NewOptions(opts...)
+				if o.DryRun {
+					t.Error("DryRun option must not be passed")
+				}
+				err := f("", "", &deptfile.File{
+					Require: []*deptfile.Require{{Path: "github.com/wa2/kazusa", Version: "v0.1.0", ToolPaths: []*deptfile.Tool{{Path: "/"}}}},
+				})
+				if err != nil {
+					return err
+				}
+				o.OnChange(&deptfile.Changes{
+					Tools: []*deptfile.Change{{Path: "github.com/wa2/kazusa", OldVersion: "v0.1.0"}},
+				})
+				return nil
+			},
+		}
+		cmd := cmd.NewRemove(mockUI, mockGoCMD, mockWorkspace, newRemoveToolCacher())
+
+		code := cmd.Run([]string{"github.com/wa2/kazusa"})
+		if code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if out := mockUI.Writer().String(); !strings.Contains(out, "- github.com/wa2/kazusa v0.1.0") {
+			t.Errorf("Run must show the removed tool, but missing:\n%s", out)
+		}
+	})
+	t.Run("Run shows the diff without updating gotool.mod in dry-run mode", func(t *testing.T) {
+		mockUI := newMockUI()
+		mockGoCMD := &gocmd.CommandMock{
+			ModTidyFunc: func(ctx context.Context, dir string) error {
+				return nil
+			},
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+				return strings.NewReader("tools\n"), nil
+			},
+		}
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				o := deptfile.NewOptions(opts...)
+				if !o.DryRun {
+					t.Error("DryRun option must be passed")
+				}
+				err := f("", "", &deptfile.File{
+					Require: []*deptfile.Require{{Path: "github.com/wa2/kazusa", Version: "v0.1.0", ToolPaths: []*deptfile.Tool{{Path: "/"}}}},
+				})
+				if err != nil {
+					return err
+				}
+				o.OnChange(&deptfile.Changes{
+					Tools:   []*deptfile.Change{{Path: "github.com/wa2/kazusa", OldVersion: "v0.1.0"}},
+					ModDiff: "--- a/gotool.mod\n+++ b/gotool.mod\n@@ -1,3 +1,1 @@\n module tools\n-\n-require github.com/wa2/kazusa v0.1.0\n",
+					Sum:     &deptfile.SumChanges{Removed: []string{"github.com/wa2/kazusa v0.1.0"}},
+				})
+				return nil
+			},
+		}
+		cmd := cmd.NewRemove(mockUI, mockGoCMD, mockWorkspace, newRemoveToolCacher())
+
+		code := cmd.Run([]string{"-n", "github.com/wa2/kazusa"})
+		if code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		out := mockUI.Writer().String()
+		for _, s := range []string{
+			"(dry-run)",
+			"- github.com/wa2/kazusa v0.1.0",
+			"-require github.com/wa2/kazusa v0.1.0",
+			"gotool.sum: 0 entries added, 1 entries removed",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("Run must show '%s', but missing:\n%s", s, out)
+			}
+		}
+	})
+	t.Run("Run suggests -tidy if remaining requirements are changed", func(t *testing.T) {
+		mockUI := newMockUI()
+		mockGoCMD := &gocmd.CommandMock{
+			ModTidyFunc: func(ctx context.Context, dir string) error {
+				return nil
+			},
+			ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+				return strings.NewReader("tools\n"), nil
+			},
+		}
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return &manager.VersionChangedErr{
+					Changes: []*deptfile.Change{{Path: "golang.org/x/text", OldVersion: "v0.3.0", NewVersion: "v0.3.2"}},
+				}
+			},
+		}
+		cmd := cmd.NewRemove(mockUI, mockGoCMD, mockWorkspace, newRemoveToolCacher())
+
+		code := cmd.Run([]string{"github.com/wa2/kazusa"})
+		if code != 1 {
+			t.Fatalf("Run must return 1, but got %d", code)
+		}
+		eout := mockUI.ErrorWriter().String()
+		for _, s := range []string{"golang.org/x/text v0.3.0 => v0.3.2", "-tidy"} {
+			if !strings.Contains(eout, s) {
+				t.Errorf("Run must show '%s', but missing:\n%s", s, eout)
+			}
+		}
+	})
 }
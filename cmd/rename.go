@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
+	"github.com/ktr0731/dept/toolcacher"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+)
+
+type renameFlagSet struct {
+	*flag.FlagSet
+
+	outputDir string
+	dryRun    bool
+}
+
+func newRenameFlagSet() *renameFlagSet {
+	rf := &renameFlagSet{FlagSet: flag.NewFlagSet("rename", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	rf.SetOutput(ioutil.Discard)
+	rf.StringVar(&rf.outputDir, "d", "", "Output dir which stores built Go tools")
+	rf.BoolVar(&rf.dryRun, "dry-run", false, "Check the new name without updating gotool.mod")
+	rf.BoolVar(&rf.dryRun, "n", false, "Same as -dry-run")
+	return rf
+}
+
+// renameCommand renames the output name of a tool.
+// See manager.Manager.Rename for details.
+type renameCommand struct {
+	f       *renameFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *renameCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var renameHelpTmpl = `Usage: dept rename <old-name> <new-name>
+
+rename renames the output name of a tool in %s.
+If dept has installed the tool in the output dir, the binary is also renamed.
+Unlike 'dept get -o', rename never builds the tool.
+
+%s
+Examples:
+
+    $ dept rename golangci-lint lint
+    $ dept rename -d bin golangci-lint lint
+`
+
+func (c *renameCommand) Help() string {
+	return fmt.Sprintf(renameHelpTmpl, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *renameCommand) Synopsis() string {
+	return "Rename the output name of a tool"
+}
+
+func (c *renameCommand) Run(args []string) int {
+	desc := strings.TrimSpace("rename " + strings.Join(args, " "))
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+	args = c.f.Args()
+
+	opts := []manager.RenameOption{
+		manager.OutputDir(c.f.outputDir),
+		manager.Description(desc),
+	}
+	if c.f.dryRun {
+		opts = append(opts, manager.DryRun())
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if len(args) != 2 {
+			return errShowHelp
+		}
+		err := c.manager.Rename(ctx, args[0], args[1], opts...)
+		if _, ok := errors.Cause(err).(*manager.ToolNotFoundErr); ok {
+			return errors.Errorf("tool '%s' is not in %s (available tools can be see 'dept list -f \"{{ .Name }}\"')", args[0], deptfile.FileName)
+		}
+		return err
+	})
+}
+
+// NewRename returns an initialized renameCommand instance.
+func NewRename(
+	ui cli.Ui,
+	workspace deptfile.Workspacer,
+	toolcacher toolcacher.Cacher,
+) cli.Command {
+	return &renameCommand{
+		f:  newRenameFlagSet(),
+		ui: ui,
+		manager: &manager.Manager{
+			ToolCacher: toolcacher,
+			Workspace:  workspace,
+		},
+	}
+}
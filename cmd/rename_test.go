@@ -0,0 +1,131 @@
+package cmd_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/toolcacher"
+)
+
+// setupRenameBinaries creates the binary of staticcheck which has content in the output dir,
+// and returns the output dir and a toolcacher which has the cached build of staticcheck.
+func setupRenameBinaries(t *testing.T, dir, content string) (string, *toolcacher.CacherMock) {
+	t.Helper()
+	binDir := filepath.Join(dir, "bin")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatalf("failed to create the output dir: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(binDir, "staticcheck"), []byte(content), 0755); err != nil {
+		t.Fatalf("failed to create a dummy binary: %s", err)
+	}
+	cachePath := filepath.Join(dir, "staticcheck-v0.0.2")
+	if err := ioutil.WriteFile(cachePath, []byte("bin"), 0755); err != nil {
+		t.Fatalf("failed to create a dummy cache: %s", err)
+	}
+	return binDir, &toolcacher.CacherMock{
+		ListFunc: func(ctx context.Context, pkgName string) ([]string, error) {
+			if pkgName == "honnef.co/go/tools/cmd/staticcheck" {
+				return []string{cachePath}, nil
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestRenameRun(t *testing.T) {
+	t.Run("Run renames the tool and the installed binary", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+		defer cleanup()
+		binDir, mockToolCacher := setupRenameBinaries(t, dir, "bin")
+
+		mockUI := newMockUI()
+		cmd := cmd.NewRename(mockUI, &deptfile.Workspace{SourcePath: dir}, mockToolCacher)
+		if code := cmd.Run([]string{"-d", binDir, "staticcheck", "sc"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if e := "honnef.co/go/tools:/cmd/staticcheck@sc,/cmd/unused v0.0.2"; !strings.Contains(string(b), e) {
+			t.Errorf("%s must contain '%s', but got:\n%s", deptfile.FileName, e, string(b))
+		}
+		if _, err := os.Stat(filepath.Join(binDir, "sc")); err != nil {
+			t.Errorf("the binary must be renamed to sc: %s", err)
+		}
+		if _, err := os.Stat(filepath.Join(binDir, "staticcheck")); !os.IsNotExist(err) {
+			t.Errorf("the old binary must be removed, but got err = %v", err)
+		}
+	})
+
+	t.Run("Run keeps the binary which dept didn't install", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+		defer cleanup()
+		binDir, mockToolCacher := setupRenameBinaries(t, dir, "foreign")
+
+		mockUI := newMockUI()
+		cmd := cmd.NewRename(mockUI, &deptfile.Workspace{SourcePath: dir}, mockToolCacher)
+		if code := cmd.Run([]string{"-d", binDir, "staticcheck", "sc"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if _, err := os.Stat(filepath.Join(binDir, "staticcheck")); err != nil {
+			t.Errorf("the binary which dept didn't install must be kept: %s", err)
+		}
+		if _, err := os.Stat(filepath.Join(binDir, "sc")); !os.IsNotExist(err) {
+			t.Errorf("the binary must not be renamed, but got err = %v", err)
+		}
+	})
+
+	t.Run("Run doesn't require the installed binary", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		cmd := cmd.NewRename(mockUI, &deptfile.Workspace{SourcePath: dir}, nil)
+		if code := cmd.Run([]string{"-d", filepath.Join(dir, "bin"), "evans", "ev"}); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+	})
+
+	t.Run("Run returns code 1", func(t *testing.T) {
+		cases := map[string][]string{
+			"no args":          nil,
+			"too many args":    {"evans", "ev", "e"},
+			"tool not found":   {"salias", "sa"},
+			"conflicted names": {"evans", "unused"},
+			"binary exists":    {"staticcheck", "sc"},
+		}
+
+		for name, args := range cases {
+			args := args
+			t.Run(name, func(t *testing.T) {
+				dir, cleanup := setupDeptfileDir(t, editTestDeptfile)
+				defer cleanup()
+				binDir, mockToolCacher := setupRenameBinaries(t, dir, "bin")
+				if err := ioutil.WriteFile(filepath.Join(binDir, "sc"), []byte("sc"), 0755); err != nil {
+					t.Fatalf("failed to create a dummy binary: %s", err)
+				}
+
+				mockUI := newMockUI()
+				cmd := cmd.NewRename(mockUI, &deptfile.Workspace{SourcePath: dir}, mockToolCacher)
+				if code := cmd.Run(append([]string{"-d", binDir}, args...)); code != 1 {
+					t.Errorf("Run must return 1, but got %d", code)
+				}
+				b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+				if err != nil {
+					t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+				}
+				if string(b) != editTestDeptfile {
+					t.Errorf("%s must not be updated, but got:\n%s", deptfile.FileName, string(b))
+				}
+			})
+		}
+	})
+}
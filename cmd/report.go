@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/mitchellh/cli"
+)
+
+// reportChanges shows a summary of changes made by a command.
+// If dryRun is true, reportChanges also shows the unified diff of gotool.mod
+// and the summary of gotool.sum changes because nothing is written.
+func reportChanges(ui cli.Ui, c *deptfile.Changes, dryRun bool) {
+	if c.Empty() {
+		if dryRun {
+			ui.Output(fmt.Sprintf("no changes to %s", deptfile.FileName))
+		}
+		return
+	}
+
+	var b strings.Builder
+	if dryRun {
+		fmt.Fprintf(&b, "%s would be changed as follows (dry-run):\n", deptfile.FileName)
+	}
+	if len(c.Tools) > 0 {
+		b.WriteString("tools:\n")
+		for _, ch := range c.Tools {
+			fmt.Fprintf(&b, "  %s\n", formatChange(ch))
+		}
+	}
+	if len(c.Indirect) > 0 {
+		b.WriteString("indirect requirements:\n")
+		for _, ch := range c.Indirect {
+			s := formatChange(ch)
+			if len(ch.RequiredBy) > 0 {
+				s += fmt.Sprintf(" (required by %s)", strings.Join(ch.RequiredBy, ", "))
+			}
+			fmt.Fprintf(&b, "  %s\n", s)
+		}
+	}
+	if dryRun {
+		if c.ModDiff != "" {
+			fmt.Fprintf(&b, "\n%s", c.ModDiff)
+		}
+		if !c.Sum.Empty() {
+			fmt.Fprintf(&b, "\n%s: %d entries added, %d entries removed\n", deptfile.FileSumName, len(c.Sum.Added), len(c.Sum.Removed))
+		}
+	}
+	if b.Len() > 0 {
+		ui.Output(strings.TrimSuffix(b.String(), "\n"))
+	}
+}
+
+// formatChange formats a change as '+ path version' (added), '- path version' (removed)
+// or '~ path old => new' (upgraded or downgraded).
+func formatChange(c *deptfile.Change) string {
+	switch {
+	case c.Added():
+		return fmt.Sprintf("+ %s %s", c.Path, c.NewVersion)
+	case c.Removed():
+		return fmt.Sprintf("- %s %s", c.Path, c.OldVersion)
+	default:
+		return fmt.Sprintf("~ %s %s => %s", c.Path, c.OldVersion, c.NewVersion)
+	}
+}
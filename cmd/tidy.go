@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+)
+
+type tidyFlagSet struct {
+	*flag.FlagSet
+
+	check  bool
+	dryRun bool
+}
+
+func newTidyFlagSet() *tidyFlagSet {
+	tf := &tidyFlagSet{FlagSet: flag.NewFlagSet("tidy", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	tf.SetOutput(ioutil.Discard)
+	tf.BoolVar(&tf.check, "check", false, "Fail if gotool.mod is not tidy without updating it")
+	tf.BoolVar(&tf.dryRun, "dry-run", false, "Show changes without updating gotool.mod")
+	tf.BoolVar(&tf.dryRun, "n", false, "Same as -dry-run")
+	return tf
+}
+
+// tidyCommand normalizes and repairs gotool.mod.
+// See manager.Manager.Tidy for details.
+type tidyCommand struct {
+	f       *tidyFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *tidyCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var tidyHelpTmpl = `Usage: dept tidy
+
+tidy normalizes and repairs %s.
+It merges duplicated module entries, sorts tool paths,
+and runs 'go mod tidy' to fix indirect requirements and missing %s entries.
+After that, tidy shows what it fixed.
+-check flag fails if %s is not tidy, without updating it.
+It is useful for CI.
+
+%s`
+
+func (c *tidyCommand) Help() string {
+	return fmt.Sprintf(tidyHelpTmpl, deptfile.FileName, deptfile.FileSumName, deptfile.FileName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *tidyCommand) Synopsis() string {
+	return fmt.Sprintf("Normalize and repair %s", deptfile.FileName)
+}
+
+func (c *tidyCommand) Run(args []string) int {
+	desc := strings.TrimSpace("tidy " + strings.Join(args, " "))
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+
+	dryRun := c.f.dryRun || c.f.check
+	opts := []manager.TidyOption{
+		manager.Description(desc),
+		manager.OnChange(func(changes *deptfile.Changes) {
+			reportChanges(c.ui, changes, dryRun)
+		}),
+	}
+	if c.f.check {
+		opts = append(opts, manager.Check())
+	}
+	if c.f.dryRun {
+		opts = append(opts, manager.DryRun())
+	}
+
+	return run(c, func(ctx context.Context) error {
+		if c.f.NArg() != 0 {
+			return errShowHelp
+		}
+		fixes, err := c.manager.Tidy(ctx, opts...)
+		if len(fixes) > 0 {
+			c.ui.Output("fixes:\n  " + strings.Join(fixes, "\n  "))
+		}
+		if err == manager.ErrNotTidy {
+			return errors.Errorf("%s, please run 'dept tidy'", err)
+		}
+		return err
+	})
+}
+
+// NewTidy returns an initialized tidyCommand instance.
+func NewTidy(
+	ui cli.Ui,
+	gocmd gocmd.Command,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &tidyCommand{
+		f:  newTidyFlagSet(),
+		ui: ui,
+		manager: &manager.Manager{
+			GoCommand: gocmd,
+			Workspace: workspace,
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package cmd_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/ktr0731/dept/gocmd"
+)
+
+const untidyDeptfile = `module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2
+	honnef.co/go/tools:/cmd/keyify v0.0.2
+)
+`
+
+func TestTidyRun(t *testing.T) {
+	newGoCMD := func(t *testing.T) *gocmd.CommandMock {
+		return &gocmd.CommandMock{
+			ModTidyFunc: func(ctx context.Context, dir string) error {
+				b, err := ioutil.ReadFile(filepath.Join(dir, filegen.FileName))
+				if err != nil {
+					t.Fatalf("failed to read the generated file: %s", err)
+				}
+				for _, p := range []string{"github.com/ktr0731/evans", "honnef.co/go/tools/cmd/keyify", "honnef.co/go/tools/cmd/staticcheck", "honnef.co/go/tools/cmd/unused"} {
+					if !strings.Contains(string(b), p) {
+						t.Errorf("the generated file must import %s, but got:\n%s", p, string(b))
+					}
+				}
+				return nil
+			},
+		}
+	}
+
+	t.Run("Run normalizes gotool.mod", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, untidyDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		mockGoCMD := newGoCMD(t)
+		cmd := cmd.NewTidy(mockUI, mockGoCMD, &deptfile.Workspace{SourcePath: dir})
+		if code := cmd.Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if n := len(mockGoCMD.ModTidyCalls()); n != 1 {
+			t.Errorf("ModTidy must be called once, but actual %d", n)
+		}
+		if out := mockUI.Writer().String(); !strings.Contains(out, "merged duplicated requires of honnef.co/go/tools") {
+			t.Errorf("Run must show fixes, but got:\n%s", out)
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if e := "honnef.co/go/tools:/cmd/keyify,/cmd/unused,/cmd/staticcheck v0.0.2"; !strings.Contains(string(b), e) {
+			t.Errorf("%s must contain '%s', but got:\n%s", deptfile.FileName, e, string(b))
+		}
+	})
+
+	t.Run("Run removes tools which are duplicated between requires", func(t *testing.T) {
+		const data = `module tools
+
+require (
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2
+	honnef.co/go/tools:/cmd/unused@u v0.0.2
+)
+`
+		dir, cleanup := setupDeptfileDir(t, data)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		mockGoCMD := &gocmd.CommandMock{
+			ModTidyFunc: func(ctx context.Context, dir string) error { return nil },
+		}
+		if code := cmd.NewTidy(mockUI, mockGoCMD, &deptfile.Workspace{SourcePath: dir}).Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		if out := mockUI.Writer().String(); !strings.Contains(out, "removed duplicated tool honnef.co/go/tools/cmd/unused") {
+			t.Errorf("Run must show fixes, but got:\n%s", out)
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if e := "module tools\n\nrequire honnef.co/go/tools:/cmd/unused,/cmd/staticcheck v0.0.2\n"; string(b) != e {
+			t.Errorf("%s must be '%s', but got:\n%s", deptfile.FileName, e, string(b))
+		}
+	})
+
+	t.Run("Run reports duplicated tool paths in a require", func(t *testing.T) {
+		const data = "module tools\n\nrequire honnef.co/go/tools:/cmd/unused,/cmd/unused@u v0.0.2\n"
+		dir, cleanup := setupDeptfileDir(t, data)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		mockGoCMD := &gocmd.CommandMock{}
+		if code := cmd.NewTidy(mockUI, mockGoCMD, &deptfile.Workspace{SourcePath: dir}).Run(nil); code != 1 {
+			t.Fatalf("Run must return 1, but got %d", code)
+		}
+		if eout := mockUI.ErrorWriter().String(); !strings.Contains(eout, "duplicated tool path /cmd/unused") {
+			t.Errorf("Run must show the position of the duplicated tool, but got '%s'", eout)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if string(b) != data {
+			t.Errorf("%s must not be updated, but got:\n%s", deptfile.FileName, string(b))
+		}
+	})
+
+	t.Run("Run with -check fails without updating gotool.mod", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, untidyDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		cmd := cmd.NewTidy(mockUI, newGoCMD(t), &deptfile.Workspace{SourcePath: dir})
+		if code := cmd.Run([]string{"-check"}); code != 1 {
+			t.Fatalf("Run must return 1, but got %d", code)
+		}
+		if eout := mockUI.ErrorWriter().String(); !strings.Contains(eout, "not tidy") {
+			t.Errorf("Run must show the reason, but got '%s'", eout)
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if string(b) != untidyDeptfile {
+			t.Errorf("%s must not be updated, but got:\n%s", deptfile.FileName, string(b))
+		}
+	})
+
+	t.Run("Run with -check succeeds if gotool.mod is tidy", func(t *testing.T) {
+		dir, cleanup := setupDeptfileDir(t, untidyDeptfile)
+		defer cleanup()
+
+		mockUI := newMockUI()
+		if code := cmd.NewTidy(mockUI, newGoCMD(t), &deptfile.Workspace{SourcePath: dir}).Run(nil); code != 0 {
+			t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+		}
+		mockUI = newMockUI()
+		if code := cmd.NewTidy(mockUI, newGoCMD(t), &deptfile.Workspace{SourcePath: dir}).Run([]string{"-check"}); code != 0 {
+			t.Errorf("Run must return 0, but got %d (err = %s, out = %s)", code, mockUI.ErrorWriter().String(), mockUI.Writer().String())
+		}
+	})
+}
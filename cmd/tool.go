@@ -1,5 +0,0 @@
-package cmd
-
-type tool struct {
-	Path, Name, Version string
-}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/manager"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+)
+
+type undoFlagSet struct {
+	*flag.FlagSet
+
+	dryRun bool
+}
+
+func newUndoFlagSet() *undoFlagSet {
+	uf := &undoFlagSet{FlagSet: flag.NewFlagSet("undo", flag.ContinueOnError)}
+
+	// Suppress outputting by flag, delegate to cli.Command instead.
+	uf.SetOutput(ioutil.Discard)
+	uf.BoolVar(&uf.dryRun, "dry-run", false, "Show changes without updating gotool.mod")
+	uf.BoolVar(&uf.dryRun, "n", false, "Same as -dry-run")
+	return uf
+}
+
+// undoCommand restores gotool.mod and gotool.sum from the journal.
+type undoCommand struct {
+	f       *undoFlagSet
+	ui      cli.Ui
+	manager *manager.Manager
+}
+
+func (c *undoCommand) UI() cli.Ui {
+	return c.ui
+}
+
+var undoHelpTmpl = `Usage: dept undo [id]
+
+undo restores %s and %s to the snapshot which is taken
+before the last command updated them.
+If id is passed, undo restores them to the snapshot which has the id.
+Restored snapshot and newer ones are removed from the history.
+Available snapshots can be seen by 'dept history'.
+
+%s`
+
+func (c *undoCommand) Help() string {
+	return fmt.Sprintf(undoHelpTmpl, deptfile.FileName, deptfile.FileSumName, FlagUsage(c.f.FlagSet, false))
+}
+
+func (c *undoCommand) Synopsis() string {
+	return fmt.Sprintf("Restore %s to the previous state", deptfile.FileName)
+}
+
+func (c *undoCommand) Run(args []string) int {
+	if err := c.f.Parse(args); err != nil {
+		c.UI().Error(err.Error())
+		return 1
+	}
+	args = c.f.Args()
+
+	return run(c, func(ctx context.Context) error {
+		if len(args) > 1 {
+			return errShowHelp
+		}
+		var id int
+		if len(args) == 1 {
+			var err error
+			id, err = strconv.Atoi(args[0])
+			if err != nil || id <= 0 {
+				return errors.Errorf("invalid id '%s'", args[0])
+			}
+		}
+
+		dryRun := c.f.dryRun
+		opts := []manager.UndoOption{
+			manager.OnChange(func(changes *deptfile.Changes) {
+				reportChanges(c.ui, changes, dryRun)
+			}),
+		}
+		if dryRun {
+			opts = append(opts, manager.DryRun())
+		}
+
+		err := c.manager.Undo(ctx, id, opts...)
+		if errors.Cause(err) == manager.ErrNoJournal {
+			return errors.New("nothing to undo")
+		}
+		return err
+	})
+}
+
+// NewUndo returns an initialized undoCommand instance.
+func NewUndo(
+	ui cli.Ui,
+	workspace deptfile.Workspacer,
+) cli.Command {
+	return &undoCommand{
+		f:       newUndoFlagSet(),
+		ui:      ui,
+		manager: &manager.Manager{Workspace: workspace},
+	}
+}
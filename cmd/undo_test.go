@@ -0,0 +1,88 @@
+package cmd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ktr0731/dept/cmd"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/pkg/errors"
+)
+
+func TestUndoRun(t *testing.T) {
+	t.Run("Run restores the passed journal entry", func(t *testing.T) {
+		cases := map[string]struct {
+			args       []string
+			expectedID int
+			dryRun     bool
+		}{
+			"latest entry":       {expectedID: 0},
+			"specified entry":    {args: []string{"3"}, expectedID: 3},
+			"dry-run":            {args: []string{"-n"}, expectedID: 0, dryRun: true},
+			"dry-run with an id": {args: []string{"-n", "2"}, expectedID: 2, dryRun: true},
+		}
+		for name, c := range cases {
+			c := c
+			t.Run(name, func(t *testing.T) {
+				mockUI := newMockUI()
+				mockWorkspace := &deptfile.WorkspacerMock{
+					DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+						o := deptfile.NewOptions(opts...)
+						if o.Undo != c.expectedID {
+							t.Errorf("Undo option must be %d, but got %d", c.expectedID, o.Undo)
+						}
+						if o.DryRun != c.dryRun {
+							t.Errorf("DryRun option must be %t, but got %t", c.dryRun, o.DryRun)
+						}
+						if err := f("", "", &deptfile.File{}); err != nil {
+							return err
+						}
+						o.OnChange(&deptfile.Changes{
+							Tools: []*deptfile.Change{
+								{Path: "github.com/ktr0731/evans", OldVersion: "v0.2.0", NewVersion: "v0.1.0"},
+							},
+						})
+						return nil
+					},
+				}
+				cmd := cmd.NewUndo(mockUI, mockWorkspace)
+
+				code := cmd.Run(c.args)
+				if code != 0 {
+					t.Fatalf("Run must return 0, but got %d (err = %s)", code, mockUI.ErrorWriter().String())
+				}
+				if out := mockUI.Writer().String(); !strings.Contains(out, "~ github.com/ktr0731/evans v0.2.0 => v0.1.0") {
+					t.Errorf("Run must show restored changes, but got:\n%s", out)
+				}
+			})
+		}
+	})
+
+	t.Run("Run returns code 1 if there is nothing to undo", func(t *testing.T) {
+		mockUI := newMockUI()
+		mockWorkspace := &deptfile.WorkspacerMock{
+			DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+				return errors.Wrap(deptfile.ErrNoJournal, "failed to find the journal entry")
+			},
+		}
+		cmd := cmd.NewUndo(mockUI, mockWorkspace)
+
+		code := cmd.Run(nil)
+		if code != 1 {
+			t.Fatalf("Run must return 1, but got %d", code)
+		}
+		if eout := mockUI.ErrorWriter().String(); !strings.Contains(eout, "nothing to undo") {
+			t.Errorf("Run must show 'nothing to undo', but got '%s'", eout)
+		}
+	})
+
+	t.Run("Run returns code 1 if the id is invalid", func(t *testing.T) {
+		mockUI := newMockUI()
+		cmd := cmd.NewUndo(mockUI, &deptfile.WorkspacerMock{})
+
+		code := cmd.Run([]string{"foo"})
+		if code != 1 {
+			t.Fatalf("Run must return 1, but got %d", code)
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package deptfile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/mod/modfile"
+)
+
+// Changes represents differences of gotool.mod between before and after Do.
+type Changes struct {
+	// Tools are changes of managed tools.
+	// Each Path is the full import path of the tool.
+	Tools []*Change
+	// Indirect are changes of indirect requirements.
+	// Each Path is the module path.
+	Indirect []*Change
+	// ModDiff is the unified diff of gotool.mod.
+	// If gotool.mod is not changed, ModDiff is empty.
+	ModDiff string
+	// Sum is the summary of changes of gotool.sum.
+	Sum *SumChanges
+}
+
+// Empty returns true if c has no changes.
+func (c *Changes) Empty() bool {
+	return len(c.Tools) == 0 && len(c.Indirect) == 0 && c.ModDiff == "" && c.Sum.Empty()
+}
+
+// SumChanges represents changes of gotool.sum.
+// Each entry is formed as 'path version'.
+// Hashes of go.mod files and module contents are treated as one entry.
+type SumChanges struct {
+	Added   []string
+	Removed []string
+}
+
+// Empty returns true if s has no changes.
+func (s *SumChanges) Empty() bool {
+	return s == nil || (len(s.Added) == 0 && len(s.Removed) == 0)
+}
+
+// Change represents a change of a tool or a module.
+// If OldVersion is empty, it means the tool or the module is newly added.
+// If NewVersion is empty, it means the tool or the module is removed.
+type Change struct {
+	Path       string
+	OldVersion string
+	NewVersion string
+	// RequiredBy is the list of tool paths which pull in Path.
+	// It is set to indirect changes only.
+	RequiredBy []string
+}
+
+// Added returns true if c represents a newly added tool or module.
+func (c *Change) Added() bool {
+	return c.OldVersion == ""
+}
+
+// Removed returns true if c represents a removed tool or module.
+func (c *Change) Removed() bool {
+	return c.NewVersion == ""
+}
+
+// toolVersions returns a map which maps full tool paths to each version.
+func toolVersions(df *File) map[string]string {
+	m := map[string]string{}
+	if df == nil {
+		return m
+	}
+	for _, r := range df.Require {
+		r := r
+		forTools(r, func(p string) {
+			m[p] = r.Version
+		})
+	}
+	return m
+}
+
+// indirectVersions returns a map which maps module paths of indirect requirements to each version.
+func indirectVersions(f *modfile.File) map[string]string {
+	m := map[string]string{}
+	if f == nil {
+		return m
+	}
+	for _, r := range f.Require {
+		if r.Indirect {
+			m[r.Mod.Path] = r.Mod.Version
+		}
+	}
+	return m
+}
+
+// diffVersions compares before and after, then returns changes sorted by path.
+func diffVersions(before, after map[string]string) []*Change {
+	var changes []*Change
+	for p, ov := range before {
+		nv, ok := after[p]
+		if !ok {
+			changes = append(changes, &Change{Path: p, OldVersion: ov})
+			continue
+		}
+		if ov != nv {
+			changes = append(changes, &Change{Path: p, OldVersion: ov, NewVersion: nv})
+		}
+	}
+	for p, nv := range after {
+		if _, ok := before[p]; !ok {
+			changes = append(changes, &Change{Path: p, NewVersion: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// snapshot is a state of the deptfile which is used to compute changes.
+type snapshot struct {
+	// tools is the result of toolVersions.
+	tools map[string]string
+	// mod is the canonical modfile.
+	mod *modfile.File
+	// data and sum are contents of gotool.mod and gotool.sum.
+	data, sum []byte
+}
+
+// computeChanges compares the deptfile before Do and after Do.
+// newFile is the deptfile after Do.
+// If there are changes of indirect requirements which are added or updated,
+// computeChanges runs 'go mod graph' in dir to find tools which pull them in.
+func computeChanges(gocmd gocmd.Command, dir string, before, after *snapshot, newFile *File) (*Changes, error) {
+	oldMod, newMod := before.mod, after.mod
+
+	// Ignore modules which are moved from indirect requirements to tools and vice versa.
+	direct := map[string]bool{}
+	for _, f := range []*modfile.File{oldMod, newMod} {
+		if f == nil {
+			continue
+		}
+		for _, r := range f.Require {
+			if !r.Indirect {
+				direct[r.Mod.Path] = true
+			}
+		}
+	}
+	oldIndirect, newIndirect := indirectVersions(oldMod), indirectVersions(newMod)
+	for p := range direct {
+		delete(oldIndirect, p)
+		delete(newIndirect, p)
+	}
+
+	modDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before.data)),
+		B:        difflib.SplitLines(string(after.data)),
+		FromFile: "a/" + FileName,
+		ToFile:   "b/" + FileName,
+		Context:  3,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compute the diff of %s", FileName)
+	}
+
+	c := &Changes{
+		Tools:    diffVersions(before.tools, after.tools),
+		Indirect: diffVersions(oldIndirect, newIndirect),
+		ModDiff:  modDiff,
+		Sum:      diffSums(before.sum, after.sum),
+	}
+
+	var needGraph bool
+	for _, ch := range c.Indirect {
+		if !ch.Removed() {
+			needGraph = true
+			break
+		}
+	}
+	if !needGraph {
+		return c, nil
+	}
+
+	g, err := modGraph(gocmd, dir)
+	if err != nil {
+		return nil, err
+	}
+	mod2tools := map[string][]string{}
+	for _, r := range newFile.Require {
+		r := r
+		forTools(r, func(p string) {
+			mod2tools[r.Path] = append(mod2tools[r.Path], p)
+		})
+	}
+	for _, ch := range c.Indirect {
+		if ch.Removed() {
+			continue
+		}
+		for _, m := range g.requiredBy(ch.Path + "@" + ch.NewVersion) {
+			ch.RequiredBy = append(ch.RequiredBy, mod2tools[m]...)
+		}
+		sort.Strings(ch.RequiredBy)
+	}
+	return c, nil
+}
+
+// diffSums compares two go.sum contents.
+func diffSums(before, after []byte) *SumChanges {
+	oldEntries, newEntries := sumEntries(before), sumEntries(after)
+	var s SumChanges
+	for e := range oldEntries {
+		if !newEntries[e] {
+			s.Removed = append(s.Removed, e)
+		}
+	}
+	for e := range newEntries {
+		if !oldEntries[e] {
+			s.Added = append(s.Added, e)
+		}
+	}
+	sort.Strings(s.Added)
+	sort.Strings(s.Removed)
+	return &s
+}
+
+// sumEntries parses go.sum content, then returns a set of 'path version'.
+// A suffix '/go.mod' of each version is trimmed.
+func sumEntries(b []byte) map[string]bool {
+	m := map[string]bool{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		sp := strings.Fields(s.Text())
+		if len(sp) != 3 {
+			continue
+		}
+		m[sp[0]+" "+strings.TrimSuffix(sp[1], "/go.mod")] = true
+	}
+	return m
+}
+
+// forTools iterates tools of r, then pass each full tool path to f.
+func forTools(r *Require, f func(path string)) {
+	for _, t := range r.ToolPaths {
+		p := r.Path
+		if !isRootToolPath(t) {
+			p += t.Path
+		}
+		f(p)
+	}
+}
+
+// graph represents the module requirement graph which is the result of 'go mod graph'.
+// Each node is formed as 'path@version' except the main module.
+type graph struct {
+	main  string
+	edges map[string][]string
+}
+
+// modGraph runs 'go mod graph' in dir, then parses the result.
+func modGraph(gocmd gocmd.Command, dir string) (*graph, error) {
+	out, err := gocmd.ModGraph(context.Background(), dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the module graph")
+	}
+	return parseGraph(out), nil
+}
+
+func parseGraph(r io.Reader) *graph {
+	g := &graph{edges: map[string][]string{}}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		sp := strings.Fields(s.Text())
+		if len(sp) != 2 {
+			continue
+		}
+		if g.main == "" && !strings.Contains(sp[0], "@") {
+			g.main = sp[0]
+		}
+		g.edges[sp[0]] = append(g.edges[sp[0]], sp[1])
+	}
+	return g
+}
+
+// requiredBy returns module paths of direct requirements of the main module
+// which depend on target directly or indirectly.
+func (g *graph) requiredBy(target string) []string {
+	var mods []string
+	for _, d := range g.edges[g.main] {
+		if d == target {
+			continue
+		}
+		if g.reachable(d, target) {
+			mods = append(mods, modulePath(d))
+		}
+	}
+	return mods
+}
+
+func (g *graph) reachable(from, to string) bool {
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, next := range g.edges[n] {
+			if next == to {
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+func modulePath(node string) string {
+	if i := strings.Index(node, "@"); i != -1 {
+		return node[:i]
+	}
+	return node
+}
@@ -2,22 +2,32 @@ package deptfile
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 
-	"github.com/ktr0731/modfile"
-	"github.com/mitchellh/copystructure"
+	"github.com/hashicorp/go-multierror"
+	"github.com/ktr0731/dept/gocmd"
 	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
 )
 
 var (
 	FileName    = "gotool.mod"
 	FileSumName = "gotool.sum"
+	// LocalFileName is the developer-local deptfile which is merged on top of gotool.mod.
+	// It should be ignored by VCS.
+	LocalFileName    = "gotool.local.mod"
+	LocalFileSumName = "gotool.local.sum"
 )
 
+// EnvFile is the environment variable which specifies the path of gotool.mod.
+// It overrides the discovery by FindDir.
+const EnvFile = "DEPT_FILE"
+
 var (
 	// ErrNotFound represents deptfile not found.
 	ErrNotFound = errors.Errorf("%s not found", FileName)
@@ -28,7 +38,24 @@ var (
 // File represents the root struct of deptfile.
 type File struct {
 	Require []*Require
-	f       *modfile.File
+	Replace []*Replace
+	Exclude []*Exclude
+	// data is the original content of the deptfile.
+	data []byte
+	// dir is the dir which has the deptfile. Relative local dirs in Replace are relative to dir.
+	dir string
+	// base has entries of deptfiles which are included by the deptfile.
+	// It is nil if the deptfile includes nothing.
+	base *File
+	// includes are absolute paths of deptfiles which are included directly or indirectly.
+	includes []string
+	// indirects are indirect requirements of included deptfiles keyed by module paths.
+	// It is set only to base.
+	indirects map[string]string
+	// name is the file name of the deptfile.
+	name string
+	// origins are file names of deptfiles which have merged tools keyed by import paths.
+	origins map[string]string
 }
 
 // Require represents a parsed direct requirement.
@@ -39,6 +66,9 @@ type Require struct {
 	ToolPaths []*Tool
 }
 
+// format formats r in the canonical form.
+// If r has only the tool in the module root, it is formatted as 'module[@name]'.
+// Otherwise, it is formatted as 'module:/path[@name],/path[@name]' in the order of ToolPaths.
 func (r *Require) format() string {
 	s := r.Path
 	if len(r.ToolPaths) == 1 && isRootToolPath(r.ToolPaths[0]) {
@@ -95,7 +125,13 @@ func (t *Tool) format() string {
 // Also parseDeptfile returns the canonical modfile. It has been removed command paths.
 // So, it is go.mod compatible.
 //
+// A deptfile can include other deptfiles by '//dept:include path' lines.
+// Their tools are merged into the returned File and the canonical modfile,
+// and entries of the deptfile take precedence over included ones.
+//
 // parseDeptfile returns ErrNotFound if fname is not found.
+// If some tool entries are malformed or conflicted, parseDeptfile returns
+// all of them as *SyntaxError which are aggregated by multierror.
 func parseDeptfile(fname string) (*File, *modfile.File, error) {
 	data, err := ioutil.ReadFile(fname)
 	if os.IsNotExist(err) {
@@ -104,47 +140,77 @@ func parseDeptfile(fname string) (*File, *modfile.File, error) {
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed to open %s", fname)
 	}
-	f, err := modfile.Parse(filepath.Base(fname), data, nil)
+	return parseWithIncludes(fname, data)
+}
+
+// parse parses data as a deptfile.
+// See parseDeptfile for details.
+func parse(fname string, data []byte) (*File, *modfile.File, error) {
+	f, err := parseModFile(fname, data, false)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to parse %s", fname)
+		return nil, nil, err
 	}
 
-	tmp, err := copystructure.Copy(f)
+	// Parse data again instead of copying f to keep comments which are attached to each line.
+	canonical, err := parseModFile(fname, data, true)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to deep copy modfile.File")
+		return nil, nil, err
 	}
-	canonical := tmp.(*modfile.File)
 
 	// Convert from modfile.File.Require to deptfile.Require.
+	var merr *multierror.Error
+	lines := strings.Split(string(data), "\n")
+	names := map[string]*toolPos{}
 	requires := make([]*Require, 0, len(f.Require))
-	for i, r := range f.Require {
+	for _, r := range f.Require {
 		// Skip indirect requirements because deptfile focuses on direct requirements (= managed tools) only.
 		if r.Indirect {
 			continue
 		}
 
-		var toolPaths []*Tool
-		path := r.Mod.Path
-
-		// If main package is not in the module root.
-		// Else number of tools is 1 and it is in the module root package.
-		if i := strings.LastIndex(r.Mod.Path, ":"); i != -1 {
-			path = r.Mod.Path[:i]
-			for _, toolPath := range strings.Split(r.Mod.Path[i+1:], ",") {
-				if i := strings.LastIndex(toolPath, "@"); i != -1 {
-					toolPaths = append(toolPaths, &Tool{Path: toolPath[:i], Name: toolPath[i+1:]})
-				} else {
-					toolPaths = append(toolPaths, &Tool{Path: toolPath})
+		// position returns the position of the offset in the path of r.
+		position := func(offset int) (int, int) {
+			line := r.Syntax.Start.Line
+			col := 1
+			if line-1 < len(lines) {
+				if i := strings.Index(lines[line-1], r.Mod.Path); i != -1 {
+					col = i + 1
 				}
 			}
-		} else {
-			toolPath := r.Mod.Path
-			if i := strings.LastIndex(toolPath, "@"); i != -1 {
-				toolPaths = append(toolPaths, &Tool{Path: "/", Name: toolPath[i+1:]})
-				path = path[:i]
-			} else {
-				toolPaths = append(toolPaths, &Tool{Path: "/"})
+			return line, col + offset
+		}
+		syntaxErr := func(offset int, format string, a ...interface{}) {
+			line, col := position(offset)
+			merr = multierror.Append(merr, &SyntaxError{
+				Filename: filepath.Base(fname),
+				Line:     line,
+				Col:      col,
+				Msg:      fmt.Sprintf(format, a...),
+			})
+		}
+
+		path, toolPaths, offsets, err := parseRequirePath(r.Mod.Path)
+		if err != nil {
+			syntaxErr(err.offset, err.msg)
+			continue
+		}
+
+		found := map[string]bool{}
+		for j, t := range toolPaths {
+			importPath := joinToolPath(path, t.Path)
+			if found[t.Path] {
+				syntaxErr(offsets[j], "duplicated tool path %s", t.Path)
+				continue
+			}
+			found[t.Path] = true
+
+			line, col := position(offsets[j])
+			name := toolName(importPath, t.Name)
+			if another, ok := names[name]; ok && another.importPath != importPath {
+				syntaxErr(offsets[j], "tool name '%s' is already used by %s at %d:%d", name, another.importPath, another.line, another.col)
+				continue
 			}
+			names[name] = &toolPos{importPath: importPath, line: line, col: col}
 		}
 
 		requires = append(requires, &Require{
@@ -152,11 +218,29 @@ func parseDeptfile(fname string) (*File, *modfile.File, error) {
 			Version:   r.Mod.Version,
 			ToolPaths: toolPaths,
 		})
-		canonical.Require[i].Mod.Path = path
-		canonical.Require[i].Syntax.Token[0] = path
 	}
-	canonical.SetRequire(canonical.Require)
-	return &File{Require: requires, f: f}, canonical, nil
+	if merr != nil {
+		merr.ErrorFormat = formatSyntaxErrors
+		return nil, nil, merr
+	}
+
+	df := &File{Require: requires, data: data, dir: filepath.Dir(fname), name: fname}
+	for _, r := range f.Replace {
+		df.Replace = append(df.Replace, &Replace{
+			Path:       r.Old.Path,
+			Version:    r.Old.Version,
+			NewPath:    r.New.Path,
+			NewVersion: r.New.Version,
+			dir:        df.dir,
+		})
+	}
+	for _, e := range f.Exclude {
+		df.Exclude = append(df.Exclude, &Exclude{Path: e.Mod.Path, Version: e.Mod.Version})
+	}
+	if err := resolveReplaceDirs(canonical, df.dir); err != nil {
+		return nil, nil, err
+	}
+	return df, canonical, nil
 }
 
 func convertGoModToDeptfile(fname string, gomod *File) (*modfile.File, error) {
@@ -167,9 +251,9 @@ func convertGoModToDeptfile(fname string, gomod *File) (*modfile.File, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to open %s", fname)
 	}
-	f, err := modfile.Parse(filepath.Base(fname), data, nil)
+	f, err := parseModFile(fname, data, false)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse %s", fname)
+		return nil, err
 	}
 
 	// no any additional information
@@ -191,38 +275,229 @@ func convertGoModToDeptfile(fname string, gomod *File) (*modfile.File, error) {
 		if !ok {
 			continue
 		}
-		p := req.format()
-		f.Require[i].Mod.Path = p
+		setRequirePath(f.Require[i], req.format())
+	}
+
+	restoreReplaceDirs(f, gomod)
+
+	f.SetRequire(f.Require)
+	gomod.dropBase(f)
+	// SetRequire marks duplicated requires as removed. Cleanup drops them actually.
+	f.Cleanup()
+
+	return f, nil
+}
+
+// SyntaxError represents a malformed or conflicting tool entry in deptfile.
+type SyntaxError struct {
+	Filename  string
+	Line, Col int
+	Msg       string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Col, e.Msg)
+}
+
+// formatSyntaxErrors formats errs one per line like the go command.
+func formatSyntaxErrors(errs []error) string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// toolPos represents the position of a tool in deptfile.
+type toolPos struct {
+	importPath string
+	line, col  int
+}
+
+// pathError represents a malformed part of a deptfile formed path.
+type pathError struct {
+	// offset is the byte offset of the malformed part in the path.
+	offset int
+	msg    string
+}
+
+// parseRequirePath parses p which is a deptfile formed path like 'module:/path@name,/path'.
+// parseRequirePath returns the module path, tools and byte offsets of each tool in p.
+func parseRequirePath(p string) (string, []*Tool, []int, *pathError) {
+	i := strings.Index(p, ":")
+	if i == -1 {
+		// Special case. The tool is in the module root.
+		mod, name := p, ""
+		if i := strings.Index(p, "@"); i != -1 {
+			mod, name = p[:i], p[i+1:]
+			if err := checkParsedToolName(name); err != nil {
+				return "", nil, nil, &pathError{offset: i + 1, msg: err.Error()}
+			}
+		}
+		if mod == "" {
+			return "", nil, nil, &pathError{msg: "empty module path"}
+		}
+		return mod, []*Tool{{Path: "/", Name: name}}, []int{0}, nil
+	}
 
-		// require statement is oneline.
-		if f.Require[i].Syntax.Token[0] == "require" {
-			f.Require[i].Syntax.Token[1] = p
-		} else {
-			f.Require[i].Syntax.Token[0] = p
+	mod := p[:i]
+	if mod == "" {
+		return "", nil, nil, &pathError{msg: "empty module path"}
+	}
+	if i == len(p)-1 {
+		return "", nil, nil, &pathError{offset: i + 1, msg: "no tool paths after ':'"}
+	}
+	var tools []*Tool
+	var offsets []int
+	offset := i + 1
+	for _, s := range strings.Split(p[i+1:], ",") {
+		t := &Tool{Path: s}
+		if i := strings.Index(s, "@"); i != -1 {
+			t.Path, t.Name = s[:i], s[i+1:]
+			if err := checkParsedToolName(t.Name); err != nil {
+				return "", nil, nil, &pathError{offset: offset + i + 1, msg: err.Error()}
+			}
 		}
+		switch {
+		case t.Path == "":
+			return "", nil, nil, &pathError{offset: offset, msg: "empty tool path"}
+		case !strings.HasPrefix(t.Path, "/"):
+			return "", nil, nil, &pathError{offset: offset, msg: fmt.Sprintf("tool path %s must start with '/'", t.Path)}
+		case path.Clean(t.Path) != t.Path:
+			return "", nil, nil, &pathError{offset: offset, msg: fmt.Sprintf("tool path %s must be clean", t.Path)}
+		}
+		tools = append(tools, t)
+		offsets = append(offsets, offset)
+		offset += len(s) + 1
 	}
+	return mod, tools, offsets, nil
+}
 
-	f.SetRequire(f.Require)
+func checkParsedToolName(name string) error {
+	if name == "" {
+		return errors.New("empty tool name")
+	}
+	if err := checkToolName(name); err != nil {
+		return errors.Errorf("invalid tool name '%s'", name)
+	}
+	return nil
+}
 
+// parseModFile parses data which is a deptfile as a modfile.
+// The modfile lexer splits tool paths by ',', so parseModFile masks deptfile specific parts of
+// require paths (tool paths and names) by spaces before parsing, and restores them after that.
+// Masking by spaces keeps positions of all tokens.
+// If canonical is true, parseModFile doesn't restore them. It means the returned modfile is go.mod compatible.
+func parseModFile(fname string, data []byte, canonical bool) (*modfile.File, error) {
+	masked, paths := maskToolPaths(data)
+	f, err := modfile.Parse(filepath.Base(fname), masked, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", fname)
+	}
+	if canonical {
+		return f, nil
+	}
+	for _, r := range f.Require {
+		if p, ok := paths[r.Syntax.Start.Line]; ok {
+			setRequirePath(r, p)
+		}
+	}
 	return f, nil
 }
 
-// Create creates a new deptfile.
+// maskToolPaths replaces deptfile specific parts of module paths in data by spaces.
+// Not only require but also replace and exclude directives can have these parts, and they are just ignored.
+// maskToolPaths returns the masked data and original require paths keyed by line numbers.
+func maskToolPaths(data []byte) ([]byte, map[int]string) {
+	masked := make([]byte, 0, len(data))
+	paths := map[int]string{}
+	var block string
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		verb := block
+		switch {
+		case block != "" && strings.HasPrefix(trimmed, ")"):
+			block = ""
+			trimmed = ""
+		case block != "":
+		default:
+			verb = ""
+			for _, v := range []string{"require", "replace", "exclude"} {
+				if strings.HasPrefix(trimmed, v) && len(trimmed) > len(v) && strings.ContainsRune(" \t(", rune(trimmed[len(v)])) {
+					verb = v
+				}
+			}
+			if verb == "" {
+				trimmed = ""
+				break
+			}
+			trimmed = strings.TrimLeft(trimmed[len(verb):], " \t")
+			if strings.HasPrefix(trimmed, "(") {
+				block = verb
+				trimmed = ""
+			}
+		}
+
+		tok := trimmed
+		if j := strings.IndexAny(tok, " \t\r\n"); j != -1 {
+			tok = tok[:j]
+		}
+		j := strings.IndexAny(tok, ":@")
+		if strings.HasPrefix(tok, "//") || j == -1 {
+			masked = append(masked, line...)
+			continue
+		}
+		if verb == "require" {
+			paths[i+1] = tok
+		}
+		start := len(line) - len(trimmed) + j
+		end := len(line) - len(trimmed) + len(tok)
+		masked = append(masked, line[:start]...)
+		masked = append(masked, strings.Repeat(" ", end-start)...)
+		masked = append(masked, line[end:]...)
+	}
+	return masked, paths
+}
+
+// setRequirePath replaces the path of r by p.
+func setRequirePath(r *modfile.Require, p string) {
+	r.Mod.Path = p
+	// require statement is oneline.
+	if r.Syntax.Token[0] == "require" {
+		r.Syntax.Token[1] = p
+	} else {
+		r.Syntax.Token[0] = p
+	}
+}
+
+// Create creates a new deptfile in dir by gocmd.
+// If gocmd is nil, gocmd.New is used.
+// If dir is empty, Create creates it in the dir specified by EnvFile, or the current dir if EnvFile is not set.
 // If already created, Create returns ErrAlreadyExist.
-func Create(ctx context.Context) error {
-	if _, err := os.Stat(FileName); err == nil {
+func Create(ctx context.Context, gocmd gocmd.Command, dir string) error {
+	if dir == "" {
+		var err error
+		dir, err = envDir()
+		if err != nil {
+			return err
+		}
+	}
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName)); err == nil {
 		return ErrAlreadyExist
 	}
 
 	var err error
 	w := &Workspace{
-		SourcePath: ".",
+		SourcePath: dir,
 		DoNotCopy:  true,
+		GoCommand:  gocmd,
 	}
-	err = w.Do(func(string, *File) error {
+	err = w.Do(func(_, workDir string, _ *File) error {
 		// TODO: module name
-		err = exec.CommandContext(ctx, "go", "mod", "init", "tools").Run()
-		if err != nil {
+		if err := w.gocmd().ModInit(ctx, workDir, "tools"); err != nil {
 			return errors.Wrap(err, "failed to init Go modules")
 		}
 		return nil
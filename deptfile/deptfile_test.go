@@ -9,8 +9,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ktr0731/dept/deptfile"
 	"github.com/ktr0731/dept/fileutil"
+	"github.com/ktr0731/dept/gocmd"
 )
 
 var (
@@ -64,7 +66,7 @@ func TestCreate(t *testing.T) {
 		cleanup := setupEnv(t, filepath.Join("testdata", "normal"))
 		defer cleanup()
 
-		err := deptfile.Create(context.Background())
+		err := deptfile.Create(context.Background(), nil, "")
 		if err == nil {
 			t.Error("Create must return an error, but got nil")
 		}
@@ -81,7 +83,7 @@ func TestCreate(t *testing.T) {
 			t.Fatalf("failed to remove go.sum from the temp dir: %s", err)
 		}
 
-		err := deptfile.Create(context.Background())
+		err := deptfile.Create(context.Background(), nil, "")
 		if err != nil {
 			t.Fatalf("Create must not return an error, but got: %s", err)
 		}
@@ -90,4 +92,108 @@ func TestCreate(t *testing.T) {
 			t.Error("after Create called, deptfile is in current dir, but missing")
 		}
 	})
+
+	t.Run("Create runs 'go mod init' by the passed command", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		mockGoCMD := &gocmd.CommandMock{
+			ModInitFunc: func(ctx context.Context, dir, modPath string) error {
+				return ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+modPath+"\n"), 0644)
+			},
+		}
+		if err := deptfile.Create(context.Background(), mockGoCMD, dir); err != nil {
+			t.Fatalf("Create must not return an error, but got: %s", err)
+		}
+		calls := mockGoCMD.ModInitCalls()
+		if len(calls) != 1 {
+			t.Fatalf("ModInit must be called once, but got %d", len(calls))
+		}
+		if calls[0].Dir == "" || calls[0].ModPath != "tools" {
+			t.Errorf("ModInit must be called with the work dir and 'tools', but got dir = '%s', path = '%s'", calls[0].Dir, calls[0].ModPath)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if string(b) != "module tools\n" {
+			t.Errorf("%s must be created by 'go mod init', but got '%s'", deptfile.FileName, string(b))
+		}
+	})
+}
+
+func TestFindDir(t *testing.T) {
+	cleanup := setupEnv(t, filepath.Join("testdata", "normal"))
+	defer cleanup()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current dir: %s", err)
+	}
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create a sub dir: %s", err)
+	}
+	if err := os.Chdir(sub); err != nil {
+		t.Fatalf("failed to change the current dir: %s", err)
+	}
+
+	t.Run("FindDir returns the nearest dir which has gotool.mod", func(t *testing.T) {
+		actual, err := deptfile.FindDir()
+		if err != nil {
+			t.Fatalf("FindDir must not return an error, but got '%s'", err)
+		}
+		if actual != dir {
+			t.Errorf("expected '%s', but got '%s'", dir, actual)
+		}
+	})
+
+	t.Run("FindDir returns the dir of DEPT_FILE", func(t *testing.T) {
+		t.Setenv(deptfile.EnvFile, filepath.Join("..", deptfile.FileName))
+		actual, err := deptfile.FindDir()
+		if err != nil {
+			t.Fatalf("FindDir must not return an error, but got '%s'", err)
+		}
+		if expected := filepath.Join(dir, "a"); actual != expected {
+			t.Errorf("expected '%s', but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("FindDir returns an error if DEPT_FILE is not gotool.mod", func(t *testing.T) {
+		t.Setenv(deptfile.EnvFile, "go.mod")
+		if _, err := deptfile.FindDir(); err == nil {
+			t.Error("FindDir must return an error, but got nil")
+		}
+	})
+}
+
+func TestFindAll(t *testing.T) {
+	root, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+	for _, dir := range []string{"", "a", filepath.Join("a", "b"), "c", ".git", "_tools", "vendor", filepath.Join("c", "testdata")} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("failed to create a dir: %s", err)
+		}
+		if dir == "c" {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, dir, deptfile.FileName), []byte("module tools\n"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+		}
+	}
+
+	dirs, err := deptfile.FindAll(root)
+	if err != nil {
+		t.Fatalf("FindAll must not return an error, but got '%s'", err)
+	}
+	expected := []string{root, filepath.Join(root, "a"), filepath.Join(root, "a", "b")}
+	if diff := cmp.Diff(expected, dirs); diff != "" {
+		t.Errorf("FindAll returned unexpected dirs:\n%s", diff)
+	}
 }
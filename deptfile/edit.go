@@ -0,0 +1,446 @@
+package deptfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+var (
+	// ErrToolNotFound represents the specified tool is not found in deptfile.
+	ErrToolNotFound = errors.New("tool not found")
+	// ErrModuleNotFound represents the specified module is not found in deptfile.
+	ErrModuleNotFound = errors.New("module not found")
+	// ErrToolNameConflicted represents the output name of a tool is already used by another tool.
+	ErrToolNameConflicted = errors.New("tool names conflicted")
+	// ErrInvalidPath represents the passed module path, tool path or tool name is invalid.
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// Parse parses data as a deptfile.
+// Parse doesn't touch the filesystem.
+func Parse(data []byte) (*File, error) {
+	df, _, err := parse(FileName, data)
+	if err != nil {
+		return nil, err
+	}
+	return df, nil
+}
+
+// Format formats f as a deptfile.
+// Requirements in each block are sorted by the module path.
+// Comments and statements other than direct requirements are kept as it is.
+// Each require must have the version.
+// Entries which are merged from included deptfiles are not formatted unless they are changed.
+func (f *File) Format() ([]byte, error) {
+	return f.local().format()
+}
+
+func (f *File) format() ([]byte, error) {
+	mf, err := f.modFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// A module may be required by several lines, so match each line with a Require one-to-one.
+	path2reqs := make(map[string][]*Require, len(f.Require))
+	for _, r := range f.Require {
+		if r.Version == "" {
+			return nil, errors.Errorf("%s has no version", r.Path)
+		}
+		path2reqs[r.Path] = append(path2reqs[r.Path], r)
+	}
+
+	matched := make(map[*Require]bool, len(f.Require))
+	for _, r := range mf.Require {
+		if r.Indirect {
+			continue
+		}
+		p := requirePath(r.Mod.Path)
+		reqs := path2reqs[p]
+		if len(reqs) == 0 {
+			dropRequire(r)
+			continue
+		}
+		req := reqs[0]
+		path2reqs[p] = reqs[1:]
+		matched[req] = true
+
+		setRequirePath(r, req.format())
+		r.Mod.Version = req.Version
+		r.Syntax.Token[len(r.Syntax.Token)-1] = req.Version
+	}
+	// Keep the order of f.Require.
+	for _, r := range f.Require {
+		if !matched[r] {
+			mf.AddNewRequire(r.Path, r.Version, false)
+			// AddNewRequire quotes paths which have ',', so set the path directly.
+			setRequirePath(mf.Require[len(mf.Require)-1], r.format())
+		}
+	}
+	if err := f.syncReplaceAndExclude(mf); err != nil {
+		return nil, err
+	}
+	mf.SortBlocks()
+	mf.Cleanup()
+
+	return mf.Format()
+}
+
+// syncReplaceAndExclude applies Replace and Exclude of f to mf.
+// Directives which are not changed are kept as it is.
+func (f *File) syncReplaceAndExclude(mf *modfile.File) error {
+	for _, r := range mf.Replace {
+		if r.Old.Path == "" {
+			// Already dropped.
+			continue
+		}
+		var found bool
+		for _, another := range f.Replace {
+			if another.Path == r.Old.Path && another.Version == r.Old.Version {
+				found = true
+			}
+		}
+		if !found {
+			mf.DropReplace(r.Old.Path, r.Old.Version)
+		}
+	}
+	for _, r := range f.Replace {
+		if cur := lookupModReplace(mf, r.Path, r.Version); cur != nil && cur.New.Path == r.NewPath && cur.New.Version == r.NewVersion {
+			continue
+		}
+		if err := mf.AddReplace(r.Path, r.Version, r.NewPath, r.NewVersion); err != nil {
+			return errors.Wrapf(err, "invalid replace of %s", r.Path)
+		}
+	}
+
+	for _, e := range mf.Exclude {
+		var found bool
+		for _, another := range f.Exclude {
+			if another.Path == e.Mod.Path && another.Version == e.Mod.Version {
+				found = true
+			}
+		}
+		if !found {
+			mf.DropExclude(e.Mod.Path, e.Mod.Version)
+		}
+	}
+	for _, e := range f.Exclude {
+		if err := mf.AddExclude(e.Path, e.Version); err != nil {
+			return errors.Wrapf(err, "invalid exclude of %s", e.Path)
+		}
+	}
+	return nil
+}
+
+func lookupModReplace(mf *modfile.File, path, version string) *modfile.Replace {
+	for _, r := range mf.Replace {
+		if r.Old.Path == path && r.Old.Version == version {
+			return r
+		}
+	}
+	return nil
+}
+
+// modFile parses the original content of f as a modfile.
+// If f is not created by Parse, modFile returns an empty one.
+func (f *File) modFile() (*modfile.File, error) {
+	data := f.data
+	if data == nil {
+		data = []byte("module tools\n")
+	}
+	return parseModFile(FileName, data, false)
+}
+
+// dropRequire drops only the line of r.
+// modfile.File.DropRequire drops all lines which have the same path.
+func dropRequire(r *modfile.Require) {
+	r.Syntax.Token = nil
+	r.Syntax.Comments.Suffix = nil
+	*r = modfile.Require{}
+}
+
+// requirePath returns the module path of p which is a deptfile formed path.
+func requirePath(p string) string {
+	if i := strings.LastIndex(p, ":"); i != -1 {
+		return p[:i]
+	}
+	if i := strings.LastIndex(p, "@"); i != -1 {
+		return p[:i]
+	}
+	return p
+}
+
+// AddTool adds the tool which is located in toolPath of the module modPath to f.
+// toolPath is the absolute path from the module root. "/" or empty means the module root.
+// If name is empty, the output name is filepath.Base of the import path.
+//
+// If the module is not managed yet, AddTool adds a new Require without the version.
+// The version must be set by SetVersion before calling Format.
+// If the tool is already managed, AddTool renames it to name if name is not empty.
+//
+// AddTool returns ErrInvalidPath if the passed paths are invalid,
+// and returns ErrToolNameConflicted if the output name is already used by another tool.
+func (f *File) AddTool(modPath, toolPath, name string) error {
+	if toolPath == "" {
+		toolPath = "/"
+	}
+	if err := checkModulePath(modPath); err != nil {
+		return err
+	}
+	if err := checkToolPath(toolPath); err != nil {
+		return err
+	}
+	if name != "" {
+		if err := checkToolName(name); err != nil {
+			return err
+		}
+	}
+
+	importPath := joinToolPath(modPath, toolPath)
+	if r, t := f.lookupTool(importPath); t != nil {
+		if name == "" {
+			return nil
+		}
+		return f.rename(r, t, name)
+	}
+
+	if err := f.checkConflict(importPath, toolName(importPath, name)); err != nil {
+		return err
+	}
+
+	t := &Tool{Path: toolPath, Name: name}
+	for _, r := range f.Require {
+		if r.Path == modPath {
+			r.ToolPaths = append(r.ToolPaths, t)
+			sortTools(r.ToolPaths)
+			return nil
+		}
+	}
+	f.Require = append(f.Require, &Require{Path: modPath, ToolPaths: []*Tool{t}})
+	return nil
+}
+
+// RemoveTool removes the tool which has importPath from f.
+// If the module has no tools after removing, the module is also removed.
+// RemoveTool returns ErrToolNotFound if the tool is not managed.
+func (f *File) RemoveTool(importPath string) error {
+	r, t := f.lookupTool(importPath)
+	if t == nil {
+		return errors.Wrap(ErrToolNotFound, importPath)
+	}
+	tools := make([]*Tool, 0, len(r.ToolPaths)-1)
+	for _, another := range r.ToolPaths {
+		if another != t {
+			tools = append(tools, another)
+		}
+	}
+	r.ToolPaths = tools
+	if len(tools) != 0 {
+		return nil
+	}
+
+	reqs := make([]*Require, 0, len(f.Require)-1)
+	for _, another := range f.Require {
+		if another != r {
+			reqs = append(reqs, another)
+		}
+	}
+	f.Require = reqs
+	return nil
+}
+
+// RenameTool renames the output name of the tool which has oldName to newName.
+// RenameTool returns ErrToolNotFound if oldName is not found,
+// and returns ErrToolNameConflicted if newName is already used by another tool.
+func (f *File) RenameTool(oldName, newName string) error {
+	if err := checkToolName(newName); err != nil {
+		return err
+	}
+	for _, r := range f.Require {
+		for _, t := range r.ToolPaths {
+			if toolName(joinToolPath(r.Path, t.Path), t.Name) == oldName {
+				return f.rename(r, t, newName)
+			}
+		}
+	}
+	return errors.Wrap(ErrToolNotFound, oldName)
+}
+
+// SetVersion sets version to the module which has modPath.
+// SetVersion returns ErrModuleNotFound if the module is not managed.
+func (f *File) SetVersion(modPath, version string) error {
+	if version == "" || strings.ContainsAny(version, " \t\n,:@") {
+		return errors.Wrapf(ErrInvalidPath, "invalid version '%s'", version)
+	}
+	for _, r := range f.Require {
+		if r.Path == modPath {
+			r.Version = version
+			return nil
+		}
+	}
+	return errors.Wrap(ErrModuleNotFound, modPath)
+}
+
+func (f *File) rename(r *Require, t *Tool, name string) error {
+	importPath := joinToolPath(r.Path, t.Path)
+	if err := f.checkConflict(importPath, name); err != nil {
+		return err
+	}
+	// Omit the name if it is the same as the default one.
+	if name == path.Base(importPath) {
+		name = ""
+	}
+	t.Name = name
+	return nil
+}
+
+// lookupTool finds the tool which has importPath.
+func (f *File) lookupTool(importPath string) (*Require, *Tool) {
+	for _, r := range f.Require {
+		for _, t := range r.ToolPaths {
+			if joinToolPath(r.Path, t.Path) == importPath {
+				return r, t
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkConflict returns ErrToolNameConflicted if name is already used by a tool other than importPath.
+func (f *File) checkConflict(importPath, name string) error {
+	for _, r := range f.Require {
+		for _, t := range r.ToolPaths {
+			p := joinToolPath(r.Path, t.Path)
+			if p != importPath && toolName(p, t.Name) == name {
+				return errors.Wrapf(ErrToolNameConflicted, "%s and %s are named as '%s'", importPath, p, name)
+			}
+		}
+	}
+	return nil
+}
+
+func checkModulePath(p string) error {
+	if p == "" || strings.ContainsAny(p, " \t\n,:@") || strings.HasPrefix(p, "/") || path.Clean(p) != p {
+		return errors.Wrapf(ErrInvalidPath, "invalid module path '%s'", p)
+	}
+	return nil
+}
+
+func checkToolPath(p string) error {
+	if !strings.HasPrefix(p, "/") || strings.ContainsAny(p, " \t\n,:@") || path.Clean(p) != p {
+		return errors.Wrapf(ErrInvalidPath, "invalid tool path '%s'", p)
+	}
+	return nil
+}
+
+func checkToolName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, " \t\n,:@/\\") {
+		return errors.Wrapf(ErrInvalidPath, "invalid tool name '%s'", name)
+	}
+	return nil
+}
+
+// joinToolPath returns the import path of the tool which is located in toolPath of modPath.
+func joinToolPath(modPath, toolPath string) string {
+	if toolPath == "/" {
+		return modPath
+	}
+	return modPath + toolPath
+}
+
+// toolName returns the output name of the tool.
+// If name is empty, it means the output name is the same as path.Base(importPath).
+func toolName(importPath, name string) string {
+	if name != "" {
+		return name
+	}
+	return path.Base(importPath)
+}
+
+// sortTools sorts tools so that the tool which is near the module root comes first.
+// Tools which have the same length are sorted in lexical order.
+func sortTools(tools []*Tool) {
+	sort.Slice(tools, func(i, j int) bool {
+		return toolLess(tools[i], tools[j])
+	})
+}
+
+func toolLess(t1, t2 *Tool) bool {
+	if len(t1.Path) != len(t2.Path) {
+		return len(t1.Path) < len(t2.Path)
+	}
+	return t1.Path < t2.Path
+}
+
+// Normalize merges requires which have the same module path and sorts tool paths of each require.
+// Tools which are duplicated between merged requires are removed.
+// Duplicated tools in a single require are never repaired because parse rejects them.
+// Normalize returns descriptions of fixes.
+func (f *File) Normalize() []string {
+	var fixes []string
+	reqs := make([]*Require, 0, len(f.Require))
+	path2req := make(map[string]*Require, len(f.Require))
+	for _, r := range f.Require {
+		merged, ok := path2req[r.Path]
+		if !ok {
+			path2req[r.Path] = r
+			reqs = append(reqs, r)
+			continue
+		}
+		fixes = append(fixes, fmt.Sprintf("merged duplicated requires of %s", r.Path))
+		found := make(map[string]bool, len(merged.ToolPaths))
+		for _, t := range merged.ToolPaths {
+			found[t.Path] = true
+		}
+		for _, t := range r.ToolPaths {
+			if found[t.Path] {
+				fixes = append(fixes, fmt.Sprintf("removed duplicated tool %s", joinToolPath(r.Path, t.Path)))
+				continue
+			}
+			found[t.Path] = true
+			merged.ToolPaths = append(merged.ToolPaths, t)
+		}
+	}
+	f.Require = reqs
+
+	for _, r := range f.Require {
+		tools := r.ToolPaths
+		if !sort.SliceIsSorted(tools, func(i, j int) bool { return toolLess(tools[i], tools[j]) }) {
+			fixes = append(fixes, fmt.Sprintf("sorted tool paths of %s", r.Path))
+			sortTools(tools)
+		}
+	}
+	return fixes
+}
+
+// WriteGoMod writes df to dir as a go.mod.
+// It is used in Workspacer.Do to apply changes of df which are made without the go command.
+func WriteGoMod(dir string, df *File) error {
+	b, err := df.Format()
+	if err != nil {
+		return err
+	}
+	name := df.name
+	if name == "" {
+		name = filepath.Join(df.dir, FileName)
+	}
+	_, canonical, err := parseWithIncludes(name, b)
+	if err != nil {
+		return err
+	}
+	b, err = canonical.Format()
+	if err != nil {
+		return errors.Wrap(err, "failed to format canonicalized modfile")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), b, 0644); err != nil {
+		return errors.Wrap(err, "failed to write out go.mod")
+	}
+	return nil
+}
@@ -0,0 +1,572 @@
+package deptfile_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/pkg/errors"
+)
+
+const editTestData = `// managed by dept
+module test
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	// itunes
+	github.com/ktr0731/itunes-cli:/itunes v0.0.1
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused@u v0.0.2
+)
+`
+
+func parseEditTestData(t *testing.T) *deptfile.File {
+	t.Helper()
+	df, err := deptfile.Parse([]byte(editTestData))
+	if err != nil {
+		t.Fatalf("Parse must not return an error, but got '%s'", err)
+	}
+	return df
+}
+
+func testFormat(t *testing.T, df *deptfile.File, expected string) {
+	t.Helper()
+	b, err := df.Format()
+	if err != nil {
+		t.Fatalf("Format must not return an error, but got '%s'", err)
+	}
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("Format returned unexpected content:\n%s", diff)
+	}
+}
+
+func TestParseAndFormat(t *testing.T) {
+	df := parseEditTestData(t)
+	if n := len(df.Require); n != 3 {
+		t.Fatalf("expected 3 requires, but got %d", n)
+	}
+	testFormat(t, df, editTestData)
+
+	_, err := deptfile.Parse([]byte("require ("))
+	if err == nil {
+		t.Error("Parse must return an error for invalid syntax, but got nil")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("File which is not created by Parse", func(t *testing.T) {
+		df := &deptfile.File{
+			Require: []*deptfile.Require{
+				{Path: "github.com/ktr0731/evans", Version: "v0.1.0", ToolPaths: []*deptfile.Tool{{Path: "/", Name: "ev"}}},
+			},
+		}
+		testFormat(t, df, "module tools\n\nrequire github.com/ktr0731/evans@ev v0.1.0\n")
+	})
+
+	t.Run("require without the version", func(t *testing.T) {
+		df := &deptfile.File{
+			Require: []*deptfile.Require{
+				{Path: "github.com/ktr0731/evans", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
+			},
+		}
+		if _, err := df.Format(); err == nil {
+			t.Error("Format must return an error, but got nil")
+		}
+	})
+
+	t.Run("duplicated requires of a module", func(t *testing.T) {
+		df, err := deptfile.Parse([]byte(`module test
+
+require (
+	honnef.co/go/tools:/cmd/staticcheck v0.0.2
+	honnef.co/go/tools:/cmd/unused v0.0.2
+)
+`))
+		if err != nil {
+			t.Fatalf("Parse must not return an error, but got '%s'", err)
+		}
+		if err := df.RenameTool("unused", "u"); err != nil {
+			t.Fatalf("RenameTool must not return an error, but got '%s'", err)
+		}
+		testFormat(t, df, `module test
+
+require (
+	honnef.co/go/tools:/cmd/staticcheck v0.0.2
+	honnef.co/go/tools:/cmd/unused@u v0.0.2
+)
+`)
+	})
+}
+
+func TestAddTool(t *testing.T) {
+	cases := map[string]struct {
+		modPath, toolPath, name string
+
+		expected string
+		err      error
+	}{
+		"new module": {
+			modPath: "github.com/ktr0731/salias", name: "sa",
+			expected: "github.com/ktr0731/salias@sa v0.2.0",
+		},
+		"new tool in a managed module": {
+			modPath: "github.com/ktr0731/itunes-cli", toolPath: "/",
+			expected: "github.com/ktr0731/itunes-cli:/,/itunes v0.0.1",
+		},
+		"managed tool": {
+			modPath: "github.com/ktr0731/evans", toolPath: "/",
+			expected: "github.com/ktr0731/evans v0.1.0",
+		},
+		"managed tool with a new name": {
+			modPath: "honnef.co/go/tools", toolPath: "/cmd/staticcheck", name: "sc",
+			expected: "honnef.co/go/tools:/cmd/staticcheck@sc,/cmd/unused@u v0.0.2",
+		},
+		"conflicted with the default name": {
+			modPath: "github.com/foo/evans",
+			err:     deptfile.ErrToolNameConflicted,
+		},
+		"conflicted with the output name": {
+			modPath: "github.com/foo/bar", toolPath: "/cmd/u",
+			err: deptfile.ErrToolNameConflicted,
+		},
+		"invalid module path": {
+			modPath: "github.com/foo/bar:baz",
+			err:     deptfile.ErrInvalidPath,
+		},
+		"invalid tool path": {
+			modPath: "github.com/foo/bar", toolPath: "cmd/bar",
+			err: deptfile.ErrInvalidPath,
+		},
+		"invalid tool name": {
+			modPath: "github.com/foo/bar", name: "b@r",
+			err: deptfile.ErrInvalidPath,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			df := parseEditTestData(t)
+			err := df.AddTool(c.modPath, c.toolPath, c.name)
+			if c.err != nil {
+				if errors.Cause(err) != c.err {
+					t.Errorf("AddTool must return '%s', but got '%v'", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddTool must not return an error, but got '%s'", err)
+			}
+			// A new module must have the version before formatting.
+			for _, r := range df.Require {
+				if r.Version == "" {
+					if err := df.SetVersion(r.Path, "v0.2.0"); err != nil {
+						t.Fatalf("SetVersion must not return an error, but got '%s'", err)
+					}
+				}
+			}
+			b, err := df.Format()
+			if err != nil {
+				t.Fatalf("Format must not return an error, but got '%s'", err)
+			}
+			reparsed, err := deptfile.Parse(b)
+			if err != nil {
+				t.Fatalf("failed to parse the formatted deptfile: %s", err)
+			}
+			if !containsRequireLine(b, c.expected) {
+				t.Errorf("formatted deptfile must contain '%s', but got:\n%s", c.expected, string(b))
+			}
+			if n := len(reparsed.Require); n < 3 {
+				t.Errorf("managed tools must be kept, but got %d requires", n)
+			}
+		})
+	}
+}
+
+func TestRemoveTool(t *testing.T) {
+	df := parseEditTestData(t)
+	if err := df.RemoveTool("honnef.co/go/tools/cmd/staticcheck"); err != nil {
+		t.Fatalf("RemoveTool must not return an error, but got '%s'", err)
+	}
+	if err := df.RemoveTool("github.com/ktr0731/itunes-cli/itunes"); err != nil {
+		t.Fatalf("RemoveTool must not return an error, but got '%s'", err)
+	}
+	if err := df.RemoveTool("github.com/ktr0731/itunes-cli/itunes"); errors.Cause(err) != deptfile.ErrToolNotFound {
+		t.Errorf("RemoveTool must return ErrToolNotFound, but got '%v'", err)
+	}
+
+	testFormat(t, df, `// managed by dept
+module test
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools:/cmd/unused@u v0.0.2
+)
+`)
+}
+
+func TestRenameTool(t *testing.T) {
+	cases := map[string]struct {
+		oldName, newName string
+
+		expected string
+		err      error
+	}{
+		"rename": {
+			oldName: "evans", newName: "ev",
+			expected: "github.com/ktr0731/evans@ev v0.1.0",
+		},
+		"rename to the default name": {
+			oldName: "u", newName: "unused",
+			expected: "honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2",
+		},
+		"not found": {
+			oldName: "unused", newName: "foo",
+			err: deptfile.ErrToolNotFound,
+		},
+		"conflicted": {
+			oldName: "evans", newName: "itunes",
+			err: deptfile.ErrToolNameConflicted,
+		},
+		"invalid name": {
+			oldName: "evans", newName: "cmd/evans",
+			err: deptfile.ErrInvalidPath,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			df := parseEditTestData(t)
+			err := df.RenameTool(c.oldName, c.newName)
+			if c.err != nil {
+				if errors.Cause(err) != c.err {
+					t.Errorf("RenameTool must return '%s', but got '%v'", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameTool must not return an error, but got '%s'", err)
+			}
+			b, err := df.Format()
+			if err != nil {
+				t.Fatalf("Format must not return an error, but got '%s'", err)
+			}
+			if !containsRequireLine(b, c.expected) {
+				t.Errorf("formatted deptfile must contain '%s', but got:\n%s", c.expected, string(b))
+			}
+		})
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	df := parseEditTestData(t)
+	if err := df.SetVersion("github.com/ktr0731/evans", "v0.2.0"); err != nil {
+		t.Fatalf("SetVersion must not return an error, but got '%s'", err)
+	}
+	if err := df.SetVersion("github.com/ktr0731/salias", "v0.2.0"); errors.Cause(err) != deptfile.ErrModuleNotFound {
+		t.Errorf("SetVersion must return ErrModuleNotFound, but got '%v'", err)
+	}
+	if err := df.SetVersion("github.com/ktr0731/evans", ""); errors.Cause(err) != deptfile.ErrInvalidPath {
+		t.Errorf("SetVersion must return ErrInvalidPath, but got '%v'", err)
+	}
+
+	b, err := df.Format()
+	if err != nil {
+		t.Fatalf("Format must not return an error, but got '%s'", err)
+	}
+	if !containsRequireLine(b, "github.com/ktr0731/evans v0.2.0") {
+		t.Errorf("the version of evans must be updated, but got:\n%s", string(b))
+	}
+}
+
+func containsRequireLine(b []byte, line string) bool {
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if string(bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(l), []byte("require")))) == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNormalize(t *testing.T) {
+	df, err := deptfile.Parse([]byte(`module test
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.0.2
+	honnef.co/go/tools:/cmd/keyify,/cmd/unused@u v0.0.2
+)
+`))
+	if err != nil {
+		t.Fatalf("Parse must not return an error, but got '%s'", err)
+	}
+
+	expected := []string{
+		"merged duplicated requires of honnef.co/go/tools",
+		"removed duplicated tool honnef.co/go/tools/cmd/unused",
+		"sorted tool paths of honnef.co/go/tools",
+	}
+	if diff := cmp.Diff(expected, df.Normalize()); diff != "" {
+		t.Errorf("Normalize returned unexpected fixes:\n%s", diff)
+	}
+	if fixes := df.Normalize(); len(fixes) != 0 {
+		t.Errorf("Normalize must be idempotent, but got %v", fixes)
+	}
+
+	testFormat(t, df, `module test
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	honnef.co/go/tools:/cmd/keyify,/cmd/unused,/cmd/staticcheck v0.0.2
+)
+`)
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	cases := map[string]struct {
+		data     string
+		expected []string
+	}{
+		"empty tool name": {
+			data:     "module tools\n\nrequire github.com/ktr0731/evans@ v0.1.0\n",
+			expected: []string{"gotool.mod:3:34: empty tool name"},
+		},
+		"empty tool name in tool paths": {
+			data:     "module tools\n\nrequire (\n\thonnef.co/go/tools:/cmd/staticcheck,/cmd/unused@ v0.0.2\n)\n",
+			expected: []string{"gotool.mod:4:50: empty tool name"},
+		},
+		"no tool paths": {
+			data:     "module tools\n\nrequire (\n\thonnef.co/go/tools: v0.0.2\n)\n",
+			expected: []string{"gotool.mod:4:21: no tool paths after ':'"},
+		},
+		"empty tool path": {
+			data:     "module tools\n\nrequire (\n\thonnef.co/go/tools:/cmd/staticcheck,,/cmd/unused v0.0.2\n)\n",
+			expected: []string{"gotool.mod:4:38: empty tool path"},
+		},
+		"invalid tool path": {
+			data:     "module tools\n\nrequire (\n\thonnef.co/go/tools:cmd/staticcheck v0.0.2\n)\n",
+			expected: []string{"gotool.mod:4:21: tool path cmd/staticcheck must start with '/'"},
+		},
+		"duplicated tool paths": {
+			data:     "module tools\n\nrequire (\n\thonnef.co/go/tools:/cmd/unused,/cmd/unused@u v0.0.2\n)\n",
+			expected: []string{"gotool.mod:4:33: duplicated tool path /cmd/unused"},
+		},
+		"conflicted names": {
+			data: `module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/ktr0731/salias@evans v0.2.0
+	honnef.co/go/tools:/cmd/staticcheck@evans,/cmd/unused v0.0.2
+	github.com/ktr0731/itunes-cli:/itunes@ v0.0.1
+)
+`,
+			expected: []string{
+				"gotool.mod:5:2: tool name 'evans' is already used by github.com/ktr0731/evans at 4:2",
+				"gotool.mod:6:21: tool name 'evans' is already used by github.com/ktr0731/evans at 4:2",
+				"gotool.mod:7:40: empty tool name",
+			},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			_, err := deptfile.Parse([]byte(c.data))
+			if err == nil {
+				t.Fatal("Parse must return an error, but got nil")
+			}
+			if diff := cmp.Diff(strings.Join(c.expected, "\n"), err.Error()); diff != "" {
+				t.Errorf("Parse returned unexpected errors:\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("duplicated requires are not errors", func(t *testing.T) {
+		_, err := deptfile.Parse([]byte("module tools\n\nrequire (\n\thonnef.co/go/tools:/cmd/unused v0.0.2\n\thonnef.co/go/tools:/cmd/unused v0.0.2\n)\n"))
+		if err != nil {
+			t.Errorf("Parse must not return an error, but got '%s'", err)
+		}
+	})
+}
+
+func TestFormatKeepsDirectives(t *testing.T) {
+	cases := map[string]string{
+		"go":               "go 1.24.0\n",
+		"toolchain":        "toolchain go1.24.1\n",
+		"godebug":          "godebug default=go1.21\n",
+		"godebug block":    "godebug (\n\thttp2client=0\n\tpanicnil=1\n)\n",
+		"retract":          "retract v1.0.0 // published accidentally\n",
+		"retract interval": "retract [v1.0.0, v1.0.5]\n",
+		"retract block":    "retract (\n\t[v1.2.0, v1.2.3]\n\t// broken\n\tv1.1.0\n)\n",
+		"tool":             "tool golang.org/x/tools/cmd/stringer\n",
+		"tool block":       "tool (\n\tgolang.org/x/tools/cmd/stringer\n\thonnef.co/go/tools/cmd/staticcheck\n)\n",
+		"ignore":           "ignore ./node_modules\n",
+		"exclude":          "exclude github.com/ktr0731/evans v0.0.1\n",
+		"replace":          "replace github.com/ktr0731/evans => github.com/foo/evans v0.1.1\n",
+		"replace block":    "replace (\n\tgithub.com/ktr0731/evans v0.1.0 => github.com/foo/evans v0.1.1\n\tgithub.com/ktr0731/itunes-cli => /path/to/itunes-cli\n)\n",
+	}
+
+	for name, directive := range cases {
+		directive := directive
+		t.Run(name, func(t *testing.T) {
+			data := editTestData + "\n" + directive
+			df, err := deptfile.Parse([]byte(data))
+			if err != nil {
+				t.Fatalf("Parse must not return an error, but got '%s'", err)
+			}
+			testFormat(t, df, data)
+
+			if err := df.RenameTool("evans", "ev"); err != nil {
+				t.Fatalf("RenameTool must not return an error, but got '%s'", err)
+			}
+			b, err := df.Format()
+			if err != nil {
+				t.Fatalf("Format must not return an error, but got '%s'", err)
+			}
+			if !strings.HasSuffix(string(b), "\n"+directive) {
+				t.Errorf("Format must keep '%s', but got:\n%s", directive, string(b))
+			}
+
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed to create a temp dir: %s", err)
+			}
+			defer os.RemoveAll(dir)
+			if err := deptfile.WriteGoMod(dir, df); err != nil {
+				t.Fatalf("WriteGoMod must not return an error, but got '%s'", err)
+			}
+			b, err = ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+			if err != nil {
+				t.Fatalf("failed to read go.mod: %s", err)
+			}
+			if !strings.HasSuffix(string(b), "\n"+directive) {
+				t.Errorf("go.mod must keep '%s', but got:\n%s", directive, string(b))
+			}
+			if !containsRequireLine(b, "honnef.co/go/tools v0.0.2") {
+				t.Errorf("go.mod must not have tool paths, but got:\n%s", string(b))
+			}
+		})
+	}
+}
+
+func TestReplaceAndExclude(t *testing.T) {
+	const data = `module test
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused@u v0.0.2
+)
+
+replace (
+	github.com/ktr0731/evans v0.1.0 => github.com/foo/evans v0.1.1
+	honnef.co/go/tools:/cmd/staticcheck => ../tools
+)
+
+exclude honnef.co/go/tools v0.0.1
+`
+	df, err := deptfile.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse must not return an error, but got '%s'", err)
+	}
+
+	expectedReplace := []*deptfile.Replace{
+		{Path: "github.com/ktr0731/evans", Version: "v0.1.0", NewPath: "github.com/foo/evans", NewVersion: "v0.1.1"},
+		{Path: "honnef.co/go/tools", NewPath: "../tools"},
+	}
+	if diff := cmp.Diff(expectedReplace, df.Replace, cmpopts.IgnoreUnexported(deptfile.Replace{})); diff != "" {
+		t.Errorf("Replace is wrong:\n%s", diff)
+	}
+	expectedExclude := []*deptfile.Exclude{{Path: "honnef.co/go/tools", Version: "v0.0.1"}}
+	if diff := cmp.Diff(expectedExclude, df.Exclude); diff != "" {
+		t.Errorf("Exclude is wrong:\n%s", diff)
+	}
+
+	t.Run("LookupReplace", func(t *testing.T) {
+		cases := map[string]struct {
+			path, version string
+			expected      string
+		}{
+			"replaced version":     {path: "github.com/ktr0731/evans", version: "v0.1.0", expected: "github.com/foo/evans v0.1.1"},
+			"not replaced version": {path: "github.com/ktr0731/evans", version: "v0.2.0"},
+			"all versions":         {path: "honnef.co/go/tools", version: "v0.0.2", expected: "../tools"},
+			"not replaced module":  {path: "github.com/ktr0731/salias", version: "v0.1.0"},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				r := df.LookupReplace(c.path, c.version)
+				if c.expected == "" {
+					if r != nil {
+						t.Errorf("LookupReplace must return nil, but got '%s'", r)
+					}
+					return
+				}
+				if r == nil || r.String() != c.expected {
+					t.Errorf("LookupReplace must return '%s', but got '%v'", c.expected, r)
+				}
+			})
+		}
+	})
+
+	t.Run("Format normalizes tool paths in replace", func(t *testing.T) {
+		b, err := df.Format()
+		if err != nil {
+			t.Fatalf("Format must not return an error, but got '%s'", err)
+		}
+		if !strings.Contains(string(b), "\thonnef.co/go/tools => ../tools\n") {
+			t.Errorf("Format must remove tool paths in replace, but got:\n%s", string(b))
+		}
+	})
+
+	t.Run("Format applies changes of Replace and Exclude", func(t *testing.T) {
+		df, err := deptfile.Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("Parse must not return an error, but got '%s'", err)
+		}
+		df.Replace = df.Replace[1:]
+		df.Replace[0].NewPath = "../tools-fork"
+		df.Exclude = append(df.Exclude, &deptfile.Exclude{Path: "github.com/ktr0731/evans", Version: "v0.0.1"})
+		b, err := df.Format()
+		if err != nil {
+			t.Fatalf("Format must not return an error, but got '%s'", err)
+		}
+		if strings.Contains(string(b), "github.com/foo/evans") {
+			t.Errorf("Format must drop removed replace, but got:\n%s", string(b))
+		}
+		for _, e := range []string{"honnef.co/go/tools => ../tools-fork", "github.com/ktr0731/evans v0.0.1"} {
+			if !strings.Contains(string(b), e) {
+				t.Errorf("Format must contain '%s', but got:\n%s", e, string(b))
+			}
+		}
+	})
+
+	t.Run("WriteGoMod resolves relative local dirs", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(dir)
+		if err := deptfile.WriteGoMod(dir, df); err != nil {
+			t.Fatalf("WriteGoMod must not return an error, but got '%s'", err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			t.Fatalf("failed to read go.mod: %s", err)
+		}
+		abs, err := filepath.Abs("../tools")
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		for _, e := range []string{"honnef.co/go/tools => " + abs, "exclude honnef.co/go/tools v0.0.1"} {
+			if !strings.Contains(string(b), e) {
+				t.Errorf("go.mod must contain '%s', but got:\n%s", e, string(b))
+			}
+		}
+	})
+}
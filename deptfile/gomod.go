@@ -0,0 +1,160 @@
+package deptfile
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// toolGoVersion is the minimum Go version which supports tool directives.
+const toolGoVersion = "1.24"
+
+// GoMod returns f as a go.mod.
+// If withTools is true, tool directives of all tools are added, so the go command can run them by 'go tool'.
+// Unlike WriteGoMod, relative local dirs in replace directives are kept as it is.
+func (f *File) GoMod(withTools bool) ([]byte, error) {
+	b, err := f.Format()
+	if err != nil {
+		return nil, err
+	}
+	mf, err := parseModFile(FileName, b, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.addBase(mf); err != nil {
+		return nil, err
+	}
+	if withTools {
+		if err := f.addToolDirectives(mf); err != nil {
+			return nil, err
+		}
+	}
+	mf.Cleanup()
+	b, err = mf.Format()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format go.mod")
+	}
+	return b, nil
+}
+
+// PatchGoMod adds tools of f to gomod which is the content of a go.mod, then returns the patched one.
+// Tool modules are required directly with the same versions as f. If gomod already requires them with higher versions,
+// the higher ones are kept because the project may depend on them. Indirect requirements of f are added only if
+// gomod doesn't require them, so the versions which the project uses are kept.
+// Replace and exclude directives of f are added if gomod doesn't have them.
+// If withTools is true, tool directives of all tools are also added.
+func (f *File) PatchGoMod(fname string, gomod []byte, withTools bool) ([]byte, error) {
+	mf, err := modfile.Parse(fname, gomod, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", fname)
+	}
+	b, err := f.Format()
+	if err != nil {
+		return nil, err
+	}
+	tools, err := parseModFile(FileName, b, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.addBase(tools); err != nil {
+		return nil, err
+	}
+
+	reqs := make([]*modfile.Require, 0, len(mf.Require)+len(tools.Require))
+	required := make(map[string]*modfile.Require, len(mf.Require))
+	for _, r := range mf.Require {
+		req := &modfile.Require{Mod: r.Mod, Indirect: r.Indirect}
+		reqs = append(reqs, req)
+		required[r.Mod.Path] = req
+	}
+	for _, r := range tools.Require {
+		req, ok := required[r.Mod.Path]
+		switch {
+		case !ok:
+			reqs = append(reqs, &modfile.Require{Mod: r.Mod, Indirect: r.Indirect})
+		case !r.Indirect:
+			req.Mod.Version = semver.Max(req.Mod.Version, r.Mod.Version)
+			req.Indirect = false
+		}
+	}
+	mf.SetRequire(reqs)
+	for _, r := range tools.Replace {
+		if lookupModReplace(mf, r.Old.Path, r.Old.Version) != nil {
+			continue
+		}
+		if err := mf.AddReplace(r.Old.Path, r.Old.Version, r.New.Path, r.New.Version); err != nil {
+			return nil, errors.Wrapf(err, "failed to add the replace directive of %s", r.Old.Path)
+		}
+	}
+	for _, e := range tools.Exclude {
+		if err := mf.AddExclude(e.Mod.Path, e.Mod.Version); err != nil {
+			return nil, errors.Wrapf(err, "failed to add the exclude directive of %s", e.Mod.Path)
+		}
+	}
+	if withTools {
+		if err := f.addToolDirectives(mf); err != nil {
+			return nil, err
+		}
+	}
+	mf.SortBlocks()
+	mf.Cleanup()
+	b, err = mf.Format()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to format %s", fname)
+	}
+	return b, nil
+}
+
+// addToolDirectives adds tool directives of all tools of f to mf.
+// If the Go version of mf doesn't support tool directives, it is also updated.
+func (f *File) addToolDirectives(mf *modfile.File) error {
+	if mf.Go == nil || olderGoVersion(mf.Go.Version, toolGoVersion) {
+		if err := mf.AddGoStmt(toolGoVersion); err != nil {
+			return errors.Wrap(err, "failed to update the go directive")
+		}
+	}
+	for _, r := range f.Require {
+		for _, t := range r.ToolPaths {
+			p := joinToolPath(r.Path, t.Path)
+			if err := mf.AddTool(p); err != nil {
+				return errors.Wrapf(err, "failed to add the tool directive of %s", p)
+			}
+		}
+	}
+	return nil
+}
+
+// olderGoVersion reports whether the language version of the Go version v is older than w.
+// Like the go command, prereleases and patch releases have the same language version as the release,
+// e.g. the language version of 1.24rc1 and 1.24.1 is 1.24.
+// If v or w is not a valid Go version, olderGoVersion returns false.
+func olderGoVersion(v, w string) bool {
+	vMajor, vMinor, ok := goLangVersion(v)
+	if !ok {
+		return false
+	}
+	wMajor, wMinor, ok := goLangVersion(w)
+	if !ok {
+		return false
+	}
+	return vMajor < wMajor || (vMajor == wMajor && vMinor < wMinor)
+}
+
+// goLangVersion returns the major and minor version of the Go version v.
+func goLangVersion(v string) (int, int, bool) {
+	m := modfile.GoVersionRE.FindStringSubmatch(v)
+	if m == nil {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,162 @@
+package deptfile_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+func TestGoMod(t *testing.T) {
+	df := parseEditTestData(t)
+
+	t.Run("without tools", func(t *testing.T) {
+		b, err := df.GoMod(false)
+		if err != nil {
+			t.Fatalf("GoMod must not return an error, but got '%s'", err)
+		}
+		const expected = `// managed by dept
+module test
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	// itunes
+	github.com/ktr0731/itunes-cli v0.0.1
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools v0.0.2
+)
+`
+		if diff := cmp.Diff(expected, string(b)); diff != "" {
+			t.Errorf("GoMod returned unexpected content:\n%s", diff)
+		}
+	})
+
+	t.Run("with tools", func(t *testing.T) {
+		b, err := df.GoMod(true)
+		if err != nil {
+			t.Fatalf("GoMod must not return an error, but got '%s'", err)
+		}
+		const expected = `// managed by dept
+module test
+
+go 1.24
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	// itunes
+	github.com/ktr0731/itunes-cli v0.0.1
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools v0.0.2
+)
+
+tool (
+	github.com/ktr0731/evans
+	github.com/ktr0731/itunes-cli/itunes
+	honnef.co/go/tools/cmd/staticcheck
+	honnef.co/go/tools/cmd/unused
+)
+`
+		if diff := cmp.Diff(expected, string(b)); diff != "" {
+			t.Errorf("GoMod returned unexpected content:\n%s", diff)
+		}
+	})
+}
+
+func TestPatchGoMod(t *testing.T) {
+	const gomod = `module example.com/project
+
+go 1.25.0
+
+require (
+	github.com/pkg/errors v0.8.0
+	github.com/urfave/cli v1.22.0 // indirect
+	honnef.co/go/tools v0.0.1 // indirect
+)
+
+replace github.com/ktr0731/evans => ../evans
+
+tool example.com/project/cmd/gen
+`
+	const data = `module tools
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/urfave/cli v1.20.0 // indirect
+	honnef.co/go/tools:/cmd/staticcheck@sc v0.0.2
+)
+
+replace github.com/ktr0731/evans => github.com/foo/evans v0.1.1
+
+exclude honnef.co/go/tools v0.0.0
+`
+	df, err := deptfile.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse must not return an error, but got '%s'", err)
+	}
+	b, err := df.PatchGoMod("go.mod", []byte(gomod), true)
+	if err != nil {
+		t.Fatalf("PatchGoMod must not return an error, but got '%s'", err)
+	}
+
+	// The project replace directive and the indirect version are kept.
+	const expected = `module example.com/project
+
+go 1.25.0
+
+require (
+	github.com/ktr0731/evans v0.1.0
+	github.com/pkg/errors v0.8.0
+	github.com/urfave/cli v1.22.0 // indirect
+	honnef.co/go/tools v0.0.2
+)
+
+replace github.com/ktr0731/evans => ../evans
+
+tool (
+	example.com/project/cmd/gen
+	github.com/ktr0731/evans
+	honnef.co/go/tools/cmd/staticcheck
+)
+
+exclude honnef.co/go/tools v0.0.0
+`
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("PatchGoMod returned unexpected content:\n%s", diff)
+	}
+}
+
+func TestPatchGoModVersions(t *testing.T) {
+	df, err := deptfile.Parse([]byte("module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n"))
+	if err != nil {
+		t.Fatalf("Parse must not return an error, but got '%s'", err)
+	}
+	cases := map[string]struct {
+		gomod    string
+		expected string
+	}{
+		"the higher version of the project is kept": {
+			gomod:    "module example.com/project\n\ngo 1.24\n\nrequire github.com/ktr0731/evans v0.2.0\n",
+			expected: "module example.com/project\n\ngo 1.24\n\nrequire github.com/ktr0731/evans v0.2.0\n\ntool github.com/ktr0731/evans\n",
+		},
+		"a prerelease go directive is kept": {
+			gomod:    "module example.com/project\n\ngo 1.25rc1\n",
+			expected: "module example.com/project\n\ngo 1.25rc1\n\nrequire github.com/ktr0731/evans v0.1.0\n\ntool github.com/ktr0731/evans\n",
+		},
+		"an old go directive is updated": {
+			gomod:    "module example.com/project\n\ngo 1.23.4\n",
+			expected: "module example.com/project\n\ngo 1.24\n\nrequire github.com/ktr0731/evans v0.1.0\n\ntool github.com/ktr0731/evans\n",
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			b, err := df.PatchGoMod("go.mod", []byte(c.gomod), true)
+			if err != nil {
+				t.Fatalf("PatchGoMod must not return an error, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, string(b)); diff != "" {
+				t.Errorf("PatchGoMod returned unexpected content:\n%s", diff)
+			}
+		})
+	}
+}
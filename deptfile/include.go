@@ -0,0 +1,445 @@
+package deptfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// includeDirective is the comment which includes another deptfile.
+// The go command and the modfile parser treat it as a comment, so a deptfile which has it is still go.mod compatible.
+//
+// For example:
+//   //dept:include ../base/gotool.mod
+const includeDirective = "//dept:include"
+
+// parseWithIncludes parses data as the deptfile fname, then merges deptfiles which are included by it.
+// See File.merge for how included deptfiles are merged.
+func parseWithIncludes(fname string, data []byte) (*File, *modfile.File, error) {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get the abs path of %s", fname)
+	}
+	return parseIncluded(abs, data, map[string]bool{})
+}
+
+// parseIncluded is parseWithIncludes which detects circular includes by visiting.
+// fname must be an absolute path.
+func parseIncluded(fname string, data []byte, visiting map[string]bool) (*File, *modfile.File, error) {
+	df, canonical, err := parse(fname, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	includes := parseIncludes(data)
+	if len(includes) == 0 {
+		return df, canonical, nil
+	}
+
+	visiting[fname] = true
+	defer delete(visiting, fname)
+
+	base := &File{dir: df.dir}
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(df.dir, inc)
+		}
+		inc = filepath.Clean(inc)
+		if visiting[inc] {
+			return nil, nil, errors.Errorf("%s: circular include of %s", fname, inc)
+		}
+		b, err := ioutil.ReadFile(inc)
+		if os.IsNotExist(err) {
+			return nil, nil, errors.Errorf("%s: included deptfile %s not found", fname, inc)
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to open %s", inc)
+		}
+		incFile, incCanonical, err := parseIncluded(inc, b, visiting)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Former includes take precedence over latter ones.
+		base.merge(incFile)
+		for _, r := range incCanonical.Require {
+			if r.Indirect {
+				base.addIndirect(r.Mod.Path, r.Mod.Version)
+			}
+		}
+		base.includes = append(base.includes, inc)
+		base.includes = append(base.includes, incFile.includes...)
+	}
+
+	df.merge(base)
+	df.base = base
+	df.includes = base.includes
+	if err := df.addBase(canonical); err != nil {
+		return nil, nil, err
+	}
+	return df, canonical, nil
+}
+
+// parseIncludes returns paths of deptfiles which are included by data in the order of appearance.
+func parseIncludes(data []byte) []string {
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, includeDirective+" ") {
+			continue
+		}
+		if p := strings.TrimSpace(strings.TrimPrefix(line, includeDirective)); p != "" {
+			paths = append(paths, filepath.FromSlash(p))
+		}
+	}
+	return paths
+}
+
+// merge merges requirements, replace and exclude directives of base into f.
+// Entries of f take precedence over base:
+//   - the version of a module which f requires is kept.
+//   - a tool which f already has is kept with its name.
+//   - a tool of base whose name is already used by a tool of f is skipped.
+// Local dirs of replace directives in base are converted into absolute ones.
+// Entries which are added to f are copies, so editing f never changes base.
+func (f *File) merge(base *File) {
+	names := map[string]bool{}
+	for _, r := range f.Require {
+		for _, t := range r.ToolPaths {
+			names[toolName(joinToolPath(r.Path, t.Path), t.Name)] = true
+		}
+	}
+	for _, b := range base.Require {
+		var r *Require
+		for _, another := range f.Require {
+			if another.Path == b.Path {
+				r = another
+				break
+			}
+		}
+		var tools []*Tool
+		for _, t := range b.ToolPaths {
+			p := joinToolPath(b.Path, t.Path)
+			if owner, _ := f.lookupTool(p); owner != nil || names[toolName(p, t.Name)] {
+				continue
+			}
+			names[toolName(p, t.Name)] = true
+			tools = append(tools, &Tool{Path: t.Path, Name: t.Name})
+			if f.origins == nil {
+				f.origins = map[string]string{}
+			}
+			f.origins[p] = base.origin(p)
+		}
+		switch {
+		case r != nil:
+			r.ToolPaths = append(r.ToolPaths, tools...)
+		case len(tools) != 0:
+			f.Require = append(f.Require, &Require{Path: b.Path, Version: b.Version, ToolPaths: tools})
+		}
+	}
+
+	for _, b := range base.Replace {
+		var found bool
+		for _, r := range f.Replace {
+			if r.Path == b.Path && r.Version == b.Version {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		newPath := b.NewPath
+		if b.IsLocal() {
+			newPath = b.Dir()
+		}
+		f.Replace = append(f.Replace, &Replace{
+			Path:       b.Path,
+			Version:    b.Version,
+			NewPath:    newPath,
+			NewVersion: b.NewVersion,
+			dir:        f.dir,
+		})
+	}
+	for _, b := range base.Exclude {
+		if !f.hasExclude(b.Path, b.Version) {
+			f.Exclude = append(f.Exclude, &Exclude{Path: b.Path, Version: b.Version})
+		}
+	}
+}
+
+// addIndirect adds the indirect requirement of path@version to f.
+// If path is already required, the higher version is kept like minimal version selection.
+func (f *File) addIndirect(path, version string) {
+	if f.indirects == nil {
+		f.indirects = map[string]string{}
+	}
+	if cur, ok := f.indirects[path]; !ok || semver.Compare(version, cur) > 0 {
+		f.indirects[path] = version
+	}
+}
+
+// origin returns the file name of the deptfile which has the tool importPath.
+func (f *File) origin(importPath string) string {
+	if name, ok := f.origins[importPath]; ok {
+		return name
+	}
+	if f.name == "" {
+		return filepath.Join(f.dir, FileName)
+	}
+	return f.name
+}
+
+// ToolFile returns the deptfile which has the tool importPath, like 'gotool.mod' or an included deptfile.
+// It is relative to the dir which has f.
+func (f *File) ToolFile(importPath string) string {
+	name := f.origin(importPath)
+	if rel, err := filepath.Rel(f.dir, name); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return name
+}
+
+// addBase adds requirements of f which mf doesn't have, and indirect requirements, replace and exclude
+// directives of included deptfiles to mf, so mf can build all tools of f with the versions which they pin.
+// If mf requires a module of an indirect requirement with a lower version, it is raised to the higher one.
+func (f *File) addBase(mf *modfile.File) error {
+	if f.base == nil {
+		return nil
+	}
+	required := make(map[string]bool, len(mf.Require))
+	for _, r := range mf.Require {
+		required[r.Mod.Path] = true
+	}
+	for _, r := range f.Require {
+		if !required[r.Path] {
+			mf.AddNewRequire(r.Path, r.Version, false)
+		}
+	}
+	paths := make([]string, 0, len(f.base.indirects))
+	for p := range f.base.indirects {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		v := f.base.indirects[p]
+		var cur *modfile.Require
+		for _, r := range mf.Require {
+			if r.Mod.Path == p {
+				cur = r
+			}
+		}
+		switch {
+		case cur == nil:
+			mf.AddNewRequire(p, v, true)
+		case semver.Compare(v, cur.Mod.Version) > 0:
+			if err := mf.AddRequire(p, v); err != nil {
+				return errors.Wrapf(err, "invalid requirement of %s", p)
+			}
+		}
+	}
+	for _, r := range f.base.Replace {
+		if lookupModReplace(mf, r.Path, r.Version) != nil {
+			continue
+		}
+		newPath := r.NewPath
+		if r.IsLocal() {
+			newPath = r.Dir()
+		}
+		if err := mf.AddReplace(r.Path, r.Version, newPath, r.NewVersion); err != nil {
+			return errors.Wrapf(err, "invalid replace of %s", r.Path)
+		}
+	}
+	for _, e := range f.base.Exclude {
+		if err := mf.AddExclude(e.Path, e.Version); err != nil {
+			return errors.Wrapf(err, "invalid exclude of %s", e.Path)
+		}
+	}
+	return nil
+}
+
+// local returns a File which has only entries which should be written to the deptfile of f.
+// Entries which are merged from included deptfiles are dropped unless they are changed.
+// If f doesn't include any deptfiles, local returns f itself.
+func (f *File) local() *File {
+	if f.base == nil {
+		return f
+	}
+	local := &File{data: f.data, dir: f.dir}
+	for _, r := range f.Require {
+		if r := f.localRequire(r, r.Version); r != nil {
+			local.Require = append(local.Require, r)
+		}
+	}
+	for _, r := range f.Replace {
+		if !f.isBaseReplace(r.Path, r.Version, r.NewPath, r.NewVersion) {
+			local.Replace = append(local.Replace, r)
+		}
+	}
+	for _, e := range f.Exclude {
+		if !f.base.hasExclude(e.Path, e.Version) {
+			local.Exclude = append(local.Exclude, e)
+		}
+	}
+	return local
+}
+
+// localRequire returns r without tools which are merged from included deptfiles as it is.
+// If the module is required by them with the same version and r has no other tools, localRequire returns nil.
+// If the version is changed, r is kept as a whole so the version overrides the included one.
+func (f *File) localRequire(r *Require, version string) *Require {
+	var b *Require
+	for _, another := range f.base.Require {
+		if another.Path == r.Path {
+			b = another
+			break
+		}
+	}
+	if b == nil {
+		return r
+	}
+	var tools []*Tool
+	for _, t := range r.ToolPaths {
+		var inherited bool
+		for _, bt := range b.ToolPaths {
+			if bt.Path == t.Path && bt.Name == t.Name {
+				inherited = true
+				break
+			}
+		}
+		if !inherited {
+			tools = append(tools, t)
+		}
+	}
+	switch {
+	case version != b.Version:
+		return r
+	case len(tools) == 0:
+		return nil
+	case len(tools) == len(r.ToolPaths):
+		return r
+	}
+	return &Require{Path: r.Path, Version: r.Version, ToolPaths: tools}
+}
+
+// isBaseReplace reports whether the replace directive is the same as one of included deptfiles.
+// newPath is compared as an absolute dir if it is a local dir.
+func (f *File) isBaseReplace(path, version, newPath, newVersion string) bool {
+	for _, r := range f.base.Replace {
+		if r.Path != path || r.Version != version || r.NewVersion != newVersion {
+			continue
+		}
+		if r.IsLocal() {
+			return r.Dir() == newPath
+		}
+		return r.NewPath == newPath
+	}
+	return false
+}
+
+func (f *File) hasExclude(path, version string) bool {
+	for _, e := range f.Exclude {
+		if e.Path == path && e.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// dropBase drops entries of mf which are merged from included deptfiles as it is.
+// mf is the go.mod of the workspace whose requirements already have paths of deptfile.
+func (f *File) dropBase(mf *modfile.File) {
+	if f.base == nil {
+		return
+	}
+	// Indirect requirements which the deptfile itself has are kept.
+	localIndirects := map[string]string{}
+	if orig, err := parseModFile(FileName, f.data, true); err == nil {
+		for _, r := range orig.Require {
+			if r.Indirect {
+				localIndirects[r.Mod.Path] = r.Mod.Version
+			}
+		}
+	}
+	for _, r := range mf.Require {
+		if r.Indirect {
+			if v, ok := f.base.indirects[r.Mod.Path]; ok && v == r.Mod.Version && localIndirects[r.Mod.Path] != v {
+				mf.DropRequire(r.Mod.Path)
+			}
+			continue
+		}
+		req, _, _, err := parseRequirePath(r.Mod.Path)
+		if err != nil {
+			continue
+		}
+		var cur *Require
+		for _, another := range f.Require {
+			if another.Path == req {
+				cur = another
+				break
+			}
+		}
+		if cur == nil {
+			continue
+		}
+		local := f.localRequire(cur, r.Mod.Version)
+		if local == nil {
+			mf.DropRequire(r.Mod.Path)
+			continue
+		}
+		setRequirePath(r, local.format())
+	}
+	for _, r := range mf.Replace {
+		if r.Old.Path != "" && f.isBaseReplace(r.Old.Path, r.Old.Version, r.New.Path, r.New.Version) {
+			mf.DropReplace(r.Old.Path, r.Old.Version)
+		}
+	}
+	for _, e := range mf.Exclude {
+		if e.Mod.Path != "" && f.base.hasExclude(e.Mod.Path, e.Mod.Version) {
+			mf.DropExclude(e.Mod.Path, e.Mod.Version)
+		}
+	}
+}
+
+// mergeIncludedSums appends checksums of included deptfiles to the go.sum fname.
+// Missing sum files are ignored.
+func (f *File) mergeIncludedSums(fname string) error {
+	if len(f.includes) == 0 {
+		return nil
+	}
+	sum, err := ioutil.ReadFile(fname)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", fname)
+	}
+	found := map[string]bool{}
+	for _, l := range strings.Split(string(sum), "\n") {
+		found[l] = true
+	}
+	for _, inc := range f.includes {
+		b, err := ioutil.ReadFile(strings.TrimSuffix(inc, filepath.Ext(inc)) + ".sum")
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the sum file of %s", inc)
+		}
+		for _, l := range strings.Split(string(b), "\n") {
+			if l == "" || found[l] {
+				continue
+			}
+			found[l] = true
+			if len(sum) != 0 && sum[len(sum)-1] != '\n' {
+				sum = append(sum, '\n')
+			}
+			sum = append(sum, l+"\n"...)
+		}
+	}
+	if err := ioutil.WriteFile(fname, sum, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", fname)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package deptfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+func TestInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base/gotool.mod": `module tools
+
+require (
+	github.com/ktr0731/evans v0.8.0
+	github.com/ktr0731/itunes-cli:/itunes v0.1.0
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.4.0
+)
+
+require (
+	golang.org/x/mod v0.4.0 // indirect
+	golang.org/x/sync v0.2.0 // indirect
+)
+
+replace github.com/ktr0731/itunes-cli => ./itunes-cli
+`,
+		"base/gotool.sum": "github.com/ktr0731/evans v0.8.0 h1:base\n",
+		"proj/gotool.mod": `module tools
+
+//dept:include ../base/gotool.mod
+
+require (
+	github.com/ktr0731/evans@ev v0.8.0
+	honnef.co/go/tools:/cmd/staticcheck v0.5.0
+)
+
+require golang.org/x/sync v0.1.0 // indirect
+`,
+		"proj/gotool.sum": "honnef.co/go/tools v0.5.0 h1:proj\n",
+	}
+	for name, content := range files {
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+			t.Fatalf("failed to create a dir: %s", err)
+		}
+		if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	proj := filepath.Join(dir, "proj")
+	w := &deptfile.Workspace{SourcePath: proj}
+	err = w.Do(func(_, workDir string, df *deptfile.File) error {
+		var tools []string
+		for _, r := range df.Require {
+			for _, tool := range r.ToolPaths {
+				tools = append(tools, r.Path+tool.Path+"@"+tool.Name+" "+r.Version)
+			}
+		}
+		expectedTools := []string{
+			"github.com/ktr0731/evans/@ev v0.8.0",
+			"honnef.co/go/tools/cmd/staticcheck@ v0.5.0",
+			"honnef.co/go/tools/cmd/unused@ v0.5.0",
+			"github.com/ktr0731/itunes-cli/itunes@ v0.1.0",
+		}
+		if diff := cmp.Diff(expectedTools, tools); diff != "" {
+			t.Errorf("included tools must be merged:\n%s", diff)
+		}
+
+		gomod, err := ioutil.ReadFile(filepath.Join(workDir, "go.mod"))
+		if err != nil {
+			t.Fatalf("failed to read go.mod: %s", err)
+		}
+		expectedLines := []string{
+			"github.com/ktr0731/itunes-cli v0.1.0",
+			filepath.Join(dir, "base", "itunes-cli"),
+			// Indirect requirements which the base pins are merged with higher versions.
+			"golang.org/x/mod v0.4.0 // indirect",
+			"golang.org/x/sync v0.2.0 // indirect",
+		}
+		for _, s := range expectedLines {
+			if !strings.Contains(string(gomod), s) {
+				t.Errorf("go.mod must contain '%s', but got:\n%s", s, gomod)
+			}
+		}
+		sum, err := ioutil.ReadFile(filepath.Join(workDir, "go.sum"))
+		if err != nil {
+			t.Fatalf("failed to read go.sum: %s", err)
+		}
+		if expected := "honnef.co/go/tools v0.5.0 h1:proj\ngithub.com/ktr0731/evans v0.8.0 h1:base\n"; string(sum) != expected {
+			t.Errorf("expected go.sum '%s', but got '%s'", expected, sum)
+		}
+
+		if err := df.AddTool("github.com/ktr0731/salias", "", ""); err != nil {
+			t.Fatalf("AddTool must not return an error, but got '%s'", err)
+		}
+		if err := df.SetVersion("github.com/ktr0731/salias", "v0.1.0"); err != nil {
+			t.Fatalf("SetVersion must not return an error, but got '%s'", err)
+		}
+		return deptfile.WriteGoMod(workDir, df)
+	})
+	if err != nil {
+		t.Fatalf("Do must not return an error, but got '%s'", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(proj, deptfile.FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+	}
+	expected := `module tools
+
+//dept:include ../base/gotool.mod
+
+require (
+	github.com/ktr0731/evans@ev v0.8.0
+	honnef.co/go/tools:/cmd/staticcheck,/cmd/unused v0.5.0
+)
+
+require github.com/ktr0731/salias v0.1.0
+`
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("only local entries must be written:\n%s", diff)
+	}
+
+	t.Run("circular includes", func(t *testing.T) {
+		base := "module tools\n\n//dept:include ../proj/gotool.mod\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "base", deptfile.FileName), []byte(base), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+		}
+		err := w.Do(func(_, _ string, _ *deptfile.File) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), "circular include") {
+			t.Errorf("Do must return an error of the circular include, but got '%v'", err)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package deptfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// JournalDir is the dir which stores snapshots of gotool.mod and gotool.sum.
+	// It is relative to the project root.
+	JournalDir = filepath.Join(".dept", "journal")
+	// MaxJournalEntries is the max number of journal entries.
+	// The oldest entries are removed if the number of entries exceeds it.
+	MaxJournalEntries = 20
+)
+
+// ErrNoJournal represents there are no journal entries to restore.
+var ErrNoJournal = errors.New("no journal entries")
+
+const journalEntryFileName = "entry.json"
+
+// JournalEntry represents a snapshot of gotool.mod and gotool.sum
+// which is taken before a mutation.
+type JournalEntry struct {
+	// ID is the sequence number of the entry. Newer entries have larger IDs.
+	ID int `json:"-"`
+	// Time is the time when the mutation is done.
+	Time time.Time `json:"time"`
+	// Description describes the mutation. For example, 'get -u'.
+	Description string `json:"description"`
+
+	dir string
+}
+
+// Mod returns the content of gotool.mod in the snapshot.
+func (e *JournalEntry) Mod() ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(e.dir, FileName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s of journal entry %d", FileName, e.ID)
+	}
+	return b, nil
+}
+
+// Sum returns the content of gotool.sum in the snapshot.
+// If gotool.sum didn't exist, Sum returns nil.
+func (e *JournalEntry) Sum() ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(e.dir, FileSumName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s of journal entry %d", FileSumName, e.ID)
+	}
+	return b, nil
+}
+
+// ReadJournal reads all journal entries in the project.
+// Entries are sorted by newest first.
+func ReadJournal(projectDir string) ([]*JournalEntry, error) {
+	dir := filepath.Join(projectDir, JournalDir)
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the journal dir")
+	}
+
+	var entries []*JournalEntry
+	for _, fi := range fis {
+		id, err := strconv.Atoi(fi.Name())
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+		e := &JournalEntry{ID: id, dir: filepath.Join(dir, fi.Name())}
+		b, err := ioutil.ReadFile(filepath.Join(e.dir, journalEntryFileName))
+		if os.IsNotExist(err) {
+			// broken entry
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read journal entry %d", id)
+		}
+		if err := json.Unmarshal(b, e); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode journal entry %d", id)
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID > entries[j].ID
+	})
+	return entries, nil
+}
+
+// findJournalEntry finds the entry which has id.
+// If id is 0, findJournalEntry returns the latest one.
+func findJournalEntry(projectDir string, id int) (*JournalEntry, error) {
+	entries, err := ReadJournal(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNoJournal
+	}
+	if id == 0 {
+		return entries[0], nil
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, errors.Errorf("journal entry %d not found", id)
+}
+
+// recordJournal records mod and sum as a new journal entry.
+// If sum is nil, gotool.sum is not recorded.
+func recordJournal(projectDir, description string, mod, sum []byte) error {
+	entries, err := ReadJournal(projectDir)
+	if err != nil {
+		return err
+	}
+	id := 1
+	if len(entries) > 0 {
+		id = entries[0].ID + 1
+	}
+
+	dir := filepath.Join(projectDir, JournalDir, strconv.Itoa(id))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create a journal entry dir")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, FileName), mod, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s to the journal", FileName)
+	}
+	if sum != nil {
+		if err := ioutil.WriteFile(filepath.Join(dir, FileSumName), sum, 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s to the journal", FileSumName)
+		}
+	}
+	b, err := json.Marshal(&JournalEntry{Time: time.Now(), Description: description})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the journal entry")
+	}
+	// Write the entry file at last because entries without it are treated as broken.
+	if err := ioutil.WriteFile(filepath.Join(dir, journalEntryFileName), b, 0644); err != nil {
+		return errors.Wrap(err, "failed to write the journal entry")
+	}
+
+	// Remove old entries.
+	for i := MaxJournalEntries - 1; i < len(entries); i++ {
+		if err := os.RemoveAll(entries[i].dir); err != nil {
+			return errors.Wrapf(err, "failed to remove old journal entry %d", entries[i].ID)
+		}
+	}
+	return nil
+}
+
+// dropJournal removes the entry which has id and all newer entries.
+func dropJournal(projectDir string, id int) error {
+	entries, err := ReadJournal(projectDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.ID < id {
+			break
+		}
+		if err := os.RemoveAll(e.dir); err != nil {
+			return errors.Wrapf(err, "failed to remove journal entry %d", e.ID)
+		}
+	}
+	return nil
+}
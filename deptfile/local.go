@@ -0,0 +1,47 @@
+package deptfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// parseLocalDeptfile parses gotool.local.mod in dir as a deptfile which includes gotool.mod in dir,
+// so tools of gotool.local.mod take precedence over gotool.mod.
+// If gotool.local.mod doesn't include gotool.mod, the include directive is added.
+// If gotool.local.mod doesn't exist, an empty one is used.
+// parseLocalDeptfile returns ErrNotFound if gotool.mod is not found.
+func parseLocalDeptfile(dir string) (*File, *modfile.File, error) {
+	if !fileExists(filepath.Join(dir, FileName)) {
+		return nil, nil, ErrNotFound
+	}
+	fname := filepath.Join(dir, LocalFileName)
+	data, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		data = []byte("module tools\n")
+	} else if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to open %s", fname)
+	}
+
+	var included bool
+	for _, p := range parseIncludes(data) {
+		if filepath.Clean(p) == FileName {
+			included = true
+		}
+	}
+	if !included {
+		if len(data) != 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		data = append(data, "\n"+includeDirective+" "+FileName+"\n"...)
+	}
+	return parseWithIncludes(fname, data)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,102 @@
+package deptfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ktr0731/dept/deptfile"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	gotoolMod := "module tools\n\nrequire github.com/ktr0731/evans v0.8.0\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, deptfile.FileName), []byte(gotoolMod), 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+	}
+
+	// tools returns tools of df with files which have them.
+	tools := func(df *deptfile.File) map[string]string {
+		m := map[string]string{}
+		for _, r := range df.Require {
+			for _, tool := range r.ToolPaths {
+				p := r.Path
+				if tool.Path != "/" {
+					p += tool.Path
+				}
+				m[p] = df.ToolFile(p)
+			}
+		}
+		return m
+	}
+
+	w := &deptfile.Workspace{SourcePath: dir, Local: true}
+	err = w.Do(func(_, workDir string, df *deptfile.File) error {
+		if err := df.AddTool("github.com/ktr0731/salias", "", ""); err != nil {
+			t.Fatalf("AddTool must not return an error, but got '%s'", err)
+		}
+		if err := df.SetVersion("github.com/ktr0731/salias", "v0.1.0"); err != nil {
+			t.Fatalf("SetVersion must not return an error, but got '%s'", err)
+		}
+		return deptfile.WriteGoMod(workDir, df)
+	})
+	if err != nil {
+		t.Fatalf("Do must not return an error, but got '%s'", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, deptfile.LocalFileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.LocalFileName, err)
+	}
+	expected := "module tools\n\n//dept:include gotool.mod\n\nrequire github.com/ktr0731/salias v0.1.0\n"
+	if diff := cmp.Diff(expected, string(b)); diff != "" {
+		t.Errorf("the tool must be written to %s:\n%s", deptfile.LocalFileName, diff)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, deptfile.FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+	}
+	if string(b) != gotoolMod {
+		t.Errorf("%s must not be changed, but got '%s'", deptfile.FileName, b)
+	}
+
+	cases := map[string]struct {
+		local    bool
+		expected map[string]string
+	}{
+		"gotool.local.mod is merged": {
+			local: true,
+			expected: map[string]string{
+				"github.com/ktr0731/evans":  deptfile.FileName,
+				"github.com/ktr0731/salias": deptfile.LocalFileName,
+			},
+		},
+		"gotool.local.mod is ignored": {
+			expected: map[string]string{
+				"github.com/ktr0731/evans": deptfile.FileName,
+			},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			w := &deptfile.Workspace{SourcePath: dir, Local: c.local, DoNotUpdate: true}
+			err := w.Do(func(_, _ string, df *deptfile.File) error {
+				if diff := cmp.Diff(c.expected, tools(df)); diff != "" {
+					t.Errorf("unexpected tools:\n%s", diff)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Do must not return an error, but got '%s'", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package deptfile
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultLockTimeout is the default value of Workspace.LockTimeout.
+const DefaultLockTimeout = 1 * time.Minute
+
+var lockRetryInterval = 100 * time.Millisecond
+
+// ErrLockTimeout represents Do couldn't acquire the lock of the deptfile in time.
+var ErrLockTimeout = errors.New("timed out waiting for the lock")
+
+// fileLock is an advisory lock which is based on flock(2), or LockFileEx on Windows.
+// An exclusive lock holder writes its pid to the lock file for other processes waiting for the lock.
+// The last holder removes the lock file, so it doesn't remain after dept exits.
+type fileLock struct {
+	f         *os.File
+	path      string
+	exclusive bool
+}
+
+// lockFile acquires the lock of path.
+// If exclusive is false, lockFile acquires a shared lock.
+// While waiting for the lock, lockFile writes the message to w.
+// If lockFile couldn't acquire the lock in timeout, it returns ErrLockTimeout.
+func lockFile(path string, exclusive bool, timeout time.Duration, w io.Writer) (*fileLock, error) {
+	deadline := time.Now().Add(timeout)
+	var notified bool
+	for {
+		f, err := openLockFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open the lock file %s", path)
+		}
+		for {
+			ok, err := tryLock(f, exclusive)
+			if err != nil {
+				f.Close()
+				return nil, errors.Wrapf(err, "failed to lock %s", path)
+			}
+			if ok {
+				break
+			}
+
+			holder := lockHolder(f)
+			if !notified {
+				fmt.Fprintf(w, "waiting for lock held by %s\n", holder)
+				notified = true
+			}
+			if time.Now().After(deadline) {
+				f.Close()
+				return nil, errors.Wrapf(ErrLockTimeout, "%s is held by %s", path, holder)
+			}
+			time.Sleep(lockRetryInterval)
+		}
+		// The previous holder may have removed the file while waiting for the lock.
+		// In that case, lock the new one instead.
+		if isSameFile(f, path) {
+			l := &fileLock{f: f, path: path, exclusive: exclusive}
+			if exclusive {
+				if err := f.Truncate(0); err != nil {
+					l.unlock()
+					return nil, errors.Wrapf(err, "failed to truncate %s", path)
+				}
+				if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+					l.unlock()
+					return nil, errors.Wrapf(err, "failed to write pid to %s", path)
+				}
+			}
+			return l, nil
+		}
+		f.Close()
+	}
+}
+
+// isSameFile returns true if f is the file which path currently points to.
+func isSameFile(f *os.File, path string) bool {
+	fi1, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	fi2, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fi1, fi2)
+}
+
+// lockHolder returns the description of the process which holds the lock.
+// Only exclusive lock holders are identified by pid.
+func lockHolder(f *os.File) string {
+	if _, err := f.Seek(0, io.SeekStart); err == nil {
+		b, err := ioutil.ReadAll(f)
+		if err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+				return fmt.Sprintf("pid %d", pid)
+			}
+		}
+	}
+	return "another dept process"
+}
+
+// unlock releases the lock.
+// If no other processes hold the lock, unlock removes the lock file.
+// Processes which have opened the removed file notice it by isSameFile after acquiring the lock.
+func (l *fileLock) unlock() error {
+	defer l.f.Close()
+	if !l.exclusive {
+		if err := unlockFile(l.f); err != nil {
+			return errors.Wrapf(err, "failed to unlock %s", l.path)
+		}
+		// A shared lock holder removes the lock file only if it can take the exclusive lock,
+		// which means there are no other holders.
+		if ok, err := tryLock(l.f, true); err != nil || !ok {
+			return nil
+		}
+	}
+	if isSameFile(l.f, l.path) {
+		// ignore errors because the lock file is only used for locking.
+		os.Remove(l.path)
+	}
+	if err := unlockFile(l.f); err != nil {
+		return errors.Wrapf(err, "failed to unlock %s", l.path)
+	}
+	return nil
+}
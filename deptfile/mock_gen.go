@@ -17,7 +17,7 @@ var (
 //
 //         // make and configure a mocked Workspacer
 //         mockedWorkspacer := &WorkspacerMock{
-//             DoFunc: func(f func(projectDir string, gomod *File) error) error {
+//             DoFunc: func(f func(projectDir string, workDir string, gomod *File) error, opts ...Option) error {
 // 	               panic("mock out the Do method")
 //             },
 //         }
@@ -28,42 +28,48 @@ var (
 //     }
 type WorkspacerMock struct {
 	// DoFunc mocks the Do method.
-	DoFunc func(f func(projectDir string, gomod *File) error) error
+	DoFunc func(f func(projectDir string, workDir string, gomod *File) error, opts ...Option) error
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// Do holds details about calls to the Do method.
 		Do []struct {
 			// F is the f argument value.
-			F func(projectDir string, gomod *File) error
+			F func(projectDir string, workDir string, gomod *File) error
+			// Opts is the opts argument value.
+			Opts []Option
 		}
 	}
 }
 
 // Do calls DoFunc.
-func (mock *WorkspacerMock) Do(f func(projectDir string, gomod *File) error) error {
+func (mock *WorkspacerMock) Do(f func(projectDir string, workDir string, gomod *File) error, opts ...Option) error {
 	if mock.DoFunc == nil {
 		panic("WorkspacerMock.DoFunc: method is nil but Workspacer.Do was just called")
 	}
 	callInfo := struct {
-		F func(projectDir string, gomod *File) error
+		F    func(projectDir string, workDir string, gomod *File) error
+		Opts []Option
 	}{
-		F: f,
+		F:    f,
+		Opts: opts,
 	}
 	lockWorkspacerMockDo.Lock()
 	mock.calls.Do = append(mock.calls.Do, callInfo)
 	lockWorkspacerMockDo.Unlock()
-	return mock.DoFunc(f)
+	return mock.DoFunc(f, opts...)
 }
 
 // DoCalls gets all the calls that were made to Do.
 // Check the length with:
 //     len(mockedWorkspacer.DoCalls())
 func (mock *WorkspacerMock) DoCalls() []struct {
-	F func(projectDir string, gomod *File) error
+	F    func(projectDir string, workDir string, gomod *File) error
+	Opts []Option
 } {
 	var calls []struct {
-		F func(projectDir string, gomod *File) error
+		F    func(projectDir string, workDir string, gomod *File) error
+		Opts []Option
 	}
 	lockWorkspacerMockDo.RLock()
 	calls = mock.calls.Do
@@ -0,0 +1,134 @@
+package deptfile
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Replace represents a replace directive.
+// If Version is empty, all versions of the module are replaced.
+// NewPath is a module path or a local dir. If NewPath is a local dir, NewVersion is empty.
+// A relative local dir is relative to the dir which has the deptfile.
+type Replace struct {
+	Path       string
+	Version    string
+	NewPath    string
+	NewVersion string
+
+	// dir is the dir which has the deptfile.
+	dir string
+}
+
+// IsLocal reports whether r replaces the module by a local dir.
+func (r *Replace) IsLocal() bool {
+	return modfile.IsDirectoryPath(r.NewPath)
+}
+
+// Dir returns the absolute path of the local dir which replaces the module.
+// If r doesn't replace the module by a local dir, Dir returns an empty string.
+func (r *Replace) Dir() string {
+	if !r.IsLocal() {
+		return ""
+	}
+	if filepath.IsAbs(r.NewPath) {
+		return r.NewPath
+	}
+	p := filepath.Join(r.dir, r.NewPath)
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// String returns the replacement of r like 'path version' or 'dir'.
+func (r *Replace) String() string {
+	if r.NewVersion == "" {
+		return r.NewPath
+	}
+	return r.NewPath + " " + r.NewVersion
+}
+
+// Exclude represents an exclude directive.
+type Exclude struct {
+	Path    string
+	Version string
+}
+
+// LookupReplace returns the replace directive which is applied to version of the module path.
+// A replace directive which has the version takes precedence over one which doesn't have it.
+// If the module is not replaced, LookupReplace returns nil.
+func (f *File) LookupReplace(path, version string) *Replace {
+	var found *Replace
+	for _, r := range f.Replace {
+		if r.Path != path {
+			continue
+		}
+		if r.Version == version {
+			return r
+		}
+		if r.Version == "" {
+			found = r
+		}
+	}
+	return found
+}
+
+// AddReplace adds a replace directive which replaces path@version by newPath@newVersion.
+// If the replacement of path@version already exists, AddReplace updates it.
+func (f *File) AddReplace(path, version, newPath, newVersion string) {
+	for _, r := range f.Replace {
+		if r.Path == path && r.Version == version {
+			r.NewPath, r.NewVersion = newPath, newVersion
+			return
+		}
+	}
+	f.Replace = append(f.Replace, &Replace{
+		Path:       path,
+		Version:    version,
+		NewPath:    newPath,
+		NewVersion: newVersion,
+		dir:        f.dir,
+	})
+}
+
+// resolveReplaceDirs replaces relative local dirs of replace directives in canonical by absolute ones
+// because the canonical modfile is written out to a temp dir.
+func resolveReplaceDirs(canonical *modfile.File, dir string) error {
+	for _, r := range canonical.Replace {
+		if !modfile.IsDirectoryPath(r.New.Path) || filepath.IsAbs(r.New.Path) {
+			continue
+		}
+		p, err := filepath.Abs(filepath.Join(dir, r.New.Path))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get abs path from %s", r.New.Path)
+		}
+		setReplaceNewPath(r, p)
+	}
+	return nil
+}
+
+// restoreReplaceDirs is the reverse of resolveReplaceDirs.
+// It restores absolute local dirs in f to relative ones which are written in gomod.
+func restoreReplaceDirs(f *modfile.File, gomod *File) {
+	for _, r := range f.Replace {
+		orig := gomod.LookupReplace(r.Old.Path, r.Old.Version)
+		if orig == nil || filepath.IsAbs(orig.NewPath) || orig.Dir() != r.New.Path {
+			continue
+		}
+		setReplaceNewPath(r, orig.NewPath)
+	}
+}
+
+// setReplaceNewPath replaces the new path of r by p.
+// Unlike modfile.File.AddReplace, it doesn't touch other replace directives.
+func setReplaceNewPath(r *modfile.Replace, p string) {
+	r.New.Path = p
+	for i, tok := range r.Syntax.Token {
+		if tok == "=>" && i+1 < len(r.Syntax.Token) {
+			r.Syntax.Token[i+1] = modfile.AutoQuote(p)
+			return
+		}
+	}
+}
@@ -4,44 +4,127 @@ package deptfile
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ktr0731/dept/fileutil"
-	"github.com/ktr0731/modfile"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/logger"
 	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
 )
 
 // Workspacer provides an environment to edit go.mod and go.sum.
 type Workspacer interface {
 	// Do copies gotool.mod and gotool.sum to the workspace.
 	// If gotool.mod is not found, Do returns ErrNotFound.
-	Do(f func(projectDir string, gomod *File) error) error
+	Do(f func(projectDir, workDir string, gomod *File) error, opts ...Option) error
+}
+
+// Option configures a single Do call.
+type Option func(*Options)
+
+// Options is the set of configurations for a single Do call.
+type Options struct {
+	// DryRun runs the whole flow of Do, but Do doesn't write gotool.mod and gotool.sum back to the project.
+	DryRun bool
+	// OnChange receives changes of gotool.mod made in Do.
+	// It is called after the workspace is updated and before gotool.mod is written back.
+	// It is not called if Workspace.DoNotUpdate is true.
+	OnChange func(*Changes)
+	// Description describes the mutation. It is recorded to the journal.
+	Description string
+	// Undo restores gotool.mod and gotool.sum to the journal entry which has the ID before calling f.
+	// If Undo is 0, the latest entry is used. If Undo is negative, nothing is restored.
+	// After gotool.mod is updated, the entry and all newer entries are removed from the journal.
+	Undo int
+}
+
+// NewOptions applies opts to a new Options.
+func NewOptions(opts ...Option) *Options {
+	o := Options{Undo: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &o
+}
+
+// DryRun enables Options.DryRun.
+func DryRun() Option {
+	return func(o *Options) {
+		o.DryRun = true
+	}
+}
+
+// OnChange sets f to Options.OnChange.
+func OnChange(f func(*Changes)) Option {
+	return func(o *Options) {
+		o.OnChange = f
+	}
+}
+
+// Description sets desc to Options.Description.
+func Description(desc string) Option {
+	return func(o *Options) {
+		o.Description = desc
+	}
+}
+
+// Undo sets id to Options.Undo.
+func Undo(id int) Option {
+	return func(o *Options) {
+		o.Undo = id
+	}
 }
 
 // Workspace is an implementation for Workspacer.
 // The environment is created in a temp dir.
 type Workspace struct {
-	// SourcePath is the root path for finding go.mod and go.sum.
-	// If SourcePath is empty, SourcePath is the Git project root.
+	// SourcePath is the dir which has gotool.mod and gotool.sum.
+	// If SourcePath is empty, FindDir is used.
 	SourcePath string
 	// DoNotCopy doesn't copy gotool.mod and gotool.sum to the workspace.
 	DoNotCopy bool
 	// DoNotUpdate doesn't update gotool.mod and gotool.sum.
 	// It is used for commands which doesn't need to update gotool.mod such like 'dept build'.
 	DoNotUpdate bool
+	// LockTimeout is the max duration to wait for the lock of gotool.mod.
+	// If LockTimeout is zero, DefaultLockTimeout is used.
+	LockTimeout time.Duration
+	// Local merges gotool.local.mod on top of gotool.mod if it exists.
+	// If DoNotUpdate is false, Do updates gotool.local.mod instead of gotool.mod, and creates it if it doesn't exist.
+	Local bool
+	// Stderr receives messages while Do is waiting for the lock.
+	// If Stderr is nil, os.Stderr is used.
+	Stderr io.Writer
+	// GoCommand runs Go commands to compute changes. If GoCommand is nil, gocmd.New is used.
+	GoCommand gocmd.Command
 }
 
 // Do copies from the project gotool.mod to a temporary workspace
 // as a go.mod.
-// Then, Do change the current dir to the workspace.
-// After that, Do changes back the current dir and remove the created workspace.
+// Then, Do calls f with the workspace dir. Do never changes the current dir of the process.
+// After that, Do removes the created workspace.
 //
-// f receives projectDir which is the project root dir.
-func (w *Workspace) Do(f func(projectDir string, gomod *File) error) error {
+// While Do is running, Do holds an advisory lock of gotool.mod to prevent other dept processes
+// from updating it concurrently.
+// The lock is shared if DoNotUpdate is true, otherwise exclusive.
+// If the lock isn't acquired in LockTimeout, Do returns ErrLockTimeout.
+// The lock file is removed when the last holder releases it.
+//
+// Before Do updates gotool.mod, Do records the previous gotool.mod and gotool.sum to the journal
+// which is stored in JournalDir. It can be restored by Undo option.
+//
+// f receives projectDir which is the project root dir,
+// and workDir which is the workspace dir that has go.mod and go.sum.
+// Go commands which edit go.mod must be run in workDir.
+func (w *Workspace) Do(f func(projectDir, workDir string, gomod *File) error, opts ...Option) error {
+	o := NewOptions(opts...)
+
 	var err error
 	cwd := w.SourcePath
 	if cwd != "" {
@@ -50,49 +133,95 @@ func (w *Workspace) Do(f func(projectDir string, gomod *File) error) error {
 			return errors.Wrapf(err, "failed to get abs path from %s", cwd)
 		}
 	} else {
-		cwd, err = projectRoot()
+		cwd, err = FindDir()
 		if err != nil {
-			cwd, err = os.Getwd()
-			if err != nil {
-				return errors.Wrap(err, "failed to get the current working dir")
-			}
+			return err
 		}
 	}
 
+	// Don't create the lock file where gotool.mod doesn't exist.
+	if !w.DoNotCopy && !fileExists(filepath.Join(cwd, FileName)) {
+		return ErrNotFound
+	}
+
+	timeout := w.LockTimeout
+	if timeout == 0 {
+		timeout = DefaultLockTimeout
+	}
+	stderr := w.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	lock, err := lockFile(filepath.Join(cwd, lockFileName()), !w.DoNotUpdate, timeout, stderr)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
 	dir, err := ioutil.TempDir("", "")
 	if err != nil {
 		return errors.Wrap(err, "failed to create a temp dir")
 	}
 	defer os.RemoveAll(dir)
 
-	os.Chdir(dir)
-	defer os.Chdir(cwd)
+	modName, sumName := FileName, FileSumName
+	if w.Local && (!w.DoNotUpdate || fileExists(filepath.Join(cwd, LocalFileName))) {
+		modName, sumName = LocalFileName, LocalFileSumName
+	}
 
 	var gomod *File
 	var canonicalModFile *modfile.File
+	var before *snapshot
 	// Parse deptfile and write out canonical formed modfile to go.mod.
 	// After that, f treats this go.mod.
 	if !w.DoNotCopy {
-		gomod, canonicalModFile, err = parseDeptfile(filepath.Join(cwd, FileName))
+		if modName == LocalFileName {
+			gomod, canonicalModFile, err = parseLocalDeptfile(cwd)
+		} else {
+			gomod, canonicalModFile, err = parseDeptfile(filepath.Join(cwd, FileName))
+		}
 		if err == ErrNotFound {
 			return ErrNotFound
 		}
 		if err != nil {
 			return errors.Wrap(err, "failed to initialize *File")
 		}
+		// Keep the current state before calling f because f may modify gomod.
+		before = &snapshot{tools: toolVersions(gomod), mod: canonicalModFile}
+		if o.OnChange != nil {
+			// ignore errors because gotool.mod is already read and gotool.sum may be missing.
+			before.data, _ = ioutil.ReadFile(filepath.Join(cwd, modName))
+			before.sum, _ = ioutil.ReadFile(filepath.Join(cwd, sumName))
+		}
 		b, err := canonicalModFile.Format()
 		if err != nil {
 			return errors.Wrap(err, "failed to format canonicalized modfile")
 		}
-		if err := ioutil.WriteFile("go.mod", b, 0644); err != nil {
+		if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), b, 0644); err != nil {
 			return errors.Wrap(err, "failed to write out go.mod")
 		}
 
 		// ignore errors because it is auto-generated file.
-		fileutil.Copy("go.sum", filepath.Join(cwd, FileSumName))
+		fileutil.Copy(filepath.Join(dir, "go.sum"), filepath.Join(cwd, sumName))
+		if err := gomod.mergeIncludedSums(filepath.Join(dir, "go.sum")); err != nil {
+			return err
+		}
 	}
 
-	if err := f(cwd, gomod); err != nil {
+	var undo *JournalEntry
+	// The journal has only gotool.mod.
+	if o.Undo >= 0 && gomod != nil && modName == FileName {
+		undo, err = findJournalEntry(cwd, o.Undo)
+		if err != nil {
+			return err
+		}
+		gomod, err = restoreJournal(cwd, dir, undo)
+		if err != nil {
+			return errors.Wrapf(err, "failed to restore journal entry %d", undo.ID)
+		}
+	}
+
+	if err := f(cwd, dir, gomod); err != nil {
 		return err
 	}
 
@@ -100,7 +229,7 @@ func (w *Workspace) Do(f func(projectDir string, gomod *File) error) error {
 		return nil
 	}
 
-	df, err := convertGoModToDeptfile("go.mod", gomod)
+	df, err := convertGoModToDeptfile(filepath.Join(dir, "go.mod"), gomod)
 	if err != nil {
 		return errors.Wrap(err, "failed to convert from go.mod to deptfile")
 	}
@@ -110,26 +239,173 @@ func (w *Workspace) Do(f func(projectDir string, gomod *File) error) error {
 		return err
 	}
 
-	if err := ioutil.WriteFile(filepath.Join(cwd, FileName), b, 0644); err != nil {
-		return errors.Wrap(err, "failed to write gotool.mod")
+	if o.OnChange != nil && gomod != nil {
+		newFile, newCanonical, err := parseWithIncludes(filepath.Join(cwd, modName), b)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse the updated deptfile")
+		}
+		// ignore errors because go.sum may be missing.
+		sum, _ := ioutil.ReadFile(filepath.Join(dir, "go.sum"))
+		after := &snapshot{tools: toolVersions(newFile), mod: newCanonical, data: b, sum: sum}
+		changes, err := computeChanges(w.gocmd(), dir, before, after, newFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to compute changes of the deptfile")
+		}
+		o.OnChange(changes)
 	}
 
-	fileutil.Copy(filepath.Join(cwd, FileSumName), "go.sum")
+	if o.DryRun {
+		logger.Println("dry-run mode: gotool.mod and gotool.sum are not updated")
+		return nil
+	}
+
+	// Keep the current contents for the journal.
+	// gotool.mod is missing if it is created by Create.
+	oldMod, err := ioutil.ReadFile(filepath.Join(cwd, modName))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", modName)
+	}
+	oldSum, err := ioutil.ReadFile(filepath.Join(cwd, sumName))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", sumName)
+	}
+
+	// Write gotool.mod and gotool.sum together to keep consistency between them.
+	files := []*fileutil.File{{Path: filepath.Join(cwd, modName), Data: b}}
+	sum, err := ioutil.ReadFile(filepath.Join(dir, "go.sum"))
+	switch {
+	case err == nil:
+		files = append(files, &fileutil.File{Path: filepath.Join(cwd, sumName), Data: sum})
+	case os.IsNotExist(err):
+		// There are no dependencies. Keep gotool.sum as it is.
+		sum = oldSum
+	default:
+		return errors.Wrap(err, "failed to read go.sum")
+	}
+	if err := fileutil.WriteFiles(files); err != nil {
+		return errors.Wrapf(err, "failed to write %s and %s", modName, sumName)
+	}
+
+	switch {
+	case undo != nil:
+		if err := dropJournal(cwd, undo.ID); err != nil {
+			return errors.Wrap(err, "failed to update the journal")
+		}
+	case oldMod != nil && modName == FileName && (!bytes.Equal(oldMod, b) || !bytes.Equal(oldSum, sum)):
+		if err := recordJournal(cwd, o.Description, oldMod, oldSum); err != nil {
+			return errors.Wrap(err, "failed to record the journal")
+		}
+	}
 
 	return nil
 }
 
-func projectRoot() (string, error) {
-	var out, eout bytes.Buffer
-	cmd := exec.Command("git", "rev-parse", "--show-cdup")
-	cmd.Stdout = &out
-	cmd.Stderr = &eout
-	if err := cmd.Run(); err != nil {
-		return "", errors.Wrapf(err, "failed to get the project root: %s", eout.String())
+// restoreJournal replaces go.mod and go.sum in dir by contents of e.
+// projRoot is the dir which has the deptfile.
+// It returns the restored deptfile.
+func restoreJournal(projRoot, dir string, e *JournalEntry) (*File, error) {
+	mod, err := e.Mod()
+	if err != nil {
+		return nil, err
+	}
+	sum, err := e.Sum()
+	if err != nil {
+		return nil, err
+	}
+	df, canonical, err := parseWithIncludes(filepath.Join(projRoot, FileName), mod)
+	if err != nil {
+		return nil, err
+	}
+	b, err := canonical.Format()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to format canonicalized modfile")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), b, 0644); err != nil {
+		return nil, errors.Wrap(err, "failed to write out go.mod")
+	}
+	// If gotool.sum didn't exist, write an empty go.sum to clear the current gotool.sum.
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.sum"), sum, 0644); err != nil {
+		return nil, errors.Wrap(err, "failed to write out go.sum")
+	}
+	return df, nil
+}
+
+// lockFileName returns the name of the lock file which is put next to gotool.mod.
+func lockFileName() string {
+	return FileName + ".lock"
+}
+
+// FindDir returns the dir which has gotool.mod.
+// If EnvFile is set, FindDir returns the dir of it.
+// Otherwise, FindDir walks up from the current dir to the nearest dir which has gotool.mod like the go command does for go.mod.
+// If gotool.mod is not found, FindDir returns the current dir.
+func FindDir() (string, error) {
+	if dir, err := envDir(); dir != "" || err != nil {
+		return dir, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the current working dir")
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, FileName)); err == nil {
+			return dir, nil
+		}
+		if filepath.Dir(dir) == dir {
+			return cwd, nil
+		}
+	}
+}
+
+// FindAll returns all dirs which have gotool.mod under root in lexical order.
+// Like the go command, dirs whose names start with '.' or '_', 'testdata' and 'vendor' are skipped.
+func FindAll(root string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get abs path from %s", root)
 	}
-	p, err := filepath.Abs(out.String())
+	var dirs []string
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if name := info.Name(); p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, FileName)); err == nil {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to parse '%s' as abs path", out.String())
+		return nil, errors.Wrapf(err, "failed to find %s under %s", FileName, root)
+	}
+	return dirs, nil
+}
+
+// envDir returns the abs dir of the deptfile which is specified by EnvFile.
+// If EnvFile is not set, envDir returns an empty string.
+func envDir() (string, error) {
+	p := os.Getenv(EnvFile)
+	if p == "" {
+		return "", nil
+	}
+	if filepath.Base(p) != FileName {
+		return "", errors.Errorf("%s must be the path of %s, but got '%s'", EnvFile, FileName, p)
+	}
+	dir, err := filepath.Abs(filepath.Dir(p))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get abs path from %s", p)
+	}
+	return dir, nil
+}
+
+func (w *Workspace) gocmd() gocmd.Command {
+	if w.GoCommand != nil {
+		return w.GoCommand
 	}
-	return strings.TrimSpace(p), nil
+	return gocmd.New()
 }
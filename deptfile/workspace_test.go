@@ -1,16 +1,24 @@
 package deptfile_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ktr0731/dept/deptfile"
-	"github.com/ktr0731/modfile"
+	"github.com/ktr0731/dept/fileutil"
+	"github.com/ktr0731/dept/gocmd"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 var _ deptfile.Workspacer = (*deptfile.Workspace)(nil)
@@ -44,7 +52,7 @@ func TestDo(t *testing.T) {
 		}
 	}
 
-	t.Run("workspace changes directory to a temp dir and copies gotool.mod to there", func(t *testing.T) {
+	t.Run("workspace copies gotool.mod to a temp dir without changing the current dir", func(t *testing.T) {
 		cases := map[string]struct {
 			dir        string
 			numRequire int
@@ -147,7 +155,7 @@ func TestDo(t *testing.T) {
 				w := &deptfile.Workspace{
 					SourcePath: cwd,
 				}
-				err = w.Do(func(proj string, gomod *deptfile.File) error {
+				err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
 					if gomod == nil {
 						t.Fatalf("deptfile must not be nil, but nil")
 					}
@@ -162,8 +170,14 @@ func TestDo(t *testing.T) {
 					if err != nil {
 						t.Fatalf("failed to get current working dir: %s", err)
 					}
-					if cwd == newcwd {
-						t.Errorf("current dir in Do must not be equal to dir outside of Do")
+					if cwd != newcwd {
+						t.Errorf("Do must not change the current dir, but changed to %s", newcwd)
+					}
+					if workDir == cwd {
+						t.Errorf("workDir must not be equal to the project dir")
+					}
+					if _, err := os.Stat(filepath.Join(workDir, "go.mod")); err != nil {
+						t.Errorf("go.mod must be in workDir, but got an error: %s", err)
 					}
 					return nil
 				})
@@ -190,18 +204,466 @@ func TestDo(t *testing.T) {
 		}
 	})
 
+	t.Run("workspace reports changes and doesn't update gotool.mod in dry-run mode", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get cwd: %s", err)
+		}
+
+		var changes *deptfile.Changes
+		w := &deptfile.Workspace{SourcePath: cwd}
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			b, err := ioutil.ReadFile(filepath.Join(workDir, "go.mod"))
+			if err != nil {
+				t.Fatalf("failed to read go.mod: %s", err)
+			}
+			s := strings.Replace(string(b), "v0.0.0-20181115031610-26cc03ed185c", "v0.1.0", 1)
+			s = strings.Replace(s, "github.com/urfave/cli v1.20.0 // indirect\n", "", 1)
+			return ioutil.WriteFile(filepath.Join(workDir, "go.mod"), []byte(s), 0644)
+		}, deptfile.DryRun(), deptfile.OnChange(func(c *deptfile.Changes) {
+			changes = c
+		}))
+		if err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+
+		expectedTools := []*deptfile.Change{
+			{Path: "github.com/ktr0731/evans", OldVersion: "v0.0.0-20181115031610-26cc03ed185c", NewVersion: "v0.1.0"},
+		}
+		if diff := cmp.Diff(expectedTools, changes.Tools); diff != "" {
+			t.Errorf("reported changes of tools are wrong:\n%s", diff)
+		}
+		expectedIndirect := []*deptfile.Change{
+			{Path: "github.com/urfave/cli", OldVersion: "v1.20.0"},
+		}
+		if diff := cmp.Diff(expectedIndirect, changes.Indirect); diff != "" {
+			t.Errorf("reported changes of indirect requirements are wrong:\n%s", diff)
+		}
+		for _, l := range []string{
+			"-\tgithub.com/ktr0731/evans v0.0.0-20181115031610-26cc03ed185c\n",
+			"+\tgithub.com/ktr0731/evans v0.1.0\n",
+			"-\tgithub.com/urfave/cli v1.20.0 // indirect\n",
+		} {
+			if !strings.Contains(changes.ModDiff, l) {
+				t.Errorf("ModDiff must contain '%s', but missing:\n%s", l, changes.ModDiff)
+			}
+		}
+		if !changes.Sum.Empty() {
+			t.Errorf("gotool.sum must not be changed, but got %+v", changes.Sum)
+		}
+
+		assertEqualDeptfile(t, filepath.Join(testDataDir, deptfile.FileName))
+	})
+
+	t.Run("workspace reports tools which pull in updated indirect requirements", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get cwd: %s", err)
+		}
+
+		const graph = `test github.com/ktr0731/evans@v0.0.0-20181115031610-26cc03ed185c
+test github.com/urfave/cli@v1.21.0
+github.com/ktr0731/evans@v0.0.0-20181115031610-26cc03ed185c github.com/urfave/cli@v1.21.0
+`
+		gocmd := &gocmd.CommandMock{
+			ModGraphFunc: func(ctx context.Context, dir string) (io.Reader, error) {
+				return strings.NewReader(graph), nil
+			},
+		}
+		var changes *deptfile.Changes
+		w := &deptfile.Workspace{SourcePath: cwd, GoCommand: gocmd}
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			b, err := ioutil.ReadFile(filepath.Join(workDir, "go.mod"))
+			if err != nil {
+				t.Fatalf("failed to read go.mod: %s", err)
+			}
+			s := strings.Replace(string(b), "github.com/urfave/cli v1.20.0", "github.com/urfave/cli v1.21.0", 1)
+			return ioutil.WriteFile(filepath.Join(workDir, "go.mod"), []byte(s), 0644)
+		}, deptfile.DryRun(), deptfile.OnChange(func(c *deptfile.Changes) {
+			changes = c
+		}))
+		if err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+
+		expectedIndirect := []*deptfile.Change{
+			{Path: "github.com/urfave/cli", OldVersion: "v1.20.0", NewVersion: "v1.21.0", RequiredBy: []string{"github.com/ktr0731/evans"}},
+		}
+		if diff := cmp.Diff(expectedIndirect, changes.Indirect); diff != "" {
+			t.Errorf("reported changes of indirect requirements are wrong:\n%s", diff)
+		}
+		if n := len(gocmd.ModGraphCalls()); n != 1 {
+			t.Errorf("ModGraph must be called once, but called %d times", n)
+		}
+	})
+
+	t.Run("workspace preserves permissions of gotool.mod and gotool.sum", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		for _, name := range []string{deptfile.FileName, deptfile.FileSumName} {
+			if err := os.Chmod(name, 0600); err != nil {
+				t.Fatalf("failed to chmod %s: %s", name, err)
+			}
+		}
+
+		w := &deptfile.Workspace{SourcePath: "."}
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+
+		for _, name := range []string{deptfile.FileName, deptfile.FileSumName} {
+			fi, err := os.Stat(name)
+			if err != nil {
+				t.Fatalf("failed to stat %s: %s", name, err)
+			}
+			if m := fi.Mode().Perm(); m != 0600 {
+				t.Errorf("permission of %s must be preserved, but got %s", name, m)
+			}
+		}
+		assertEqualDeptfile(t, filepath.Join(testDataDir, deptfile.FileName))
+	})
+
+	t.Run("workspace keeps directives which the go command writes to go.mod", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		directives := "go 1.24.0\n\ntoolchain go1.24.1\n\ngodebug default=go1.21\n"
+		w := &deptfile.Workspace{SourcePath: "."}
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			// Emulate the go command which adds new directives.
+			fname := filepath.Join(workDir, "go.mod")
+			b, err := ioutil.ReadFile(fname)
+			if err != nil {
+				return err
+			}
+			b = bytes.Replace(b, []byte("\nrequire"), []byte("\n"+directives+"\nrequire"), 1)
+			return ioutil.WriteFile(fname, b, 0644)
+		})
+		if err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+
+		b, err := ioutil.ReadFile(deptfile.FileName)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if !strings.Contains(string(b), directives) {
+			t.Errorf("%s must contain '%s', but got:\n%s", deptfile.FileName, directives, string(b))
+		}
+		if !strings.Contains(string(b), "honnef.co/go/tools:/cmd/staticcheck,/cmd/unused") {
+			t.Errorf("%s must keep tool paths, but got:\n%s", deptfile.FileName, string(b))
+		}
+		checkGoModSyntax(t)
+	})
+
+	t.Run("workspace resolves relative local dirs of replace directives", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		b, err := ioutil.ReadFile(deptfile.FileName)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		replace := "replace github.com/ktr0731/salias => ./salias\n"
+		if err := ioutil.WriteFile(deptfile.FileName, append(b, "\n"+replace...), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", deptfile.FileName, err)
+		}
+
+		w := &deptfile.Workspace{SourcePath: "."}
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			b, err := ioutil.ReadFile(filepath.Join(workDir, "go.mod"))
+			if err != nil {
+				return err
+			}
+			if e := "replace github.com/ktr0731/salias => " + filepath.Join(proj, "salias"); !strings.Contains(string(b), e) {
+				t.Errorf("go.mod must contain '%s', but got:\n%s", e, string(b))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+
+		b, err = ioutil.ReadFile(deptfile.FileName)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		if !strings.Contains(string(b), replace) {
+			t.Errorf("%s must keep '%s', but got:\n%s", deptfile.FileName, replace, string(b))
+		}
+	})
+
+	t.Run("workspace locks gotool.mod while running", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get cwd: %s", err)
+		}
+
+		cases := map[string]struct {
+			outer, inner bool // DoNotUpdate
+			locked       bool
+		}{
+			"mutating commands are exclusive":            {outer: false, inner: false, locked: true},
+			"read-only command waits for a mutating one": {outer: false, inner: true, locked: true},
+			"mutating command waits for a read-only one": {outer: true, inner: false, locked: true},
+			"read-only commands can run concurrently":    {outer: true, inner: true, locked: false},
+		}
+		for name, c := range cases {
+			t.Run(name, func(t *testing.T) {
+				var buf bytes.Buffer
+				outer := &deptfile.Workspace{SourcePath: cwd, DoNotUpdate: c.outer}
+				err := outer.Do(func(string, string, *deptfile.File) error {
+					inner := &deptfile.Workspace{
+						SourcePath:  cwd,
+						DoNotUpdate: c.inner,
+						LockTimeout: 200 * time.Millisecond,
+						Stderr:      &buf,
+					}
+					return inner.Do(func(string, string, *deptfile.File) error { return nil })
+				})
+
+				if _, err := os.Stat(filepath.Join(cwd, deptfile.FileName+".lock")); !os.IsNotExist(err) {
+					t.Errorf("the lock file must be removed after Do, but got '%v'", err)
+				}
+
+				if !c.locked {
+					if err != nil {
+						t.Errorf("Do must not return errors, but got '%s'", err)
+					}
+					return
+				}
+
+				if errors.Cause(err) != deptfile.ErrLockTimeout {
+					t.Fatalf("Do must return ErrLockTimeout, but got '%v'", err)
+				}
+				expected := "waiting for lock held by another dept process"
+				if !c.outer {
+					expected = fmt.Sprintf("waiting for lock held by pid %d", os.Getpid())
+				}
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("Do must show '%s', but got '%s'", expected, buf.String())
+				}
+			})
+		}
+	})
+
+	t.Run("workspace records the journal and restores it by Undo", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get cwd: %s", err)
+		}
+
+		oldMax := deptfile.MaxJournalEntries
+		deptfile.MaxJournalEntries = 2
+		defer func() {
+			deptfile.MaxJournalEntries = oldMax
+		}()
+
+		w := &deptfile.Workspace{SourcePath: cwd}
+		prev := "v0.0.0-20181115031610-26cc03ed185c"
+		for _, v := range []string{"v0.1.0", "v0.2.0", "v0.3.0"} {
+			old, v := prev, v
+			err := w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+				b, err := ioutil.ReadFile(filepath.Join(workDir, "go.mod"))
+				if err != nil {
+					t.Fatalf("failed to read go.mod: %s", err)
+				}
+				s := strings.Replace(string(b), "github.com/ktr0731/evans "+old, "github.com/ktr0731/evans "+v, 1)
+				return ioutil.WriteFile(filepath.Join(workDir, "go.mod"), []byte(s), 0644)
+			}, deptfile.Description("get evans@"+v))
+			if err != nil {
+				t.Fatalf("Do must not return errors, but got an error: %s", err)
+			}
+			prev = v
+		}
+
+		entries, err := deptfile.ReadJournal(cwd)
+		if err != nil {
+			t.Fatalf("ReadJournal must not return errors, but got an error: %s", err)
+		}
+		var descs []string
+		for _, e := range entries {
+			descs = append(descs, fmt.Sprintf("%d: %s", e.ID, e.Description))
+		}
+		// The oldest entry must be removed by MaxJournalEntries.
+		expected := []string{"3: get evans@v0.3.0", "2: get evans@v0.2.0"}
+		if diff := cmp.Diff(expected, descs); diff != "" {
+			t.Errorf("journal entries are wrong:\n%s", diff)
+		}
+
+		// Restore the state before 'get evans@v0.2.0'.
+		var changes *deptfile.Changes
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			return nil
+		}, deptfile.Undo(2), deptfile.OnChange(func(c *deptfile.Changes) {
+			changes = c
+		}))
+		if err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+		expectedTools := []*deptfile.Change{
+			{Path: "github.com/ktr0731/evans", OldVersion: "v0.3.0", NewVersion: "v0.1.0"},
+		}
+		if diff := cmp.Diff(expectedTools, changes.Tools); diff != "" {
+			t.Errorf("reported changes of tools are wrong:\n%s", diff)
+		}
+
+		entries, err = deptfile.ReadJournal(cwd)
+		if err != nil {
+			t.Fatalf("ReadJournal must not return errors, but got an error: %s", err)
+		}
+		if n := len(entries); n != 0 {
+			t.Errorf("restored entry and newer ones must be removed, but %d entries remain", n)
+		}
+
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+			return nil
+		}, deptfile.Undo(0))
+		if errors.Cause(err) != deptfile.ErrNoJournal {
+			t.Errorf("Do must return ErrNoJournal, but got '%v'", err)
+		}
+	})
+
+	t.Run("workspaces can run concurrently", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+
+		var eg errgroup.Group
+		for i := 0; i < 3; i++ {
+			dir, err := ioutil.TempDir("", "")
+			if err != nil {
+				t.Fatalf("failed to create a temp dir: %s", err)
+			}
+			defer os.RemoveAll(dir)
+			for _, name := range []string{deptfile.FileName, deptfile.FileSumName} {
+				if err := fileutil.Copy(filepath.Join(dir, name), filepath.Join(testDataDir, name)); err != nil {
+					t.Fatalf("failed to copy %s: %s", name, err)
+				}
+			}
+
+			eg.Go(func() error {
+				w := &deptfile.Workspace{SourcePath: dir}
+				return w.Do(func(proj, workDir string, gomod *deptfile.File) error {
+					if proj != dir {
+						return errors.Errorf("projectDir must be %s, but got %s", dir, proj)
+					}
+					if _, err := os.Stat(filepath.Join(workDir, "go.mod")); err != nil {
+						return errors.Wrap(err, "go.mod must be in workDir")
+					}
+					return nil
+				})
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			t.Errorf("Do must not return errors, but got an error: %s", err)
+		}
+	})
+
+	t.Run("mutating workspaces are serialized while the lock file is removed", func(t *testing.T) {
+		testDataDir, err := filepath.Abs(filepath.Join("testdata", "normal"))
+		if err != nil {
+			t.Fatalf("failed to get abs path: %s", err)
+		}
+		cleanup := setupEnv(t, testDataDir)
+		defer cleanup()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get cwd: %s", err)
+		}
+
+		const n = 5
+		var running, maxRunning int32
+		var eg errgroup.Group
+		for i := 0; i < n; i++ {
+			eg.Go(func() error {
+				w := &deptfile.Workspace{SourcePath: cwd, Stderr: ioutil.Discard}
+				return w.Do(func(string, string, *deptfile.File) error {
+					r := atomic.AddInt32(&running, 1)
+					defer atomic.AddInt32(&running, -1)
+					for {
+						m := atomic.LoadInt32(&maxRunning)
+						if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					return nil
+				})
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			t.Fatalf("Do must not return errors, but got an error: %s", err)
+		}
+		if maxRunning != 1 {
+			t.Errorf("mutating workspaces must not run concurrently, but %d ran at the same time", maxRunning)
+		}
+		if _, err := os.Stat(filepath.Join(cwd, deptfile.FileName+".lock")); !os.IsNotExist(err) {
+			t.Errorf("the lock file must be removed after Do, but got '%v'", err)
+		}
+	})
+
 	t.Run("workspace returns ErrNotFound", func(t *testing.T) {
 		dir, err := ioutil.TempDir("", "")
 		if err != nil {
 			t.Fatalf("failed to create a temp dir: %s", err)
 		}
 		w := &deptfile.Workspace{SourcePath: dir}
-		err = w.Do(func(proj string, gomod *deptfile.File) error {
+		err = w.Do(func(proj, workDir string, gomod *deptfile.File) error {
 			return nil
 		})
 		if err != deptfile.ErrNotFound {
 			t.Errorf("workspace must return ErrNotFound because gotool.mod is not found in current working dir, but '%s'", err)
 		}
+		if _, err := os.Stat(filepath.Join(dir, deptfile.FileName+".lock")); !os.IsNotExist(err) {
+			t.Errorf("the lock file must not be created, but got '%v'", err)
+		}
 	})
 }
 
@@ -210,7 +672,7 @@ func checkGoModSyntax(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to read %s", deptfile.FileName)
 	}
-	_, err = modfile.Parse(deptfile.FileName, b, nil)
+	_, err = deptfile.Parse(b)
 	if err != nil {
 		fmt.Println(string(b))
 		t.Fatalf("failed to parse %s: %s", deptfile.FileName, err)
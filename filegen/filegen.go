@@ -4,11 +4,19 @@
 package filegen
 
 import (
+	"bytes"
 	"fmt"
 	"go/format"
 	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
 )
 
+// FileName is the name of the file which is generated by GenerateFile.
+const FileName = "tools.go"
+
 var tmpl = `package tools
 import (
 	%s
@@ -29,3 +37,16 @@ func Generate(w io.Writer, paths []string) {
 	}
 	w.Write(b)
 }
+
+// GenerateFile generates Go source code same as Generate,
+// then writes it to FileName in dir.
+// GenerateFile returns the path of the generated file.
+func GenerateFile(dir string, paths []string) (string, error) {
+	var buf bytes.Buffer
+	Generate(&buf, paths)
+	p := filepath.Join(dir, FileName)
+	if err := ioutil.WriteFile(p, buf.Bytes(), 0644); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s", p)
+	}
+	return p, nil
+}
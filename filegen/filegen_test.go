@@ -2,6 +2,9 @@ package filegen_test
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ktr0731/dept/filegen"
@@ -38,3 +41,29 @@ func TestGeneratorInvalidSource(t *testing.T) {
 	var buf bytes.Buffer
 	filegen.Generate(&buf, df)
 }
+
+func TestGenerateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := filegen.GenerateFile(dir, []string{"github.com/ktr0731/evans"})
+	if err != nil {
+		t.Fatalf("GenerateFile must not return errors, but got '%s'", err)
+	}
+	if expected := filepath.Join(dir, filegen.FileName); p != expected {
+		t.Errorf("expected path is %s, but got %s", expected, p)
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatalf("failed to read the generated file: %s", err)
+	}
+	var buf bytes.Buffer
+	filegen.Generate(&buf, []string{"github.com/ktr0731/evans"})
+	if actual := string(b); buf.String() != actual {
+		t.Errorf("expected:\n%s\n\nactual:\n%s", buf.String(), actual)
+	}
+}
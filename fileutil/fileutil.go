@@ -1,10 +1,13 @@
 package fileutil
 
 import (
+	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 )
 
@@ -44,3 +47,166 @@ func Copy(to, from string) error {
 
 	return nil
 }
+
+// SameContent returns true if a and b have the same content.
+func SameContent(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to open %s", a)
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to open %s", b)
+	}
+	defer fb.Close()
+
+	fia, err := fa.Stat()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get file info from %s", a)
+	}
+	fib, err := fb.Stat()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get file info from %s", b)
+	}
+	if fia.Size() != fib.Size() {
+		return false, nil
+	}
+
+	bufa, bufb := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		na, erra := io.ReadFull(fa, bufa)
+		nb, errb := io.ReadFull(fb, bufb)
+		if !bytes.Equal(bufa[:na], bufb[:nb]) {
+			return false, nil
+		}
+		if erra == io.EOF || erra == io.ErrUnexpectedEOF {
+			return errb == io.EOF || errb == io.ErrUnexpectedEOF, nil
+		}
+		if erra != nil {
+			return false, errors.Wrapf(erra, "failed to read %s", a)
+		}
+		if errb != nil {
+			return false, errors.Wrapf(errb, "failed to read %s", b)
+		}
+	}
+}
+
+var rename = os.Rename
+
+// File represents a file which is written by WriteFiles.
+type File struct {
+	Path string
+	Data []byte
+}
+
+// WriteFiles writes all files as a group.
+// At first, WriteFiles writes each content to a temp file in the same dir as the destination.
+// Then, it renames temp files to destinations.
+// If one of renames failed, WriteFiles rolls back already renamed files to the previous contents.
+//
+// The permission of an existing file is preserved. A new file is created with 0644.
+func WriteFiles(files []*File) (err error) {
+	type state struct {
+		tmp     string
+		mode    os.FileMode
+		existed bool
+		old     []byte
+	}
+	states := make([]*state, 0, len(files))
+	defer func() {
+		// Remove temp files which are not renamed.
+		for _, s := range states {
+			if s.tmp != "" {
+				os.Remove(s.tmp)
+			}
+		}
+	}()
+
+	for _, f := range files {
+		s := &state{mode: 0644}
+		fi, err := os.Stat(f.Path)
+		switch {
+		case err == nil:
+			s.existed = true
+			s.mode = fi.Mode().Perm()
+			s.old, err = ioutil.ReadFile(f.Path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s", f.Path)
+			}
+		case !os.IsNotExist(err):
+			return errors.Wrapf(err, "failed to get file info from %s", f.Path)
+		}
+
+		s.tmp, err = writeTemp(f.Path, f.Data, s.mode)
+		if err != nil {
+			return err
+		}
+		states = append(states, s)
+	}
+
+	for i, f := range files {
+		if err := rename(states[i].tmp, f.Path); err != nil {
+			var rerr error
+			for j := i - 1; j >= 0; j-- {
+				s := states[j]
+				if !s.existed {
+					if err := os.Remove(files[j].Path); err != nil {
+						rerr = multierror.Append(rerr, err)
+					}
+					continue
+				}
+				if err := writeAtomic(files[j].Path, s.old, s.mode); err != nil {
+					rerr = multierror.Append(rerr, err)
+				}
+			}
+			if rerr != nil {
+				return errors.Wrapf(err, "failed to write %s, also failed to roll back: %s", f.Path, rerr)
+			}
+			return errors.Wrapf(err, "failed to write %s", f.Path)
+		}
+		states[i].tmp = ""
+	}
+	return nil
+}
+
+// writeAtomic writes data to a temp file, then renames it to path.
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := writeTemp(path, data, mode)
+	if err != nil {
+		return err
+	}
+	if err := rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "failed to rename %s to %s", tmp, path)
+	}
+	return nil
+}
+
+// writeTemp writes data to a new temp file in the same dir as path.
+// It returns the temp file name.
+func writeTemp(path string, data []byte, mode os.FileMode) (string, error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create a temp file for %s", path)
+	}
+	name := f.Name()
+	err = func() error {
+		defer f.Close()
+		if _, err := f.Write(data); err != nil {
+			return errors.Wrapf(err, "failed to write to %s", name)
+		}
+		if err := f.Chmod(mode); err != nil {
+			return errors.Wrapf(err, "failed to chmod %s", name)
+		}
+		if err := f.Sync(); err != nil {
+			return errors.Wrapf(err, "failed to sync %s", name)
+		}
+		return nil
+	}()
+	if err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
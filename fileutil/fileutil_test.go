@@ -0,0 +1,142 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func setupFiles(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), []byte("old a"), 0600); err != nil {
+		t.Fatalf("failed to write a file: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b"), []byte("old b"), 0644); err != nil {
+		t.Fatalf("failed to write a file: %s", err)
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func assertFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", path, err)
+	}
+	if string(b) != content {
+		t.Errorf("%s: expected content is '%s', but got '%s'", path, content, string(b))
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %s", path, err)
+	}
+	if m := fi.Mode().Perm(); m != mode {
+		t.Errorf("%s: expected mode is %s, but got %s", path, mode, m)
+	}
+}
+
+func assertNoTempFiles(t *testing.T, dir string, n int) {
+	t.Helper()
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %s", err)
+	}
+	if len(fis) != n {
+		t.Errorf("expected %d files in %s, but got %d (temp files remain?)", n, dir, len(fis))
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	t.Run("WriteFiles writes all files with preserving permissions", func(t *testing.T) {
+		dir, cleanup := setupFiles(t)
+		defer cleanup()
+
+		err := WriteFiles([]*File{
+			{Path: filepath.Join(dir, "a"), Data: []byte("new a")},
+			{Path: filepath.Join(dir, "b"), Data: []byte("new b")},
+			{Path: filepath.Join(dir, "c"), Data: []byte("new c")},
+		})
+		if err != nil {
+			t.Fatalf("WriteFiles must not return errors, but got '%s'", err)
+		}
+
+		assertFile(t, filepath.Join(dir, "a"), "new a", 0600)
+		assertFile(t, filepath.Join(dir, "b"), "new b", 0644)
+		assertFile(t, filepath.Join(dir, "c"), "new c", 0644)
+		assertNoTempFiles(t, dir, 3)
+	})
+
+	t.Run("WriteFiles rolls back written files if one of renames failed", func(t *testing.T) {
+		dir, cleanup := setupFiles(t)
+		defer cleanup()
+
+		var n int
+		rename = func(from, to string) error {
+			n++
+			if n == 3 {
+				return errors.New("an error")
+			}
+			return os.Rename(from, to)
+		}
+		defer func() {
+			rename = os.Rename
+		}()
+
+		err := WriteFiles([]*File{
+			{Path: filepath.Join(dir, "a"), Data: []byte("new a")},
+			{Path: filepath.Join(dir, "c"), Data: []byte("new c")},
+			{Path: filepath.Join(dir, "b"), Data: []byte("new b")},
+		})
+		if err == nil {
+			t.Fatal("WriteFiles must return an error, but got nil")
+		}
+
+		assertFile(t, filepath.Join(dir, "a"), "old a", 0600)
+		assertFile(t, filepath.Join(dir, "b"), "old b", 0644)
+		if _, err := os.Stat(filepath.Join(dir, "c")); !os.IsNotExist(err) {
+			t.Errorf("a new file must be removed by rolling back, but got %v", err)
+		}
+		assertNoTempFiles(t, dir, 2)
+	})
+}
+
+func TestSameContent(t *testing.T) {
+	dir, cleanup := setupFiles(t)
+	defer cleanup()
+	if err := ioutil.WriteFile(filepath.Join(dir, "c"), []byte("old a"), 0755); err != nil {
+		t.Fatalf("failed to write a file: %s", err)
+	}
+
+	cases := map[string]struct {
+		a, b     string
+		expected bool
+	}{
+		"same content":      {a: "a", b: "c", expected: true},
+		"different content": {a: "a", b: "b", expected: false},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			same, err := SameContent(filepath.Join(dir, c.a), filepath.Join(dir, c.b))
+			if err != nil {
+				t.Fatalf("SameContent must not return an error, but got '%s'", err)
+			}
+			if same != c.expected {
+				t.Errorf("SameContent must return %t, but got %t", c.expected, same)
+			}
+		})
+	}
+
+	if _, err := SameContent(filepath.Join(dir, "a"), filepath.Join(dir, "d")); err == nil {
+		t.Error("SameContent must return an error if the file doesn't exist, but got nil")
+	}
+}
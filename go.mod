@@ -1,20 +1,28 @@
 module github.com/ktr0731/dept
 
-go 1.13
+go 1.23.0
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/google/go-cmp v0.2.0
+	github.com/google/go-cmp v0.6.0
 	github.com/hashicorp/go-multierror v1.0.0
 	github.com/hashicorp/go-version v1.0.0
-	github.com/ktr0731/modfile v1.11.2
-	github.com/mattn/go-isatty v0.0.4 // indirect
 	github.com/mitchellh/cli v1.0.0
-	github.com/mitchellh/copystructure v1.0.0
 	github.com/pkg/errors v0.8.0
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.2.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0
 	go.uber.org/goleak v0.10.0
-	golang.org/x/sync v0.0.0-20181108010431-42b317875d0f
-	golang.org/x/sys v0.0.0-20190215142949-d0b11bdaac8a // indirect
+	golang.org/x/mod v0.26.0
+	golang.org/x/sync v0.15.0
+	golang.org/x/sys v0.33.0
+)
+
+require (
+	github.com/armon/go-radix v0.0.0-20180808171621-7fddfc383310 // indirect
+	github.com/bgentry/speakeasy v0.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fatih/color v1.7.0 // indirect
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/mattn/go-colorable v0.0.9 // indirect
+	github.com/mattn/go-isatty v0.0.4 // indirect
+	github.com/posener/complete v1.1.1 // indirect
+	github.com/stretchr/testify v1.2.2 // indirect
 )
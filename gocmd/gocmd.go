@@ -23,6 +23,7 @@ func (e *TimeoutErr) Error() string {
 }
 
 // Command provides available Go commands.
+// Each command runs in the passed dir. If dir is empty, it runs in the current dir.
 // Each comamnd may return these errors:
 //
 //   - *TimeoutErr: comamnd timed out
@@ -31,19 +32,24 @@ func (e *TimeoutErr) Error() string {
 //
 type Command interface {
 	// Get executes 'go get' with args.
-	Get(ctx context.Context, args ...string) error
+	Get(ctx context.Context, dir string, args ...string) error
 	// Build executes 'go build' with args.
-	Build(ctx context.Context, args ...string) error
+	Build(ctx context.Context, dir string, args ...string) error
+	// ModInit executes 'go mod init' with the module path.
+	ModInit(ctx context.Context, dir, modPath string) error
 	// ModTidy executes 'go mod tidy'.
-	ModTidy(ctx context.Context) error
+	ModTidy(ctx context.Context, dir string) error
 	// ModDownload executes 'go mod download'
-	ModDownload(ctx context.Context) error
+	ModDownload(ctx context.Context, dir string) error
+	// ModGraph executes 'go mod graph'.
+	// The result is represents as an io.Reader.
+	ModGraph(ctx context.Context, dir string) (io.Reader, error)
 	// List executes 'go list' with args.
 	// The result is represents as an io.Reader.
-	List(ctx context.Context, args ...string) (io.Reader, error)
+	List(ctx context.Context, dir string, args ...string) (io.Reader, error)
 	// Env executes 'go env' with args
 	// The resutl is represents as an io.Reder.
-	Env(ctx context.Context, args ...string) (io.Reader, error)
+	Env(ctx context.Context, dir string, args ...string) (io.Reader, error)
 }
 
 // New returns a new instance of Command.
@@ -53,48 +59,58 @@ func New() Command {
 
 type command struct{}
 
-func (c *command) Get(ctx context.Context, args ...string) error {
-	return run(ctx, 15*time.Minute, "get", args)
+func (c *command) Get(ctx context.Context, dir string, args ...string) error {
+	return run(ctx, 15*time.Minute, dir, "get", args)
+}
+
+func (c *command) Build(ctx context.Context, dir string, args ...string) error {
+	return run(ctx, 15*time.Minute, dir, "build", args)
+}
+
+func (c *command) ModInit(ctx context.Context, dir, modPath string) error {
+	return run(ctx, 1*time.Minute, dir, "mod", []string{"init", modPath})
 }
 
-func (c *command) Build(ctx context.Context, args ...string) error {
-	return run(ctx, 15*time.Minute, "build", args)
+func (c *command) ModTidy(ctx context.Context, dir string) error {
+	return run(ctx, 3*time.Minute, dir, "mod", []string{"tidy"})
 }
 
-func (c *command) ModTidy(ctx context.Context) error {
-	return run(ctx, 3*time.Minute, "mod", []string{"tidy"})
+func (c *command) ModDownload(ctx context.Context, dir string) error {
+	return run(ctx, 3*time.Minute, dir, "mod", []string{"download"})
 }
 
-func (c *command) ModDownload(ctx context.Context) error {
-	return run(ctx, 3*time.Minute, "mod", []string{"download"})
+func (c *command) ModGraph(ctx context.Context, dir string) (io.Reader, error) {
+	return runWithOutput(ctx, 3*time.Minute, dir, "mod", []string{"graph"})
 }
 
-func (c *command) List(ctx context.Context, args ...string) (io.Reader, error) {
-	return runWithOutput(ctx, 10*time.Minute, "list", args)
+func (c *command) List(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+	return runWithOutput(ctx, 10*time.Minute, dir, "list", args)
 }
 
-func (c *command) Env(ctx context.Context, args ...string) (io.Reader, error) {
-	return runWithOutput(ctx, 1*time.Minute, "env", args)
+func (c *command) Env(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+	return runWithOutput(ctx, 1*time.Minute, dir, "env", args)
 }
 
-func runWithOutput(ctx context.Context, timeout time.Duration, command string, args []string) (io.Reader, error) {
+func runWithOutput(ctx context.Context, timeout time.Duration, dir, command string, args []string) (io.Reader, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var out, eout bytes.Buffer
 	cmd := exec.CommandContext(ctx, "go", append([]string{command}, args...)...)
+	cmd.Dir = dir
 	cmd.Stdout = &out
 	cmd.Stderr = &eout
 
 	return &out, runCommand(ctx, cmd)
 }
 
-func run(ctx context.Context, timeout time.Duration, command string, args []string) error {
+func run(ctx context.Context, timeout time.Duration, dir, command string, args []string) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var eout bytes.Buffer
 	cmd := exec.CommandContext(ctx, "go", append([]string{command}, args...)...)
+	cmd.Dir = dir
 	cmd.Stderr = &eout
 
 	return runCommand(ctx, cmd)
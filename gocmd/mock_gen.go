@@ -15,6 +15,8 @@ var (
 	lockCommandMockGet         sync.RWMutex
 	lockCommandMockList        sync.RWMutex
 	lockCommandMockModDownload sync.RWMutex
+	lockCommandMockModGraph    sync.RWMutex
+	lockCommandMockModInit     sync.RWMutex
 	lockCommandMockModTidy     sync.RWMutex
 )
 
@@ -24,22 +26,28 @@ var (
 //
 //         // make and configure a mocked Command
 //         mockedCommand := &CommandMock{
-//             BuildFunc: func(ctx context.Context, args ...string) error {
+//             BuildFunc: func(ctx context.Context, dir string, args ...string) error {
 // 	               panic("mock out the Build method")
 //             },
-//             EnvFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+//             EnvFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 // 	               panic("mock out the Env method")
 //             },
-//             GetFunc: func(ctx context.Context, args ...string) error {
+//             GetFunc: func(ctx context.Context, dir string, args ...string) error {
 // 	               panic("mock out the Get method")
 //             },
-//             ListFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+//             ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 // 	               panic("mock out the List method")
 //             },
-//             ModDownloadFunc: func(ctx context.Context) error {
+//             ModDownloadFunc: func(ctx context.Context, dir string) error {
 // 	               panic("mock out the ModDownload method")
 //             },
-//             ModTidyFunc: func(ctx context.Context) error {
+//             ModGraphFunc: func(ctx context.Context, dir string) (io.Reader, error) {
+// 	               panic("mock out the ModGraph method")
+//             },
+//             ModInitFunc: func(ctx context.Context, dir string, modPath string) error {
+// 	               panic("mock out the ModInit method")
+//             },
+//             ModTidyFunc: func(ctx context.Context, dir string) error {
 // 	               panic("mock out the ModTidy method")
 //             },
 //         }
@@ -50,22 +58,28 @@ var (
 //     }
 type CommandMock struct {
 	// BuildFunc mocks the Build method.
-	BuildFunc func(ctx context.Context, args ...string) error
+	BuildFunc func(ctx context.Context, dir string, args ...string) error
 
 	// EnvFunc mocks the Env method.
-	EnvFunc func(ctx context.Context, args ...string) (io.Reader, error)
+	EnvFunc func(ctx context.Context, dir string, args ...string) (io.Reader, error)
 
 	// GetFunc mocks the Get method.
-	GetFunc func(ctx context.Context, args ...string) error
+	GetFunc func(ctx context.Context, dir string, args ...string) error
 
 	// ListFunc mocks the List method.
-	ListFunc func(ctx context.Context, args ...string) (io.Reader, error)
+	ListFunc func(ctx context.Context, dir string, args ...string) (io.Reader, error)
 
 	// ModDownloadFunc mocks the ModDownload method.
-	ModDownloadFunc func(ctx context.Context) error
+	ModDownloadFunc func(ctx context.Context, dir string) error
+
+	// ModGraphFunc mocks the ModGraph method.
+	ModGraphFunc func(ctx context.Context, dir string) (io.Reader, error)
+
+	// ModInitFunc mocks the ModInit method.
+	ModInitFunc func(ctx context.Context, dir string, modPath string) error
 
 	// ModTidyFunc mocks the ModTidy method.
-	ModTidyFunc func(ctx context.Context) error
+	ModTidyFunc func(ctx context.Context, dir string) error
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -73,6 +87,8 @@ type CommandMock struct {
 		Build []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
 			// Args is the args argument value.
 			Args []string
 		}
@@ -80,6 +96,8 @@ type CommandMock struct {
 		Env []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
 			// Args is the args argument value.
 			Args []string
 		}
@@ -87,6 +105,8 @@ type CommandMock struct {
 		Get []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
 			// Args is the args argument value.
 			Args []string
 		}
@@ -94,6 +114,8 @@ type CommandMock struct {
 		List []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
 			// Args is the args argument value.
 			Args []string
 		}
@@ -101,31 +123,53 @@ type CommandMock struct {
 		ModDownload []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
+		}
+		// ModGraph holds details about calls to the ModGraph method.
+		ModGraph []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
+		}
+		// ModInit holds details about calls to the ModInit method.
+		ModInit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
+			// ModPath is the modPath argument value.
+			ModPath string
 		}
 		// ModTidy holds details about calls to the ModTidy method.
 		ModTidy []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
 		}
 	}
 }
 
 // Build calls BuildFunc.
-func (mock *CommandMock) Build(ctx context.Context, args ...string) error {
+func (mock *CommandMock) Build(ctx context.Context, dir string, args ...string) error {
 	if mock.BuildFunc == nil {
 		panic("CommandMock.BuildFunc: method is nil but Command.Build was just called")
 	}
 	callInfo := struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}{
 		Ctx:  ctx,
+		Dir:  dir,
 		Args: args,
 	}
 	lockCommandMockBuild.Lock()
 	mock.calls.Build = append(mock.calls.Build, callInfo)
 	lockCommandMockBuild.Unlock()
-	return mock.BuildFunc(ctx, args...)
+	return mock.BuildFunc(ctx, dir, args...)
 }
 
 // BuildCalls gets all the calls that were made to Build.
@@ -133,10 +177,12 @@ func (mock *CommandMock) Build(ctx context.Context, args ...string) error {
 //     len(mockedCommand.BuildCalls())
 func (mock *CommandMock) BuildCalls() []struct {
 	Ctx  context.Context
+	Dir  string
 	Args []string
 } {
 	var calls []struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}
 	lockCommandMockBuild.RLock()
@@ -146,21 +192,23 @@ func (mock *CommandMock) BuildCalls() []struct {
 }
 
 // Env calls EnvFunc.
-func (mock *CommandMock) Env(ctx context.Context, args ...string) (io.Reader, error) {
+func (mock *CommandMock) Env(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 	if mock.EnvFunc == nil {
 		panic("CommandMock.EnvFunc: method is nil but Command.Env was just called")
 	}
 	callInfo := struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}{
 		Ctx:  ctx,
+		Dir:  dir,
 		Args: args,
 	}
 	lockCommandMockEnv.Lock()
 	mock.calls.Env = append(mock.calls.Env, callInfo)
 	lockCommandMockEnv.Unlock()
-	return mock.EnvFunc(ctx, args...)
+	return mock.EnvFunc(ctx, dir, args...)
 }
 
 // EnvCalls gets all the calls that were made to Env.
@@ -168,10 +216,12 @@ func (mock *CommandMock) Env(ctx context.Context, args ...string) (io.Reader, er
 //     len(mockedCommand.EnvCalls())
 func (mock *CommandMock) EnvCalls() []struct {
 	Ctx  context.Context
+	Dir  string
 	Args []string
 } {
 	var calls []struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}
 	lockCommandMockEnv.RLock()
@@ -181,21 +231,23 @@ func (mock *CommandMock) EnvCalls() []struct {
 }
 
 // Get calls GetFunc.
-func (mock *CommandMock) Get(ctx context.Context, args ...string) error {
+func (mock *CommandMock) Get(ctx context.Context, dir string, args ...string) error {
 	if mock.GetFunc == nil {
 		panic("CommandMock.GetFunc: method is nil but Command.Get was just called")
 	}
 	callInfo := struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}{
 		Ctx:  ctx,
+		Dir:  dir,
 		Args: args,
 	}
 	lockCommandMockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	lockCommandMockGet.Unlock()
-	return mock.GetFunc(ctx, args...)
+	return mock.GetFunc(ctx, dir, args...)
 }
 
 // GetCalls gets all the calls that were made to Get.
@@ -203,10 +255,12 @@ func (mock *CommandMock) Get(ctx context.Context, args ...string) error {
 //     len(mockedCommand.GetCalls())
 func (mock *CommandMock) GetCalls() []struct {
 	Ctx  context.Context
+	Dir  string
 	Args []string
 } {
 	var calls []struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}
 	lockCommandMockGet.RLock()
@@ -216,21 +270,23 @@ func (mock *CommandMock) GetCalls() []struct {
 }
 
 // List calls ListFunc.
-func (mock *CommandMock) List(ctx context.Context, args ...string) (io.Reader, error) {
+func (mock *CommandMock) List(ctx context.Context, dir string, args ...string) (io.Reader, error) {
 	if mock.ListFunc == nil {
 		panic("CommandMock.ListFunc: method is nil but Command.List was just called")
 	}
 	callInfo := struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}{
 		Ctx:  ctx,
+		Dir:  dir,
 		Args: args,
 	}
 	lockCommandMockList.Lock()
 	mock.calls.List = append(mock.calls.List, callInfo)
 	lockCommandMockList.Unlock()
-	return mock.ListFunc(ctx, args...)
+	return mock.ListFunc(ctx, dir, args...)
 }
 
 // ListCalls gets all the calls that were made to List.
@@ -238,10 +294,12 @@ func (mock *CommandMock) List(ctx context.Context, args ...string) (io.Reader, e
 //     len(mockedCommand.ListCalls())
 func (mock *CommandMock) ListCalls() []struct {
 	Ctx  context.Context
+	Dir  string
 	Args []string
 } {
 	var calls []struct {
 		Ctx  context.Context
+		Dir  string
 		Args []string
 	}
 	lockCommandMockList.RLock()
@@ -251,19 +309,21 @@ func (mock *CommandMock) ListCalls() []struct {
 }
 
 // ModDownload calls ModDownloadFunc.
-func (mock *CommandMock) ModDownload(ctx context.Context) error {
+func (mock *CommandMock) ModDownload(ctx context.Context, dir string) error {
 	if mock.ModDownloadFunc == nil {
 		panic("CommandMock.ModDownloadFunc: method is nil but Command.ModDownload was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
+		Dir string
 	}{
 		Ctx: ctx,
+		Dir: dir,
 	}
 	lockCommandMockModDownload.Lock()
 	mock.calls.ModDownload = append(mock.calls.ModDownload, callInfo)
 	lockCommandMockModDownload.Unlock()
-	return mock.ModDownloadFunc(ctx)
+	return mock.ModDownloadFunc(ctx, dir)
 }
 
 // ModDownloadCalls gets all the calls that were made to ModDownload.
@@ -271,9 +331,11 @@ func (mock *CommandMock) ModDownload(ctx context.Context) error {
 //     len(mockedCommand.ModDownloadCalls())
 func (mock *CommandMock) ModDownloadCalls() []struct {
 	Ctx context.Context
+	Dir string
 } {
 	var calls []struct {
 		Ctx context.Context
+		Dir string
 	}
 	lockCommandMockModDownload.RLock()
 	calls = mock.calls.ModDownload
@@ -281,20 +343,96 @@ func (mock *CommandMock) ModDownloadCalls() []struct {
 	return calls
 }
 
+// ModGraph calls ModGraphFunc.
+func (mock *CommandMock) ModGraph(ctx context.Context, dir string) (io.Reader, error) {
+	if mock.ModGraphFunc == nil {
+		panic("CommandMock.ModGraphFunc: method is nil but Command.ModGraph was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Dir string
+	}{
+		Ctx: ctx,
+		Dir: dir,
+	}
+	lockCommandMockModGraph.Lock()
+	mock.calls.ModGraph = append(mock.calls.ModGraph, callInfo)
+	lockCommandMockModGraph.Unlock()
+	return mock.ModGraphFunc(ctx, dir)
+}
+
+// ModGraphCalls gets all the calls that were made to ModGraph.
+// Check the length with:
+//     len(mockedCommand.ModGraphCalls())
+func (mock *CommandMock) ModGraphCalls() []struct {
+	Ctx context.Context
+	Dir string
+} {
+	var calls []struct {
+		Ctx context.Context
+		Dir string
+	}
+	lockCommandMockModGraph.RLock()
+	calls = mock.calls.ModGraph
+	lockCommandMockModGraph.RUnlock()
+	return calls
+}
+
+// ModInit calls ModInitFunc.
+func (mock *CommandMock) ModInit(ctx context.Context, dir string, modPath string) error {
+	if mock.ModInitFunc == nil {
+		panic("CommandMock.ModInitFunc: method is nil but Command.ModInit was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Dir     string
+		ModPath string
+	}{
+		Ctx:     ctx,
+		Dir:     dir,
+		ModPath: modPath,
+	}
+	lockCommandMockModInit.Lock()
+	mock.calls.ModInit = append(mock.calls.ModInit, callInfo)
+	lockCommandMockModInit.Unlock()
+	return mock.ModInitFunc(ctx, dir, modPath)
+}
+
+// ModInitCalls gets all the calls that were made to ModInit.
+// Check the length with:
+//     len(mockedCommand.ModInitCalls())
+func (mock *CommandMock) ModInitCalls() []struct {
+	Ctx     context.Context
+	Dir     string
+	ModPath string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Dir     string
+		ModPath string
+	}
+	lockCommandMockModInit.RLock()
+	calls = mock.calls.ModInit
+	lockCommandMockModInit.RUnlock()
+	return calls
+}
+
 // ModTidy calls ModTidyFunc.
-func (mock *CommandMock) ModTidy(ctx context.Context) error {
+func (mock *CommandMock) ModTidy(ctx context.Context, dir string) error {
 	if mock.ModTidyFunc == nil {
 		panic("CommandMock.ModTidyFunc: method is nil but Command.ModTidy was just called")
 	}
 	callInfo := struct {
 		Ctx context.Context
+		Dir string
 	}{
 		Ctx: ctx,
+		Dir: dir,
 	}
 	lockCommandMockModTidy.Lock()
 	mock.calls.ModTidy = append(mock.calls.ModTidy, callInfo)
 	lockCommandMockModTidy.Unlock()
-	return mock.ModTidyFunc(ctx)
+	return mock.ModTidyFunc(ctx, dir)
 }
 
 // ModTidyCalls gets all the calls that were made to ModTidy.
@@ -302,9 +440,11 @@ func (mock *CommandMock) ModTidy(ctx context.Context) error {
 //     len(mockedCommand.ModTidyCalls())
 func (mock *CommandMock) ModTidyCalls() []struct {
 	Ctx context.Context
+	Dir string
 } {
 	var calls []struct {
 		Ctx context.Context
+		Dir string
 	}
 	lockCommandMockModTidy.RLock()
 	calls = mock.calls.ModTidy
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/fileutil"
+	"github.com/ktr0731/dept/logger"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// Build builds all tools, then copies them to the output dir.
+// Built tools are cached, so Build builds each tool only once for each version.
+// Build returns a map which maps output names to paths of copied binaries.
+// gotool.local.mod is merged on top of gotool.mod if it exists.
+// Build uses OutputDir option.
+func (m *Manager) Build(ctx context.Context, opts ...BuildOption) (map[string]string, error) {
+	o := newOptions(opts)
+	outputDir, err := absOutputDir(o.outputDir)
+	if err != nil {
+		return nil, err
+	}
+	cacher, err := m.toolCacher()
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	binPaths := map[string]string{}
+	err = m.localWorkspace(true).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		outputDir := resolveOutputDir(projRoot, outputDir)
+
+		var tools []*Tool
+		for _, r := range df.Require {
+			forToolsWithOutputName(r, func(path, out string) bool {
+				tools = appendTool(tools, df, r, path, out)
+				return true
+			})
+		}
+
+		eg, ctx := errgroup.WithContext(ctx)
+		for _, t := range tools {
+			t := t
+			eg.Go(func() error {
+				version, err := t.cacheVersion(ctx, cacher)
+				if err != nil {
+					return err
+				}
+				cachePath, err := cacher.Get(ctx, workDir, t.Path, version)
+				if err != nil {
+					return errors.Wrapf(err, "failed to get cache of %s", t.Path)
+				}
+				binPath := filepath.Join(outputDir, t.Name)
+				logger.Printf("copy %s from %s to %s", t.Path, cachePath, binPath)
+				if err := fileutil.Copy(binPath, cachePath); err != nil {
+					return errors.Wrapf(err, "failed to copy %s from %s to %s", t.Path, cachePath, binPath)
+				}
+				mu.Lock()
+				binPaths[t.Name] = binPath
+				mu.Unlock()
+				return nil
+			})
+		}
+		return eg.Wait()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return binPaths, nil
+}
+
+// Resolve returns the path of the cached binary of the tool which has name as the output name.
+// If the tool is not cached yet, Resolve builds it.
+// If the tool is not managed, Resolve returns *ToolNotFoundErr.
+// gotool.local.mod is merged on top of gotool.mod if it exists.
+func (m *Manager) Resolve(ctx context.Context, name string) (string, error) {
+	cacher, err := m.toolCacher()
+	if err != nil {
+		return "", err
+	}
+
+	var cachePath string
+	err = m.localWorkspace(true).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		t, err := findTool(df, name)
+		if err != nil {
+			return err
+		}
+		version, err := t.cacheVersion(ctx, cacher)
+		if err != nil {
+			return err
+		}
+		cachePath, err = cacher.Get(ctx, workDir, t.Path, version)
+		if err != nil {
+			return errors.Wrap(err, "failed to get a cached tool path")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+// Exec executes the tool which has name as the output name with args.
+// The tool is connected to Stdin, Stdout and Stderr.
+// If the tool is not managed, Exec returns *ToolNotFoundErr.
+func (m *Manager) Exec(ctx context.Context, name string, args []string) error {
+	p, err := m.Resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, p, args...)
+	cmd.Stdin = m.stdin()
+	cmd.Stdout = m.stdout()
+	cmd.Stderr = m.stderr()
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to execute %s", strings.Join(append([]string{name}, args...), " "))
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package manager provides the Go API to manage Go tools which are listed in gotool.mod.
+// All sub-commands of dept are implemented on top of this package.
+package manager
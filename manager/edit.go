@@ -0,0 +1,27 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/ktr0731/dept/deptfile"
+)
+
+// Edit calls f with the current gotool.mod, then writes changes made by f back to gotool.mod.
+// Edit never runs the go command, so dependencies of added tools are not resolved
+// until the next Get or Build.
+// Edit uses DryRun and Description options.
+func (m *Manager) Edit(ctx context.Context, f func(df *deptfile.File) error, opts ...EditOption) error {
+	return m.edit(newOptions(opts), "edit", func(_ string, df *deptfile.File) error {
+		return f(df)
+	})
+}
+
+// edit is the implementation of Edit. f also receives the project root dir.
+func (m *Manager) edit(o *options, desc string, f func(projRoot string, df *deptfile.File) error) error {
+	return m.workspace(false).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		if err := f(projRoot, df); err != nil {
+			return err
+		}
+		return deptfile.WriteGoMod(workDir, df)
+	}, o.workspaceOptions(desc)...)
+}
@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/pkg/errors"
+)
+
+// Export formats.
+const (
+	// ExportGoModTool is the export format which is a go.mod with tool directives.
+	ExportGoModTool = "gomod-tool"
+	// ExportMakefile is the export format which is a Makefile fragment which installs tools.
+	ExportMakefile = "makefile"
+	// ExportShell is the export format which is a shell script which installs tools.
+	ExportShell = "sh"
+	// ExportToolsGo is the export format which is a tools.go with blank imports.
+	ExportToolsGo = "tools-go"
+)
+
+const (
+	// defaultToolsDir is the default dir to install tools by exported Makefile and shell script.
+	defaultToolsDir = "_tools"
+	// toolsGoDir is the dir which has tools.go in the project root.
+	toolsGoDir = "tools"
+)
+
+// Export converts gotool.mod into format and returns it.
+//
+// ExportGoModTool converts gotool.mod into a go.mod which has tool directives and requirements
+// equivalent to gotool.mod, so the go command can run tools by 'go tool'.
+// If Write option is passed, Export patches go.mod and go.sum in the project root instead of
+// returning the converted one. Versions of tool modules are the same as gotool.mod.
+// Because 'go tool' doesn't support output names, Export also returns warnings for renamed tools.
+//
+// ExportMakefile and ExportShell convert gotool.mod into a Makefile fragment which has a target per tool
+// and an equivalent shell script. They install tools by 'go install path@version' to $(TOOLS_DIR)
+// and rename them to output names. The default TOOLS_DIR is OutputDir, or '_tools' if it is empty.
+// Targets of the Makefile depend on $(DEPTFILE) which is gotool.mod by default.
+// Because 'go install' ignores replace directives, Export returns warnings for replaced tools.
+//
+// ExportToolsGo converts gotool.mod into a tools.go which imports all tools with the 'tools' build constraint.
+// If Write option is passed, Export writes it to 'tools' dir in the project root, then patches go.mod and go.sum
+// without tool directives.
+//
+// If format is not supported, Export returns ErrUnknownFormat.
+// Export uses OutputDir and Write options.
+func (m *Manager) Export(ctx context.Context, format string, opts ...ExportOption) ([]byte, []string, error) {
+	o := newOptions(opts)
+	switch format {
+	case ExportGoModTool, ExportToolsGo:
+	case ExportMakefile, ExportShell:
+		if o.write {
+			return nil, nil, errors.Errorf("%s format doesn't support writing into the project", format)
+		}
+	default:
+		return nil, nil, errors.Wrap(ErrUnknownFormat, format)
+	}
+
+	var (
+		b        []byte
+		warnings []string
+	)
+	err := m.workspace(true).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		tools := listTools(df)
+		var err error
+		switch format {
+		case ExportGoModTool:
+			warnings = renamedToolWarnings(tools)
+			if o.write {
+				return patchGoMod(projRoot, df, true)
+			}
+			b, err = df.GoMod(true)
+		case ExportMakefile:
+			warnings = replacedToolWarnings(tools)
+			b = exportMakefile(tools, o.outputDir)
+		case ExportShell:
+			warnings = replacedToolWarnings(tools)
+			b = exportShell(tools, o.outputDir)
+		case ExportToolsGo:
+			b = exportToolsGo(tools)
+			if o.write {
+				fname := filepath.Join(projRoot, toolsGoDir, filegen.FileName)
+				if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+					return errors.Wrapf(err, "failed to create the dir of %s", fname)
+				}
+				if err := ioutil.WriteFile(fname, b, 0644); err != nil {
+					return errors.Wrapf(err, "failed to write %s", fname)
+				}
+				b = nil
+				return patchGoMod(projRoot, df, false)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, warnings, nil
+}
+
+// listTools returns all tools in df.
+func listTools(df *deptfile.File) []*Tool {
+	var tools []*Tool
+	for _, r := range df.Require {
+		forToolsWithOutputName(r, func(path, out string) bool {
+			tools = appendTool(tools, df, r, path, out)
+			return true
+		})
+	}
+	return tools
+}
+
+// exportMakefile returns a Makefile fragment which installs tools to dir.
+// Each target depends on gotool.mod, so tools are reinstalled when it is updated.
+// Renamed tools are installed to their own dirs before copying, so parallel builds don't conflict.
+func exportMakefile(tools []*Tool, dir string) []byte {
+	if dir == "" {
+		dir = defaultToolsDir
+	}
+	var b strings.Builder
+	b.WriteString("# Code generated by dept export. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "TOOLS_DIR ?= %s\n", dir)
+	fmt.Fprintf(&b, "DEPTFILE ?= %s\n\n", deptfile.FileName)
+	b.WriteString(".PHONY: tools\ntools:")
+	for _, t := range tools {
+		fmt.Fprintf(&b, " $(TOOLS_DIR)/%s", t.Name)
+	}
+	b.WriteString("\n")
+	for _, t := range tools {
+		fmt.Fprintf(&b, "\n$(TOOLS_DIR)/%s: $(DEPTFILE)\n", t.Name)
+		if base := filepath.Base(t.Path); base != t.Name {
+			fmt.Fprintf(&b, "\tGOBIN=$(abspath $(TOOLS_DIR)/.%s) go install %s@%s\n", t.Name, t.Path, t.Version)
+			fmt.Fprintf(&b, "\tcp $(TOOLS_DIR)/.%s/%s $@\n", t.Name, base)
+			continue
+		}
+		fmt.Fprintf(&b, "\tGOBIN=$(abspath $(dir $@)) go install %s@%s\n", t.Path, t.Version)
+		// go install doesn't update the binary if it is up to date.
+		b.WriteString("\t@touch $@\n")
+	}
+	return []byte(b.String())
+}
+
+// exportShell returns a shell script which installs tools to dir.
+// Renamed tools are installed to their own dirs before renaming, so they never overwrite other tools.
+func exportShell(tools []*Tool, dir string) []byte {
+	if dir == "" {
+		dir = defaultToolsDir
+	}
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n# Code generated by dept export. DO NOT EDIT.\nset -eu\n\n")
+	fmt.Fprintf(&b, "TOOLS_DIR=\"${TOOLS_DIR:-%s}\"\n", dir)
+	b.WriteString("mkdir -p \"$TOOLS_DIR\"\nGOBIN=\"$(cd \"$TOOLS_DIR\" && pwd)\"\nexport GOBIN\n\n")
+	for _, t := range tools {
+		if base := filepath.Base(t.Path); base != t.Name {
+			fmt.Fprintf(&b, "GOBIN=\"$GOBIN/.%s\" go install %s@%s\n", t.Name, t.Path, t.Version)
+			fmt.Fprintf(&b, "mv \"$GOBIN/.%s/%s\" \"$GOBIN/%s\"\n", t.Name, base, t.Name)
+			fmt.Fprintf(&b, "rmdir \"$GOBIN/.%s\"\n", t.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "go install %s@%s\n", t.Path, t.Version)
+	}
+	return []byte(b.String())
+}
+
+// exportToolsGo returns a tools.go which imports tools.
+func exportToolsGo(tools []*Tool) []byte {
+	paths := make([]string, 0, len(tools))
+	for _, t := range tools {
+		paths = append(paths, t.Path)
+	}
+	sort.Strings(paths)
+	var b bytes.Buffer
+	b.WriteString("//go:build tools\n\n")
+	filegen.Generate(&b, paths)
+	return b.Bytes()
+}
+
+// renamedToolWarnings returns warnings for tools which have the output name.
+func renamedToolWarnings(tools []*Tool) []string {
+	var warnings []string
+	for _, t := range tools {
+		if base := filepath.Base(t.Path); t.Name != base {
+			warnings = append(warnings, fmt.Sprintf("%s is renamed to %s, but 'go tool' runs it as %s", t.Path, t.Name, base))
+		}
+	}
+	return warnings
+}
+
+// replacedToolWarnings returns warnings for tools whose modules are replaced.
+func replacedToolWarnings(tools []*Tool) []string {
+	var warnings []string
+	for _, t := range tools {
+		if t.Replace != "" {
+			warnings = append(warnings, fmt.Sprintf("%s is replaced by %s, but 'go install' ignores replace directives", t.Path, t.Replace))
+		}
+	}
+	return warnings
+}
+
+// patchGoMod adds tools of df to go.mod in projRoot, then merges gotool.sum into go.sum.
+// If withTools is true, tool directives are also added.
+func patchGoMod(projRoot string, df *deptfile.File, withTools bool) error {
+	fname := filepath.Join(projRoot, "go.mod")
+	gomod, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", fname)
+	}
+	b, err := df.PatchGoMod(fname, gomod, withTools)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fname, b, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", fname)
+	}
+	return mergeSum(filepath.Join(projRoot, "go.sum"), filepath.Join(projRoot, deptfile.FileSumName))
+}
+
+// mergeSum adds lines of src which dst doesn't have to dst.
+// If src doesn't exist, mergeSum does nothing.
+func mergeSum(dst, src string) error {
+	srcSum, err := ioutil.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", src)
+	}
+	dstSum, err := ioutil.ReadFile(dst)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", dst)
+	}
+
+	found := map[string]bool{}
+	var lines []string
+	for _, l := range strings.Split(string(dstSum)+"\n"+string(srcSum), "\n") {
+		if l == "" || found[l] {
+			continue
+		}
+		found[l] = true
+		lines = append(lines, l)
+	}
+	sort.Strings(lines)
+	if err := ioutil.WriteFile(dst, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", dst)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Fmt formats gotool.mod in the canonical form.
+// Fmt merges duplicated requires and sorts tool paths. Comments are kept as it is.
+// Like Edit, Fmt never runs the go command.
+// Fmt returns the unified diff between the current and the formatted gotool.mod.
+// If gotool.mod is already formatted, the diff is empty.
+// Fmt uses DryRun and Description options.
+func (m *Manager) Fmt(ctx context.Context, opts ...EditOption) (string, error) {
+	var diff string
+	err := m.edit(newOptions(opts), "fmt", func(projRoot string, df *deptfile.File) error {
+		fname := filepath.Join(projRoot, deptfile.FileName)
+		before, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", fname)
+		}
+		df.Normalize()
+		after, err := df.Format()
+		if err != nil {
+			return err
+		}
+		diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(before)),
+			B:        difflib.SplitLines(string(after)),
+			FromFile: "a/" + deptfile.FileName,
+			ToFile:   "b/" + deptfile.FileName,
+			Context:  3,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to compute the diff of %s", deptfile.FileName)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return diff, nil
+}
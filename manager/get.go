@@ -0,0 +1,372 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/logger"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/sync/errgroup"
+)
+
+// localVersion is the version of modules which are replaced by local dirs.
+// It is the same as the one which the go command uses.
+const localVersion = "v0.0.0-00010101000000-000000000000"
+
+// Target represents a tool which is passed to Get.
+type Target struct {
+	// Path is the import path of the tool with an optional version.
+	// For example, 'github.com/ktr0731/salias@v0.1.0'
+	// Path can also be a local dir like './tools/gen'. See Get for details.
+	Path string
+	// Name is the output name of the tool.
+	// If Name is empty, filepath.Base of the path is used.
+	Name string
+}
+
+// Get adds targets to gotool.mod, then builds them.
+// Get works as follows.
+//
+//   1. load deptfile.
+//   2. generate Go code which imports all required tools.
+//   3. run 'go get' with Go modules aware mode to collect dependencies of 2.
+//   4. build binaries.
+//
+// If a target is a local dir, the module which has the dir is replaced by it.
+// The replace directive is recorded in gotool.mod with the path relative to the project root.
+// Cached binaries of such tools are keyed by the hash of the source tree,
+// so they are rebuilt when the local code changes.
+//
+// If Update option is passed, Get updates targets to the latest version.
+// If Update option is passed without targets, Get updates all tools.
+// If no targets are passed without Update option, Get returns ErrNoTargets.
+// If Local option is passed, Get writes targets to gotool.local.mod which is merged on top of gotool.mod,
+// and creates it if it doesn't exist.
+// Get uses OutputDir, Update, DryRun, OnChange, Description and Local options.
+// In dry-run mode, Get doesn't build tools.
+func (m *Manager) Get(ctx context.Context, targets []*Target, opts ...GetOption) error {
+	o := newOptions(opts)
+	outputDir, err := absOutputDir(o.outputDir)
+	if err != nil {
+		return err
+	}
+
+	desc := []string{"get"}
+	if o.update {
+		desc = append(desc, "-u")
+	}
+	for _, t := range targets {
+		desc = append(desc, t.Path)
+	}
+
+	w := m.workspace(false)
+	if o.local {
+		w = m.localWorkspace(false)
+	}
+
+	gocmd := m.gocmd()
+	return w.Do(func(projRoot, workDir string, df *deptfile.File) error {
+		localPaths, targets, err := initLocalPaths(projRoot, workDir, df, targets)
+		if err != nil {
+			return err
+		}
+		paths, err := initModPaths(ctx, gocmd, workDir, targets)
+		if err != nil {
+			return err
+		}
+		paths = append(localPaths, paths...)
+
+		if len(paths) == 0 && !o.update {
+			return ErrNoTargets
+		}
+
+		cleanup, err := generateGoFile(workDir, df, paths)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if len(paths) == 0 && o.update {
+			logger.Println("updating all tools to the latest version")
+			if err := gocmd.Get(ctx, workDir, "-u", "-d"); err != nil {
+				return errors.Wrap(err, "failed to update Go tools")
+			}
+			return nil
+		}
+
+		// Always Get runs 'go get'.
+		// If an unmanaged tool is passed with -u option, '// indirect' will be marked
+		// because it is not included in gotool.mod.
+		getArgs := make([]string, 0, 1+len(paths))
+		getArgs = append(getArgs, "-d")
+		for _, p := range paths {
+			// Local tools are resolved by the replace directive.
+			if !p.Local {
+				getArgs = append(getArgs, p.modPath())
+			}
+		}
+		logger.Println("getting all dependencies")
+		if err := gocmd.Get(ctx, workDir, append(getArgs, ".")...); err != nil {
+			return errors.Wrap(err, "failed to get Go tools dependencies")
+		}
+
+		eg, ctx := errgroup.WithContext(ctx)
+		outputDir := resolveOutputDir(projRoot, outputDir)
+		for _, path := range paths {
+			path := path
+			eg.Go(func() error {
+				// If also Update is passed, update Repo to the latest.
+				if o.update && path.Ver == "" && !path.Local {
+					logger.Printf("updating %s to the latest version", path.Repo)
+					if err := gocmd.Get(ctx, workDir, "-u", "-d", path.Repo); err != nil {
+						return errors.Wrap(err, "failed to get Go tools dependencies")
+					}
+				}
+
+				if o.dryRun {
+					return nil
+				}
+
+				binPath := filepath.Join(outputDir, toolName(path.Repo, path.Out))
+				logger.Printf("building %s to %s", path.Repo, binPath)
+				if err := gocmd.Build(ctx, workDir, "-o", binPath, path.Repo); err != nil {
+					return errors.Wrapf(err, "failed to buld %s (bin path = %s)", path.Repo, binPath)
+				}
+
+				return nil
+			})
+		}
+		if err := eg.Wait(); errors.Cause(err) == context.Canceled {
+			return context.Canceled
+		} else if err != nil {
+			return errors.Wrap(err, "failed to build tools")
+		}
+
+		return nil
+	}, o.workspaceOptions(strings.Join(desc, " "))...)
+}
+
+// initModPaths parses passed targets and collect its module roots.
+// initModPaths must be called inside of a workspace. dir is the workspace dir.
+func initModPaths(ctx context.Context, gocmd gocmd.Command, dir string, targets []*Target) ([]*path, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	// Targets which have the output name are got in advance
+	// to prevent updating go.mod by 'go list'.
+	getPaths := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if t.Name != "" {
+			getPaths = append(getPaths, t.Path)
+		}
+	}
+	if len(getPaths) > 0 {
+		logger.Println("getting all dependencies passed with output names")
+		if err := gocmd.Get(ctx, dir, getPaths...); err != nil {
+			return nil, errors.Wrap(err, "failed to get additional dependencies")
+		}
+	}
+
+	found := map[string]interface{}{}
+	paths := make([]*path, 0, len(targets))
+
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, t := range targets {
+		repo, ver, err := ParsePath(t.Path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := found[repo]; ok {
+			continue
+		}
+		found[repo] = nil
+		path := &path{Val: t.Path, Repo: repo, Ver: ver, Out: t.Name}
+		paths = append(paths, path)
+		eg.Go(func() (err error) {
+			path.ModRoot, err = getModuleRoot(ctx, gocmd, dir, path.Repo)
+			return
+		})
+	}
+	return paths, eg.Wait()
+}
+
+// initLocalPaths finds targets which are local dirs, then replaces modules which have them by the dirs.
+// It returns paths of local targets and remaining targets.
+// initLocalPaths must be called inside of a workspace. dir is the workspace dir.
+func initLocalPaths(projRoot, dir string, df *deptfile.File, targets []*Target) ([]*path, []*Target, error) {
+	var paths []*path
+	remains := make([]*Target, 0, len(targets))
+	for _, t := range targets {
+		if !modfile.IsDirectoryPath(t.Path) {
+			remains = append(remains, t)
+			continue
+		}
+		modPath, modDir, importPath, err := findLocalModule(t.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		rel, err := filepath.Rel(projRoot, modDir)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get the relative path of %s", modDir)
+		}
+		rel = filepath.ToSlash(rel)
+		if !modfile.IsDirectoryPath(rel) {
+			rel = "./" + rel
+		}
+		logger.Printf("replace %s by %s", modPath, rel)
+		df.AddReplace(modPath, "", rel, "")
+
+		if err := df.AddTool(modPath, strings.TrimPrefix(importPath, modPath), t.Name); err != nil {
+			if errors.Cause(err) == deptfile.ErrToolNameConflicted {
+				return nil, nil, errors.Wrap(err, "please rename tool name by -o option")
+			}
+			return nil, nil, err
+		}
+		for _, r := range df.Require {
+			if r.Path == modPath && r.Version == "" {
+				r.Version = localVersion
+			}
+		}
+		paths = append(paths, &path{Val: t.Path, ModRoot: modPath, Repo: importPath, Out: t.Name, Local: true})
+	}
+	if len(paths) == 0 {
+		return nil, remains, nil
+	}
+	// Write out the replace directives before running the go command.
+	if err := deptfile.WriteGoMod(dir, df); err != nil {
+		return nil, nil, err
+	}
+	return paths, remains, nil
+}
+
+// findLocalModule finds the module which has the local dir.
+// It returns the module path, the module root dir and the import path of the package in dir.
+func findLocalModule(dir string) (modPath, modDir, importPath string, err error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", "", errors.Wrapf(err, "failed to get the abs path of %s", dir)
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		b, err := ioutil.ReadFile(filepath.Join(d, "go.mod"))
+		if os.IsNotExist(err) {
+			if filepath.Dir(d) == d {
+				return "", "", "", errors.Errorf("go.mod is not found in %s or any parent dirs", dir)
+			}
+			continue
+		}
+		if err != nil {
+			return "", "", "", errors.Wrapf(err, "failed to read go.mod in %s", d)
+		}
+		modPath := modfile.ModulePath(b)
+		if modPath == "" {
+			return "", "", "", errors.Errorf("go.mod in %s has no module path", d)
+		}
+		rel, err := filepath.Rel(d, abs)
+		if err != nil {
+			return "", "", "", errors.Wrapf(err, "failed to get the relative path of %s", abs)
+		}
+		importPath := modPath
+		if rel != "." {
+			importPath += "/" + filepath.ToSlash(rel)
+		}
+		return modPath, d, importPath, nil
+	}
+}
+
+// generateGoFile adds paths to df, then generates a Go file which imports all tools of df in dir.
+// File name is always "tools.go", also package name is "tools".
+// Returned func is a cleanup function.
+func generateGoFile(dir string, df *deptfile.File, paths []*path) (func(), error) {
+	for _, path := range paths {
+		// If the tool is already managed and -o is passed, the tool is renamed to it.
+		if err := df.AddTool(path.ModRoot, strings.TrimPrefix(path.Repo, path.ModRoot), path.Out); err != nil {
+			if errors.Cause(err) == deptfile.ErrToolNameConflicted {
+				return nil, errors.Wrap(err, "please rename tool name by -o option")
+			}
+			return nil, err
+		}
+	}
+
+	importPaths := make([]string, 0, len(df.Require))
+	for _, r := range df.Require {
+		forTools(r, func(importPath string) bool {
+			importPaths = append(importPaths, importPath)
+			return true
+		})
+	}
+
+	fname, err := filegen.GenerateFile(dir, importPaths)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a temp file which contains required Go tools in the import statement")
+	}
+
+	return func() {
+		if err := recover(); err != nil {
+			os.Remove(fname)
+			panic(err)
+		}
+		os.Remove(fname)
+	}, nil
+}
+
+func getModuleRoot(ctx context.Context, gocmd gocmd.Command, dir, path string) (string, error) {
+	logger.Printf("get the module root of %s", path)
+	res, err := gocmd.List(ctx, dir, "-f", `{{ .Module.Path }}`, path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the module root of %s", path)
+	}
+	b, err := ioutil.ReadAll(res)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to convert io.Reader to string")
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+type path struct {
+	// Val is the original value of path.
+	// For example, 'github.com/ktr0731/salias@v0.1.0'
+	Val string
+	// ModRoot is the module root of path without the version.
+	// For example, 'github.com/ktr0731/salias'
+	ModRoot string
+	// Repo is the repository name of Val.
+	// For example, 'github.com/ktr0731/salias'
+	Repo string
+	// Ver is the version of Val.
+	// For example, 'v0.1.0'
+	Ver string
+	// Out is the output name of the tool specified by path.
+	// For example, 'salias'
+	// If Out is empty, it means Out is same as filepath.Base(Repo).
+	Out string
+	// Local reports whether the tool is in a local dir.
+	Local bool
+}
+
+// modPath returns the completely module path which includes module's version.
+func (p *path) modPath() string {
+	if p.Ver != "" {
+		return p.ModRoot + "@" + p.Ver
+	}
+	return p.ModRoot
+}
+
+// absOutputDir converts dir to an absolute path if dir is not empty.
+func absOutputDir(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the abs path of %s", dir)
+	}
+	return abs, nil
+}
@@ -0,0 +1,345 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Import sources.
+const (
+	// ImportFromGoMod is the import source which is a go.mod with tool directives.
+	ImportFromGoMod = "gomod"
+	// ImportFromToolsGo is the import source which is a tools.go with blank imports.
+	ImportFromToolsGo = "tools-go"
+)
+
+// Import adds tools which are managed in another way to gotool.mod.
+// If gotool.mod doesn't exist, Import creates it in the same way as Init.
+//
+// ImportFromGoMod imports tools of tool directives in the go.mod fname.
+// If fname is empty, go.mod in the project root is used.
+//
+// ImportFromToolsGo imports tools which are imported by blank imports in the Go file fname.
+// Build constraints like '//go:build tools' are ignored. Versions are resolved by the nearest go.mod
+// from fname. If fname is empty, tools.go or tools/tools.go in the project root is used.
+//
+// If from is empty, it is detected by the extension of fname.
+// Versions of tool modules are the same as the go.mod, and replace directives of them are also imported.
+// If gotool.mod already requires a tool module at another version, Import returns an error instead of changing it.
+// Requirements of the go.mod which are in the module graph of imported tools are added as indirect ones
+// and go.sum is merged into gotool.sum, so nothing is upgraded by the next Get, Build or Tidy.
+// The module graph is computed by 'go mod graph' in the dir of the go.mod.
+//
+// If from is not supported, Import returns ErrUnknownFormat.
+// Import returns imported tools.
+// Import uses DryRun and Description options.
+func (m *Manager) Import(ctx context.Context, from, fname string, opts ...ImportOption) ([]*Tool, error) {
+	if from == "" {
+		from = ImportFromGoMod
+		if filepath.Ext(fname) == ".go" {
+			from = ImportFromToolsGo
+		}
+	}
+	if from != ImportFromGoMod && from != ImportFromToolsGo {
+		return nil, errors.Wrap(ErrUnknownFormat, from)
+	}
+	o := newOptions(opts)
+
+	var tools []*Tool
+	f := func(projRoot, workDir string, df *deptfile.File) error {
+		current, err := readGoMod(filepath.Join(workDir, "go.mod"))
+		if err != nil {
+			return err
+		}
+		required := make(map[string]string, len(current.Require))
+		for _, r := range current.Require {
+			required[r.Mod.Path] = r.Mod.Version
+		}
+		var gomod string
+		if from == ImportFromGoMod {
+			tools, gomod, err = importGoMod(projRoot, fname, df, required)
+		} else {
+			tools, gomod, err = importToolsGo(projRoot, fname, df, required)
+		}
+		if err != nil {
+			return err
+		}
+		if err := deptfile.WriteGoMod(workDir, df); err != nil {
+			return err
+		}
+		return importIndirects(ctx, m.gocmd(), workDir, gomod, tools)
+	}
+	desc := "import -from=" + from
+	err := m.workspace(false).Do(f, o.workspaceOptions(desc)...)
+	if err == ErrNotFound {
+		if err := m.Init(ctx); err != nil {
+			return nil, err
+		}
+		err = m.workspace(false).Do(f, o.workspaceOptions(desc)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tools, nil
+}
+
+// importGoMod adds tools of tool directives in the go.mod fname to df.
+// It returns imported tools and the name of the go.mod.
+func importGoMod(projRoot, fname string, df *deptfile.File, required map[string]string) ([]*Tool, string, error) {
+	if fname == "" {
+		fname = filepath.Join(projRoot, "go.mod")
+	}
+	mf, err := readGoMod(fname)
+	if err != nil {
+		return nil, "", err
+	}
+	paths := make([]string, 0, len(mf.Tool))
+	for _, t := range mf.Tool {
+		paths = append(paths, t.Path)
+	}
+	tools, err := importTools(projRoot, fname, mf, df, paths, required)
+	if err != nil {
+		return nil, "", err
+	}
+	return tools, fname, nil
+}
+
+// importToolsGo adds tools which are imported by blank imports in the Go file fname to df.
+// It returns imported tools and the name of the go.mod which is used to resolve versions.
+func importToolsGo(projRoot, fname string, df *deptfile.File, required map[string]string) ([]*Tool, string, error) {
+	if fname == "" {
+		fname = filepath.Join(projRoot, filegen.FileName)
+		if _, err := os.Stat(fname); os.IsNotExist(err) {
+			fname = filepath.Join(projRoot, toolsGoDir, filegen.FileName)
+		}
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), fname, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to parse %s", fname)
+	}
+	var paths []string
+	for _, imp := range f.Imports {
+		if imp.Name == nil || imp.Name.Name != "_" {
+			continue
+		}
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "invalid import path %s", imp.Path.Value)
+		}
+		paths = append(paths, p)
+	}
+
+	_, modDir, _, err := findLocalModule(filepath.Dir(fname))
+	if err != nil {
+		return nil, "", err
+	}
+	gomod := filepath.Join(modDir, "go.mod")
+	mf, err := readGoMod(gomod)
+	if err != nil {
+		return nil, "", err
+	}
+	tools, err := importTools(projRoot, gomod, mf, df, paths, required)
+	if err != nil {
+		return nil, "", err
+	}
+	return tools, gomod, nil
+}
+
+// importTools adds tools which have paths to df with versions which are required by mf.
+// fname is the file name of mf. required maps modules which are already required to each version.
+// If the version of a required module would be changed, importTools returns an error.
+func importTools(projRoot, fname string, mf *modfile.File, df *deptfile.File, paths []string, required map[string]string) ([]*Tool, error) {
+	imported := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		r := lookupToolModule(mf, p)
+		if r == nil {
+			return nil, errors.Errorf("the module of %s is not required in %s", p, fname)
+		}
+		if v, ok := required[r.Mod.Path]; ok && v != r.Mod.Version {
+			return nil, errors.Errorf("%s is required at %s, but %s requires %s. Please run 'dept get %s@%s' to change it", r.Mod.Path, v, fname, r.Mod.Version, r.Mod.Path, r.Mod.Version)
+		}
+		if err := df.AddTool(r.Mod.Path, strings.TrimPrefix(p, r.Mod.Path), ""); err != nil {
+			return nil, errors.Wrapf(err, "failed to import %s", p)
+		}
+		if err := df.SetVersion(r.Mod.Path, r.Mod.Version); err != nil {
+			return nil, err
+		}
+		if err := importReplace(projRoot, fname, df, mf, r.Mod.Path, r.Mod.Version); err != nil {
+			return nil, err
+		}
+		imported[p] = true
+	}
+
+	tools := make([]*Tool, 0, len(imported))
+	for _, r := range df.Require {
+		forToolsWithOutputName(r, func(path, out string) bool {
+			if imported[path] {
+				tools = appendTool(tools, df, r, path, out)
+			}
+			return true
+		})
+	}
+	return tools, nil
+}
+
+// importIndirects adds requirements of the go.mod gomod which are in the module graph of tools
+// and which go.mod in dir doesn't have as indirect ones, then merges go.sum which is next to gomod into go.sum in dir.
+// Versions of requirements which go.mod in dir already has are kept.
+func importIndirects(ctx context.Context, gocmd gocmd.Command, dir, gomod string, tools []*Tool) error {
+	src, err := readGoMod(gomod)
+	if err != nil {
+		return err
+	}
+	var roots []string
+	for _, t := range tools {
+		if r := lookupToolModule(src, t.Path); r != nil {
+			roots = append(roots, r.Mod.String())
+		}
+	}
+	if len(roots) == 0 {
+		return nil
+	}
+	out, err := gocmd.ModGraph(ctx, filepath.Dir(gomod))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the module graph of %s", gomod)
+	}
+	deps, err := reachableModules(out, roots)
+	if err != nil {
+		return err
+	}
+
+	fname := filepath.Join(dir, "go.mod")
+	dst, err := readGoMod(fname)
+	if err != nil {
+		return err
+	}
+	inDst := make(map[string]bool, len(dst.Require))
+	for _, r := range dst.Require {
+		inDst[r.Mod.Path] = true
+	}
+	for _, r := range src.Require {
+		if !deps[r.Mod.Path] {
+			continue
+		}
+		if !inDst[r.Mod.Path] {
+			dst.AddNewRequire(r.Mod.Path, r.Mod.Version, true)
+		}
+	}
+	dst.Cleanup()
+	b, err := dst.Format()
+	if err != nil {
+		return errors.Wrapf(err, "failed to format %s", fname)
+	}
+	if err := ioutil.WriteFile(fname, b, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", fname)
+	}
+	return mergeSum(filepath.Join(dir, "go.sum"), filepath.Join(filepath.Dir(gomod), "go.sum"))
+}
+
+// reachableModules parses the result of 'go mod graph', then returns paths of modules
+// which roots depend on directly or indirectly. Each root is formed as 'path@version'.
+func reachableModules(r io.Reader, roots []string) (map[string]bool, error) {
+	edges := map[string][]string{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		sp := strings.Fields(s.Text())
+		if len(sp) != 2 {
+			continue
+		}
+		edges[sp[0]] = append(edges[sp[0]], sp[1])
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read the module graph")
+	}
+
+	visited := map[string]bool{}
+	queue := append([]string(nil), roots...)
+	for _, n := range roots {
+		visited[n] = true
+	}
+	mods := map[string]bool{}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[n] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+			if i := strings.Index(next, "@"); i != -1 {
+				mods[next[:i]] = true
+			}
+		}
+	}
+	return mods, nil
+}
+
+// readGoMod reads and parses the go.mod fname.
+func readGoMod(fname string) (*modfile.File, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", fname)
+	}
+	mf, err := modfile.Parse(fname, b, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", fname)
+	}
+	return mf, nil
+}
+
+// lookupToolModule returns the requirement of mf which the tool belongs to.
+// If some modules have the tool path as the prefix, the longest one is returned.
+func lookupToolModule(mf *modfile.File, toolPath string) *modfile.Require {
+	var found *modfile.Require
+	for _, r := range mf.Require {
+		if toolPath != r.Mod.Path && !strings.HasPrefix(toolPath, r.Mod.Path+"/") {
+			continue
+		}
+		if found == nil || len(r.Mod.Path) > len(found.Mod.Path) {
+			found = r
+		}
+	}
+	return found
+}
+
+// importReplace copies replace directives of modPath@version in mf to df.
+// Relative local dirs are rebased on projRoot which has gotool.mod.
+func importReplace(projRoot, fname string, df *deptfile.File, mf *modfile.File, modPath, version string) error {
+	for _, r := range mf.Replace {
+		if r.Old.Path != modPath || (r.Old.Version != "" && r.Old.Version != version) {
+			continue
+		}
+		newPath := r.New.Path
+		if modfile.IsDirectoryPath(newPath) && !filepath.IsAbs(newPath) {
+			dir, err := filepath.Abs(filepath.Join(filepath.Dir(fname), newPath))
+			if err != nil {
+				return errors.Wrapf(err, "failed to get the abs path of %s", newPath)
+			}
+			rel, err := filepath.Rel(projRoot, dir)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get the relative path of %s", dir)
+			}
+			newPath = filepath.ToSlash(rel)
+			if !modfile.IsDirectoryPath(newPath) {
+				newPath = "./" + newPath
+			}
+		}
+		df.AddReplace(r.Old.Path, r.Old.Version, newPath, r.New.Version)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/toolcacher"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotFound is returned if gotool.mod is not found.
+	ErrNotFound = deptfile.ErrNotFound
+	// ErrAlreadyExist is returned by Init if gotool.mod already exists.
+	ErrAlreadyExist = deptfile.ErrAlreadyExist
+	// ErrNoJournal is returned by Undo if there are no journal entries.
+	ErrNoJournal = deptfile.ErrNoJournal
+	// ErrNoTargets is returned by Get if no tools are passed without Update option.
+	ErrNoTargets = errors.New("no tools passed")
+	// ErrNotTidy is returned by Tidy with Check option if gotool.mod is not tidy.
+	ErrNotTidy = errors.Errorf("%s is not tidy", deptfile.FileName)
+	// ErrUnknownFormat is returned by Export and Import if the passed format is not supported.
+	ErrUnknownFormat = errors.New("unknown format")
+)
+
+// ToolNotFoundErr represents the passed tool is not managed by gotool.mod.
+type ToolNotFoundErr struct {
+	// Name is the tool path or the output name which is passed.
+	Name string
+}
+
+func (e *ToolNotFoundErr) Error() string {
+	return fmt.Sprintf("%s not found in %s", e.Name, deptfile.FileName)
+}
+
+// VersionChangedErr represents removing tools changes versions of remaining requirements.
+type VersionChangedErr struct {
+	// Changes are version changes of remaining requirements.
+	Changes []*deptfile.Change
+}
+
+func (e *VersionChangedErr) Error() string {
+	s := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		s = append(s, fmt.Sprintf("%s %s => %s", c.Path, c.OldVersion, c.NewVersion))
+	}
+	return fmt.Sprintf("'go mod tidy' changes versions of remaining requirements: %s", strings.Join(s, ", "))
+}
+
+// Manager manages Go tools which are listed in gotool.mod.
+// The zero value is ready to use.
+type Manager struct {
+	// Dir is the project dir which has gotool.mod.
+	// If Dir is empty, the nearest dir which has gotool.mod from the current dir is used.
+	// It can be overridden by $DEPT_FILE. See deptfile.FindDir for details.
+	Dir string
+	// Stdin, Stdout and Stderr are passed to the tool executed by Exec.
+	// Stderr also receives messages while waiting for the lock of gotool.mod.
+	// If they are nil, os.Stdin, os.Stdout and os.Stderr are used.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// LockTimeout is the max duration to wait for the lock of gotool.mod.
+	// If LockTimeout is zero, deptfile.DefaultLockTimeout is used.
+	LockTimeout time.Duration
+
+	// GoCommand runs Go commands. If GoCommand is nil, gocmd.New is used.
+	GoCommand gocmd.Command
+	// ToolCacher caches built tools. If ToolCacher is nil, toolcacher.New is used at first use.
+	ToolCacher toolcacher.Cacher
+	// Workspace overrides workspaces which are created by each method.
+	// If it is a *deptfile.Workspace, DoNotUpdate and Local are set by each method,
+	// and its unset fields are filled by Dir, LockTimeout, Stderr and GoCommand.
+	// It is mainly used for testing.
+	Workspace deptfile.Workspacer
+
+	cacherOnce sync.Once
+	cacher     toolcacher.Cacher
+	cacherErr  error
+
+	// parent is the manager which creates m by Projects.
+	// The tool cacher of parent is shared with m.
+	parent *Manager
+}
+
+// Init creates a new gotool.mod.
+// Unlike other methods, if Dir is empty, Init creates it in the current dir, or the dir of $DEPT_FILE if it is set.
+// If gotool.mod already exists, Init returns ErrAlreadyExist.
+func (m *Manager) Init(ctx context.Context) error {
+	return deptfile.Create(ctx, m.GoCommand, m.Dir)
+}
+
+// Clean removes all cached tools.
+func (m *Manager) Clean(ctx context.Context) error {
+	c, err := m.toolCacher()
+	if err != nil {
+		return err
+	}
+	return c.Clear(ctx)
+}
+
+// History returns journal entries newest first.
+func (m *Manager) History(ctx context.Context) ([]*deptfile.JournalEntry, error) {
+	var entries []*deptfile.JournalEntry
+	err := m.workspace(true).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		var err error
+		entries, err = deptfile.ReadJournal(projRoot)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Undo restores gotool.mod and gotool.sum to the journal entry which has id.
+// If id is 0, the latest entry is restored. If there are no entries, Undo returns ErrNoJournal.
+// Undo uses DryRun and OnChange options.
+func (m *Manager) Undo(ctx context.Context, id int, opts ...UndoOption) error {
+	o := newOptions(opts)
+	wopts := append(o.workspaceOptions(""), deptfile.Undo(id))
+	return m.workspace(false).Do(func(string, string, *deptfile.File) error {
+		return nil
+	}, wopts...)
+}
+
+// workspace returns a workspace for a method.
+// If readOnly is true, the workspace doesn't update gotool.mod.
+func (m *Manager) workspace(readOnly bool) deptfile.Workspacer {
+	return m.newWorkspace(readOnly, false)
+}
+
+// localWorkspace returns the workspace which merges gotool.local.mod on top of gotool.mod.
+// If readOnly is false, the workspace updates gotool.local.mod instead of gotool.mod.
+func (m *Manager) localWorkspace(readOnly bool) deptfile.Workspacer {
+	return m.newWorkspace(readOnly, true)
+}
+
+// newWorkspace returns a workspace for a method.
+// If Workspace is a *deptfile.Workspace, its copy is configured by readOnly, local and fields of m.
+// Other Workspacers are returned as it is.
+func (m *Manager) newWorkspace(readOnly, local bool) deptfile.Workspacer {
+	if w, ok := m.Workspace.(*deptfile.Workspace); ok {
+		cp := *w
+		if cp.SourcePath == "" {
+			cp.SourcePath = m.Dir
+		}
+		if cp.LockTimeout == 0 {
+			cp.LockTimeout = m.LockTimeout
+		}
+		if cp.Stderr == nil {
+			cp.Stderr = m.Stderr
+		}
+		if cp.GoCommand == nil {
+			cp.GoCommand = m.GoCommand
+		}
+		cp.DoNotUpdate = readOnly
+		cp.Local = local
+		return &cp
+	}
+	if m.Workspace != nil {
+		return m.Workspace
+	}
+	return &deptfile.Workspace{
+		SourcePath:  m.Dir,
+		DoNotUpdate: readOnly,
+		LockTimeout: m.LockTimeout,
+		Local:       local,
+		Stderr:      m.stderr(),
+		GoCommand:   m.GoCommand,
+	}
+}
+
+func (m *Manager) gocmd() gocmd.Command {
+	if m.GoCommand != nil {
+		return m.GoCommand
+	}
+	return gocmd.New()
+}
+
+func (m *Manager) toolCacher() (toolcacher.Cacher, error) {
+	if m.ToolCacher != nil {
+		return m.ToolCacher, nil
+	}
+	if m.parent != nil {
+		return m.parent.toolCacher()
+	}
+	m.cacherOnce.Do(func() {
+		m.cacher, m.cacherErr = toolcacher.New(m.gocmd())
+		if m.cacherErr != nil {
+			m.cacherErr = errors.Wrap(m.cacherErr, "failed to instantiate toolcacher")
+		}
+	})
+	return m.cacher, m.cacherErr
+}
+
+func (m *Manager) stdin() io.Reader {
+	if m.Stdin != nil {
+		return m.Stdin
+	}
+	return os.Stdin
+}
+
+func (m *Manager) stdout() io.Writer {
+	if m.Stdout != nil {
+		return m.Stdout
+	}
+	return os.Stdout
+}
+
+func (m *Manager) stderr() io.Writer {
+	if m.Stderr != nil {
+		return m.Stderr
+	}
+	return os.Stderr
+}
@@ -0,0 +1,630 @@
+package manager_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-multierror"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/manager"
+	"github.com/ktr0731/dept/toolcacher"
+	"github.com/pkg/errors"
+)
+
+func newWorkspace() *deptfile.WorkspacerMock {
+	return &deptfile.WorkspacerMock{
+		DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+			return f("", "", &deptfile.File{
+				Require: []*deptfile.Require{
+					{Path: "github.com/ktr0731/evans", Version: "v0.1.0", ToolPaths: []*deptfile.Tool{{Path: "/", Name: "ev"}}},
+					{Path: "honnef.co/go/tools", Version: "v0.2.0", ToolPaths: []*deptfile.Tool{{Path: "/cmd/staticcheck"}, {Path: "/cmd/unused"}}},
+				},
+			})
+		},
+	}
+}
+
+func TestList(t *testing.T) {
+	cases := map[string]struct {
+		paths    []string
+		expected []*manager.Tool
+	}{
+		"all tools": {
+			expected: []*manager.Tool{
+				{Path: "github.com/ktr0731/evans", Name: "ev", Version: "v0.1.0"},
+				{Path: "honnef.co/go/tools/cmd/staticcheck", Name: "staticcheck", Version: "v0.2.0"},
+				{Path: "honnef.co/go/tools/cmd/unused", Name: "unused", Version: "v0.2.0"},
+			},
+		},
+		"a module": {
+			paths: []string{"honnef.co/go/tools"},
+			expected: []*manager.Tool{
+				{Path: "honnef.co/go/tools/cmd/staticcheck", Name: "staticcheck", Version: "v0.2.0"},
+				{Path: "honnef.co/go/tools/cmd/unused", Name: "unused", Version: "v0.2.0"},
+			},
+		},
+		"a tool": {
+			paths: []string{"honnef.co/go/tools/cmd/unused"},
+			expected: []*manager.Tool{
+				{Path: "honnef.co/go/tools/cmd/unused", Name: "unused", Version: "v0.2.0"},
+			},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m := &manager.Manager{Workspace: newWorkspace()}
+			tools, err := m.List(context.Background(), c.paths...)
+			if err != nil {
+				t.Fatalf("List must not return errors, but got '%s'", err)
+			}
+			if diff := cmp.Diff(c.expected, tools); diff != "" {
+				t.Errorf("listed tools are wrong:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	mockToolCacher := &toolcacher.CacherMock{
+		GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
+			return filepath.Join("cache", pkgName+"-"+version), nil
+		},
+	}
+	m := &manager.Manager{Workspace: newWorkspace(), ToolCacher: mockToolCacher}
+
+	t.Run("Resolve returns the cached path", func(t *testing.T) {
+		p, err := m.Resolve(context.Background(), "ev")
+		if err != nil {
+			t.Fatalf("Resolve must not return errors, but got '%s'", err)
+		}
+		if expected := filepath.Join("cache", "github.com/ktr0731/evans-v0.1.0"); p != expected {
+			t.Errorf("expected path is %s, but got %s", expected, p)
+		}
+	})
+
+	t.Run("Resolve returns ToolNotFoundErr", func(t *testing.T) {
+		// 'evans' is renamed to 'ev'.
+		_, err := m.Resolve(context.Background(), "evans")
+		if _, ok := errors.Cause(err).(*manager.ToolNotFoundErr); !ok {
+			t.Errorf("Resolve must return *ToolNotFoundErr, but got '%v'", err)
+		}
+	})
+}
+
+func TestReplacedTool(t *testing.T) {
+	workspace := &deptfile.WorkspacerMock{
+		DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+			return f("", "", &deptfile.File{
+				Require: []*deptfile.Require{
+					{Path: "github.com/ktr0731/evans", Version: "v0.1.0", ToolPaths: []*deptfile.Tool{{Path: "/", Name: "ev"}}},
+					{Path: "honnef.co/go/tools", Version: "v0.2.0", ToolPaths: []*deptfile.Tool{{Path: "/cmd/staticcheck"}}},
+				},
+				Replace: []*deptfile.Replace{
+					{Path: "github.com/ktr0731/evans", Version: "v0.1.0", NewPath: "github.com/foo/evans", NewVersion: "v0.1.1"},
+					{Path: "honnef.co/go/tools", NewPath: "/path/to/tools"},
+				},
+			})
+		},
+	}
+	mockToolCacher := &toolcacher.CacherMock{
+		GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
+			return filepath.Join("cache", pkgName+"-"+version), nil
+		},
+	}
+	m := &manager.Manager{Workspace: workspace, ToolCacher: mockToolCacher}
+
+	tools, err := m.List(context.Background())
+	if err != nil {
+		t.Fatalf("List must not return errors, but got '%s'", err)
+	}
+	expected := []*manager.Tool{
+		{Path: "github.com/ktr0731/evans", Name: "ev", Version: "v0.1.0", Replace: "github.com/foo/evans v0.1.1"},
+		{Path: "honnef.co/go/tools/cmd/staticcheck", Name: "staticcheck", Version: "v0.2.0", Replace: "/path/to/tools"},
+	}
+	if diff := cmp.Diff(expected, tools); diff != "" {
+		t.Errorf("listed tools are wrong:\n%s", diff)
+	}
+
+	p, err := m.Resolve(context.Background(), "ev")
+	if err != nil {
+		t.Fatalf("Resolve must not return errors, but got '%s'", err)
+	}
+	if upstream := filepath.Join("cache", "github.com/ktr0731/evans-v0.1.0"); !strings.HasPrefix(p, upstream+"-replaced-") {
+		t.Errorf("the cache key of the replaced tool must be different from the upstream one, but got %s", p)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	outputDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	mockToolCacher := &toolcacher.CacherMock{
+		GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
+			p := filepath.Join(cacheDir, filepath.Base(pkgName))
+			return p, ioutil.WriteFile(p, []byte(pkgName), 0755)
+		},
+	}
+	m := &manager.Manager{Workspace: newWorkspace(), ToolCacher: mockToolCacher}
+
+	binPaths, err := m.Build(context.Background(), manager.OutputDir(outputDir))
+	if err != nil {
+		t.Fatalf("Build must not return errors, but got '%s'", err)
+	}
+	expected := map[string]string{
+		"ev":          filepath.Join(outputDir, "ev"),
+		"staticcheck": filepath.Join(outputDir, "staticcheck"),
+		"unused":      filepath.Join(outputDir, "unused"),
+	}
+	if diff := cmp.Diff(expected, binPaths); diff != "" {
+		t.Errorf("returned paths are wrong:\n%s", diff)
+	}
+	for _, p := range binPaths {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s must be copied, but got an error: %s", p, err)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "ev")
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil {
+		t.Fatalf("failed to write a pseudo tool: %s", err)
+	}
+
+	mockToolCacher := &toolcacher.CacherMock{
+		GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
+			return bin, nil
+		},
+	}
+	var out bytes.Buffer
+	m := &manager.Manager{Workspace: newWorkspace(), ToolCacher: mockToolCacher, Stdout: &out}
+
+	if err := m.Exec(context.Background(), "ev", []string{"foo", "bar"}); err != nil {
+		t.Fatalf("Exec must not return errors, but got '%s'", err)
+	}
+	if actual := out.String(); actual != "foo bar\n" {
+		t.Errorf("the tool must be executed with args, but got '%s'", actual)
+	}
+}
+
+func TestGet(t *testing.T) {
+	t.Run("Get returns ErrNoTargets", func(t *testing.T) {
+		m := &manager.Manager{Workspace: newWorkspace()}
+		err := m.Get(context.Background(), nil)
+		if errors.Cause(err) != manager.ErrNoTargets {
+			t.Errorf("Get must return ErrNoTargets, but got '%v'", err)
+		}
+	})
+
+	t.Run("Get replaces the module by the passed local dir", func(t *testing.T) {
+		projRoot, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(projRoot)
+		modDir := filepath.Join(projRoot, "tools")
+		writeFile(t, filepath.Join(projRoot, deptfile.FileName), "module tools\n")
+		writeFile(t, filepath.Join(modDir, "go.mod"), "module example.com/tools\n")
+		writeFile(t, filepath.Join(modDir, "cmd", "gen", "main.go"), "package main\n\nfunc main() {}\n")
+
+		var built []string
+		mockGoCMD := &gocmd.CommandMock{
+			GetFunc: func(ctx context.Context, dir string, args ...string) error {
+				b, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+				if err != nil {
+					t.Fatalf("failed to read go.mod: %s", err)
+				}
+				if e := "replace example.com/tools => " + modDir; !strings.Contains(string(b), e) {
+					t.Errorf("go.mod must contain '%s', but got:\n%s", e, string(b))
+				}
+				for _, a := range args {
+					if strings.HasPrefix(a, "example.com/tools") {
+						t.Errorf("the local tool must not be passed to 'go get', but got %v", args)
+					}
+				}
+				return nil
+			},
+			BuildFunc: func(ctx context.Context, dir string, args ...string) error {
+				built = append(built, args[len(args)-1])
+				return nil
+			},
+		}
+		m := &manager.Manager{Workspace: &deptfile.Workspace{SourcePath: projRoot}, GoCommand: mockGoCMD}
+		err = m.Get(context.Background(), []*manager.Target{{Path: filepath.Join(modDir, "cmd", "gen")}}, manager.OutputDir(projRoot))
+		if err != nil {
+			t.Fatalf("Get must not return errors, but got '%s'", err)
+		}
+		if diff := cmp.Diff([]string{"example.com/tools/cmd/gen"}, built); diff != "" {
+			t.Errorf("built tools are wrong:\n%s", diff)
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(projRoot, deptfile.FileName))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", deptfile.FileName, err)
+		}
+		for _, e := range []string{
+			"require example.com/tools:/cmd/gen v0.0.0-00010101000000-000000000000",
+			"replace example.com/tools => ./tools",
+		} {
+			if !strings.Contains(string(b), e) {
+				t.Errorf("%s must contain '%s', but got:\n%s", deptfile.FileName, e, string(b))
+			}
+		}
+	})
+}
+
+func TestLocalToolCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {}\n")
+
+	var versions []string
+	mockToolCacher := &toolcacher.CacherMock{
+		SourceVersionFunc: func(ctx context.Context, pkgName, version, srcDir string) (string, error) {
+			if srcDir != dir {
+				t.Errorf("SourceVersion must be called with the replaced dir %s, but got %s", dir, srcDir)
+			}
+			return version + "-src-0123456789ab", nil
+		},
+		GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
+			versions = append(versions, version)
+			return filepath.Join("cache", pkgName+"-"+version), nil
+		},
+	}
+	workspace := &deptfile.WorkspacerMock{
+		DoFunc: func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+			return f("", "", &deptfile.File{
+				Require: []*deptfile.Require{
+					{Path: "example.com/gen", Version: "v0.0.0-00010101000000-000000000000", ToolPaths: []*deptfile.Tool{{Path: "/"}}},
+				},
+				Replace: []*deptfile.Replace{{Path: "example.com/gen", NewPath: dir}},
+			})
+		},
+	}
+	m := &manager.Manager{Workspace: workspace, ToolCacher: mockToolCacher}
+
+	if _, err := m.Resolve(context.Background(), "gen"); err != nil {
+		t.Fatalf("Resolve must not return errors, but got '%s'", err)
+	}
+	expected := []string{"v0.0.0-00010101000000-000000000000-src-0123456789ab"}
+	if diff := cmp.Diff(expected, versions); diff != "" {
+		t.Errorf("the cache key must be the source version:\n%s", diff)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatalf("failed to create %s: %s", filepath.Dir(name), err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", name, err)
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	const out = `{"Path": "github.com/ktr0731/evans", "Version": "v0.1.0", "Update": {"Path": "github.com/ktr0731/evans", "Version": "v0.2.0"}}
+{"Path": "honnef.co/go/tools", "Version": "v0.2.0"}
+`
+	var args []string
+	mockGoCMD := &gocmd.CommandMock{
+		ListFunc: func(ctx context.Context, dir string, a ...string) (io.Reader, error) {
+			args = a
+			return strings.NewReader(out), nil
+		},
+	}
+	m := &manager.Manager{Workspace: newWorkspace(), GoCommand: mockGoCMD}
+	mods, err := m.Outdated(context.Background())
+	if err != nil {
+		t.Fatalf("Outdated must not return errors, but got '%s'", err)
+	}
+	expectedArgs := []string{"-m", "-u", "-json", "github.com/ktr0731/evans", "honnef.co/go/tools"}
+	if diff := cmp.Diff(expectedArgs, args); diff != "" {
+		t.Errorf("'go list' args are wrong:\n%s", diff)
+	}
+	expected := []*manager.OutdatedModule{
+		{Path: "github.com/ktr0731/evans", Version: "v0.1.0", Latest: "v0.2.0", Tools: []string{"ev"}},
+	}
+	if diff := cmp.Diff(expected, mods); diff != "" {
+		t.Errorf("outdated modules are wrong:\n%s", diff)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	root, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+	writeFile(t, filepath.Join(root, "a", deptfile.FileName), "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n")
+	writeFile(t, filepath.Join(root, "b", "c", deptfile.FileName), "module tools\n\nrequire github.com/ktr0731/evans@ev v0.2.0\n")
+
+	m := &manager.Manager{Dir: root}
+	projects, err := m.Projects(context.Background())
+	if err != nil {
+		t.Fatalf("Projects must not return errors, but got '%s'", err)
+	}
+	expected := map[string][]*manager.Tool{
+		filepath.Join("a", deptfile.FileName):      {{Path: "github.com/ktr0731/evans", Name: "evans", Version: "v0.1.0"}},
+		filepath.Join("b", "c", deptfile.FileName): {{Path: "github.com/ktr0731/evans", Name: "ev", Version: "v0.2.0"}},
+	}
+	if len(projects) != len(expected) {
+		t.Fatalf("Projects must return %d projects, but got %d", len(expected), len(projects))
+	}
+	for _, p := range projects {
+		tools, err := p.Manager.List(context.Background())
+		if err != nil {
+			t.Fatalf("List must not return errors, but got '%s'", err)
+		}
+		if diff := cmp.Diff(expected[p.File], tools); diff != "" {
+			t.Errorf("listed tools of %s are wrong:\n%s", p.File, diff)
+		}
+	}
+}
+
+func TestInjectedWorkspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, deptfile.FileName), "module tools\n\nrequire github.com/ktr0731/evans v0.1.0\n")
+
+	var buf bytes.Buffer
+	m := &manager.Manager{
+		Stderr:      &buf,
+		LockTimeout: 200 * time.Millisecond,
+		Workspace:   &deptfile.Workspace{SourcePath: dir},
+	}
+	outer := &deptfile.Workspace{SourcePath: dir, DoNotCopy: true}
+	err = outer.Do(func(string, string, *deptfile.File) error {
+		_, err := m.List(context.Background())
+		return err
+	})
+	if errors.Cause(err) != deptfile.ErrLockTimeout {
+		t.Fatalf("List must return ErrLockTimeout by LockTimeout of the manager, but got '%v'", err)
+	}
+	if !strings.Contains(buf.String(), "waiting for lock") {
+		t.Errorf("the message while waiting for the lock must be written to Stderr of the manager, but got '%s'", buf.String())
+	}
+}
+
+func TestRemove(t *testing.T) {
+	t.Run("Remove returns ToolNotFoundErr for each unmanaged tool", func(t *testing.T) {
+		m := &manager.Manager{Workspace: newWorkspace()}
+		_, err := m.Remove(context.Background(), []string{"github.com/foo/bar", "github.com/ktr0731/evans", "github.com/foo/baz"})
+		merr, ok := errors.Cause(err).(*multierror.Error)
+		if !ok {
+			t.Fatalf("Remove must return *multierror.Error, but got '%v'", err)
+		}
+		var names []string
+		for _, err := range merr.Errors {
+			e, ok := err.(*manager.ToolNotFoundErr)
+			if !ok {
+				t.Fatalf("each error must be *ToolNotFoundErr, but got %T", err)
+			}
+			names = append(names, e.Name)
+		}
+		if len(names) != 2 {
+			t.Errorf("Remove must return 2 errors, but got %v", names)
+		}
+	})
+	t.Run("Remove removes tools by paths or names, then deletes binaries", func(t *testing.T) {
+		cases := map[string]struct {
+			targets  []string
+			module   bool
+			required []string
+			foreign  []string
+			bins     []string
+			caches   []string
+		}{
+			"import path": {
+				targets:  []string{"honnef.co/go/tools/cmd/unused"},
+				required: []string{"github.com/ktr0731/evans", "honnef.co/go/tools/cmd/staticcheck"},
+				bins:     []string{"unused"},
+				caches:   []string{"honnef.co/go/tools/cmd/unused-v0.1.0", "honnef.co/go/tools/cmd/unused-v0.2.0"},
+			},
+			"output name": {
+				targets:  []string{"ev"},
+				required: []string{"honnef.co/go/tools/cmd/staticcheck", "honnef.co/go/tools/cmd/unused"},
+				bins:     []string{"ev"},
+				caches:   []string{"github.com/ktr0731/evans-v0.1.0", "github.com/ktr0731/evans-v0.2.0"},
+			},
+			"binary which dept didn't install": {
+				targets:  []string{"honnef.co/go/tools/cmd/unused"},
+				required: []string{"github.com/ktr0731/evans", "honnef.co/go/tools/cmd/staticcheck"},
+				foreign:  []string{"unused"},
+				caches:   []string{"honnef.co/go/tools/cmd/unused-v0.1.0", "honnef.co/go/tools/cmd/unused-v0.2.0"},
+			},
+			"whole module by a tool name": {
+				targets:  []string{"staticcheck"},
+				module:   true,
+				required: []string{"github.com/ktr0731/evans"},
+				bins:     []string{"staticcheck", "unused"},
+				caches:   []string{"honnef.co/go/tools/cmd/staticcheck-v0.1.0", "honnef.co/go/tools/cmd/staticcheck-v0.2.0", "honnef.co/go/tools/cmd/unused-v0.1.0", "honnef.co/go/tools/cmd/unused-v0.2.0"},
+			},
+			"whole module by the module path": {
+				targets:  []string{"honnef.co/go/tools"},
+				module:   true,
+				required: []string{"github.com/ktr0731/evans"},
+				bins:     []string{"staticcheck", "unused"},
+				caches:   []string{"honnef.co/go/tools/cmd/staticcheck-v0.1.0", "honnef.co/go/tools/cmd/staticcheck-v0.2.0", "honnef.co/go/tools/cmd/unused-v0.1.0", "honnef.co/go/tools/cmd/unused-v0.2.0"},
+			},
+		}
+		for name, c := range cases {
+			c := c
+			t.Run(name, func(t *testing.T) {
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatalf("failed to create a temp dir: %s", err)
+				}
+				defer os.RemoveAll(dir)
+				cachePath := filepath.Join(dir, "cache")
+				if err := ioutil.WriteFile(cachePath, []byte("bin"), 0755); err != nil {
+					t.Fatalf("failed to create a dummy cache: %s", err)
+				}
+				foreign := map[string]bool{}
+				for _, name := range c.foreign {
+					foreign[name] = true
+				}
+				for _, name := range []string{"ev", "staticcheck", "unused"} {
+					content := []byte("bin")
+					if foreign[name] {
+						content = []byte("foreign")
+					}
+					if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0755); err != nil {
+						t.Fatalf("failed to create a dummy binary: %s", err)
+					}
+				}
+
+				var required []string
+				mockGoCMD := &gocmd.CommandMock{
+					ModTidyFunc: func(ctx context.Context, dir string) error {
+						b, err := ioutil.ReadFile(filepath.Join(dir, filegen.FileName))
+						if err != nil {
+							t.Fatalf("failed to read the generated file: %s", err)
+						}
+						for _, l := range strings.Split(string(b), "\n") {
+							if i := strings.Index(l, `"`); i != -1 {
+								required = append(required, strings.Trim(l[i:], `"`))
+							}
+						}
+						return nil
+					},
+					ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+						return strings.NewReader("tools\n"), nil
+					},
+				}
+				mockToolCacher := &toolcacher.CacherMock{
+					ListFunc: func(ctx context.Context, pkgName string) ([]string, error) {
+						return []string{cachePath}, nil
+					},
+					RemoveAllFunc: func(ctx context.Context, pkgName string) ([]string, error) {
+						return []string{pkgName + "-v0.1.0", pkgName + "-v0.2.0"}, nil
+					},
+				}
+				workspace := newWorkspace()
+				do := workspace.DoFunc
+				workspace.DoFunc = func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+					return do(func(projectDir, _ string, gomod *deptfile.File) error {
+						return f(projectDir, dir, gomod)
+					}, opts...)
+				}
+				m := &manager.Manager{
+					GoCommand:  mockGoCMD,
+					ToolCacher: mockToolCacher,
+					Workspace:  workspace,
+				}
+				opts := []manager.RemoveOption{manager.OutputDir(dir)}
+				if c.module {
+					opts = append(opts, manager.Module())
+				}
+				deleted, err := m.Remove(context.Background(), c.targets, opts...)
+				if err != nil {
+					t.Fatalf("Remove must not return errors, but got '%s'", err)
+				}
+				if diff := cmp.Diff(c.required, required); diff != "" {
+					t.Errorf("remaining tools are wrong:\n%s", diff)
+				}
+				var expected []string
+				for _, b := range c.bins {
+					expected = append(expected, filepath.Join(dir, b))
+				}
+				expected = append(expected, c.caches...)
+				if diff := cmp.Diff(expected, deleted); diff != "" {
+					t.Errorf("deleted binaries are wrong:\n%s", diff)
+				}
+				for _, name := range c.foreign {
+					if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+						t.Errorf("the binary which dept didn't install must survive: %s", err)
+					}
+				}
+			})
+		}
+	})
+	t.Run("Remove returns VersionChangedErr if selected versions of remaining modules are changed", func(t *testing.T) {
+		for name, fullTidy := range map[string]bool{"default": false, "full tidy": true} {
+			fullTidy := fullTidy
+			t.Run(name, func(t *testing.T) {
+				dir, err := ioutil.TempDir("", "")
+				if err != nil {
+					t.Fatalf("failed to create a temp dir: %s", err)
+				}
+				defer os.RemoveAll(dir)
+				var tidied bool
+				mockGoCMD := &gocmd.CommandMock{
+					ModTidyFunc: func(ctx context.Context, dir string) error {
+						// evans and the indirect requirement of x/text v0.3.2 are dropped.
+						tidied = true
+						return nil
+					},
+					ListFunc: func(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+						if !tidied {
+							return strings.NewReader("tools\ngithub.com/ktr0731/evans v0.1.0\ngolang.org/x/text v0.3.2\nhonnef.co/go/tools v0.2.0\n"), nil
+						}
+						// x/text is still required by honnef.co/go/tools, but falls back to v0.3.0.
+						return strings.NewReader("tools\ngolang.org/x/text v0.3.0\nhonnef.co/go/tools v0.2.0\n"), nil
+					},
+				}
+				workspace := newWorkspace()
+				do := workspace.DoFunc
+				workspace.DoFunc = func(f func(projectDir, workDir string, gomod *deptfile.File) error, opts ...deptfile.Option) error {
+					return do(func(projectDir, _ string, gomod *deptfile.File) error {
+						return f(projectDir, dir, gomod)
+					}, opts...)
+				}
+				m := &manager.Manager{
+					GoCommand:  mockGoCMD,
+					ToolCacher: &toolcacher.CacherMock{},
+					Workspace:  workspace,
+				}
+				opts := []manager.RemoveOption{manager.OutputDir(dir), manager.DryRun()}
+				if fullTidy {
+					opts = append(opts, manager.FullTidy())
+				}
+				_, err = m.Remove(context.Background(), []string{"ev"}, opts...)
+				if fullTidy {
+					if err != nil {
+						t.Errorf("Remove must not return errors, but got '%s'", err)
+					}
+					return
+				}
+				verr, ok := errors.Cause(err).(*manager.VersionChangedErr)
+				if !ok {
+					t.Fatalf("Remove must return *VersionChangedErr, but got '%v'", err)
+				}
+				expected := []*deptfile.Change{{Path: "golang.org/x/text", OldVersion: "v0.3.2", NewVersion: "v0.3.0"}}
+				if diff := cmp.Diff(expected, verr.Changes); diff != "" {
+					t.Errorf("changes are wrong:\n%s", diff)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package manager
+
+import "github.com/ktr0731/dept/deptfile"
+
+// options represents optional behaviors of methods.
+// Each method accepts only options which it uses by its own option interface like GetOption.
+type options struct {
+	// outputDir is the dir to store built tools.
+	// If outputDir is empty, $GOBIN is used. If $GOBIN is also empty, '_tools' in the project dir is used.
+	outputDir string
+	// update updates passed tools to the latest version.
+	// If no tools are passed to Get, all tools are updated.
+	update bool
+	// module makes Remove remove whole modules which passed tools belong to.
+	module bool
+	// fullTidy allows Remove to change versions of remaining requirements by 'go mod tidy'.
+	fullTidy bool
+	// check makes Tidy return ErrNotTidy instead of updating gotool.mod if it is not tidy.
+	check bool
+	// dryRun doesn't update gotool.mod, gotool.sum and tools.
+	dryRun bool
+	// onChange receives changes of gotool.mod.
+	onChange func(*deptfile.Changes)
+	// description describes the change for the journal. For example, 'get -u'.
+	description string
+	// write makes Export write the result into the project instead of returning it.
+	write bool
+	// local makes Get write tools to gotool.local.mod instead of gotool.mod.
+	local bool
+}
+
+// option sets a value to options.
+type option func(*options)
+
+func (f option) apply(o *options) {
+	f(o)
+}
+
+// applier is implemented by all options.
+type applier interface {
+	apply(*options)
+}
+
+func newOptions[T applier](opts []T) *options {
+	var o options
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+	return &o
+}
+
+// BuildOption is an option of Build.
+type BuildOption interface {
+	applier
+	buildOption()
+}
+
+// GetOption is an option of Get.
+type GetOption interface {
+	applier
+	getOption()
+}
+
+// RemoveOption is an option of Remove.
+type RemoveOption interface {
+	applier
+	removeOption()
+}
+
+// TidyOption is an option of Tidy.
+type TidyOption interface {
+	applier
+	tidyOption()
+}
+
+// EditOption is an option of Edit and Fmt.
+type EditOption interface {
+	applier
+	editOption()
+}
+
+// ImportOption is an option of Import.
+type ImportOption interface {
+	applier
+	importOption()
+}
+
+// ExportOption is an option of Export.
+type ExportOption interface {
+	applier
+	exportOption()
+}
+
+// RenameOption is an option of Rename.
+type RenameOption interface {
+	applier
+	renameOption()
+}
+
+// UndoOption is an option of Undo.
+type UndoOption interface {
+	applier
+	undoOption()
+}
+
+// OutputDirOption is an option of Build, Get, Remove, Export and Rename.
+type OutputDirOption struct{ option }
+
+func (OutputDirOption) buildOption()  {}
+func (OutputDirOption) getOption()    {}
+func (OutputDirOption) removeOption() {}
+func (OutputDirOption) exportOption() {}
+func (OutputDirOption) renameOption() {}
+
+// OutputDir sets the dir to store built tools.
+// If dir is empty, $GOBIN is used. If $GOBIN is also empty, '_tools' in the project dir is used.
+func OutputDir(dir string) OutputDirOption {
+	return OutputDirOption{func(o *options) {
+		o.outputDir = dir
+	}}
+}
+
+// UpdateOption is an option of Get.
+type UpdateOption struct{ option }
+
+func (UpdateOption) getOption() {}
+
+// Update makes Get update passed tools to the latest version.
+// If no tools are passed to Get, all tools are updated.
+func Update() UpdateOption {
+	return UpdateOption{func(o *options) {
+		o.update = true
+	}}
+}
+
+// LocalOption is an option of Get.
+type LocalOption struct{ option }
+
+func (LocalOption) getOption() {}
+
+// Local makes Get write tools to gotool.local.mod instead of gotool.mod.
+func Local() LocalOption {
+	return LocalOption{func(o *options) {
+		o.local = true
+	}}
+}
+
+// ModuleOption is an option of Remove.
+type ModuleOption struct{ option }
+
+func (ModuleOption) removeOption() {}
+
+// Module makes Remove remove whole modules which passed tools belong to.
+func Module() ModuleOption {
+	return ModuleOption{func(o *options) {
+		o.module = true
+	}}
+}
+
+// FullTidyOption is an option of Remove.
+type FullTidyOption struct{ option }
+
+func (FullTidyOption) removeOption() {}
+
+// FullTidy allows Remove to change versions of remaining requirements by 'go mod tidy'.
+func FullTidy() FullTidyOption {
+	return FullTidyOption{func(o *options) {
+		o.fullTidy = true
+	}}
+}
+
+// CheckOption is an option of Tidy.
+type CheckOption struct{ option }
+
+func (CheckOption) tidyOption() {}
+
+// Check makes Tidy return ErrNotTidy instead of updating gotool.mod if it is not tidy.
+func Check() CheckOption {
+	return CheckOption{func(o *options) {
+		o.check = true
+	}}
+}
+
+// WriteOption is an option of Export.
+type WriteOption struct{ option }
+
+func (WriteOption) exportOption() {}
+
+// Write makes Export write the result into the project instead of returning it.
+func Write() WriteOption {
+	return WriteOption{func(o *options) {
+		o.write = true
+	}}
+}
+
+// DryRunOption is an option of methods which update gotool.mod.
+type DryRunOption struct{ option }
+
+func (DryRunOption) getOption()    {}
+func (DryRunOption) removeOption() {}
+func (DryRunOption) tidyOption()   {}
+func (DryRunOption) editOption()   {}
+func (DryRunOption) importOption() {}
+func (DryRunOption) renameOption() {}
+func (DryRunOption) undoOption()   {}
+
+// DryRun doesn't update gotool.mod, gotool.sum and tools.
+func DryRun() DryRunOption {
+	return DryRunOption{func(o *options) {
+		o.dryRun = true
+	}}
+}
+
+// OnChangeOption is an option of Get, Remove, Tidy and Undo.
+type OnChangeOption struct{ option }
+
+func (OnChangeOption) getOption()    {}
+func (OnChangeOption) removeOption() {}
+func (OnChangeOption) tidyOption()   {}
+func (OnChangeOption) undoOption()   {}
+
+// OnChange sets f which receives changes of gotool.mod.
+func OnChange(f func(*deptfile.Changes)) OnChangeOption {
+	return OnChangeOption{func(o *options) {
+		o.onChange = f
+	}}
+}
+
+// DescriptionOption is an option of methods which record the journal.
+type DescriptionOption struct{ option }
+
+func (DescriptionOption) getOption()    {}
+func (DescriptionOption) removeOption() {}
+func (DescriptionOption) tidyOption()   {}
+func (DescriptionOption) editOption()   {}
+func (DescriptionOption) importOption() {}
+func (DescriptionOption) renameOption() {}
+
+// Description describes the change for the journal. For example, 'get -u'.
+func Description(desc string) DescriptionOption {
+	return DescriptionOption{func(o *options) {
+		o.description = desc
+	}}
+}
+
+// workspaceOptions converts o to options for deptfile.Workspacer.
+func (o *options) workspaceOptions(desc string) []deptfile.Option {
+	if o.description != "" {
+		desc = o.description
+	}
+	opts := []deptfile.Option{deptfile.Description(desc)}
+	if o.onChange != nil {
+		opts = append(opts, deptfile.OnChange(o.onChange))
+	}
+	if o.dryRun {
+		opts = append(opts, deptfile.DryRun())
+	}
+	return opts
+}
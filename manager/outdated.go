@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/pkg/errors"
+)
+
+// OutdatedModule represents a module of tools which has a newer version.
+type OutdatedModule struct {
+	// Path is the module path.
+	Path string
+	// Version is the version which is required by gotool.mod.
+	Version string
+	// Latest is the latest version of the module.
+	Latest string
+	// Tools are output names of tools which belong to the module.
+	Tools []string
+}
+
+// Outdated returns modules of tools which have newer versions.
+// Outdated runs 'go list -m -u' in the workspace, so gotool.mod is never updated.
+// Modules which are replaced by local dirs are skipped.
+func (m *Manager) Outdated(ctx context.Context) ([]*OutdatedModule, error) {
+	gocmd := m.gocmd()
+	var outdated []*OutdatedModule
+	err := m.workspace(true).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		args := []string{"-m", "-u", "-json"}
+		tools := map[string][]string{}
+		for _, r := range df.Require {
+			if rep := df.LookupReplace(r.Path, r.Version); rep != nil && rep.IsLocal() {
+				continue
+			}
+			args = append(args, r.Path)
+			forToolsWithOutputName(r, func(path, out string) bool {
+				tools[r.Path] = append(tools[r.Path], toolName(path, out))
+				return true
+			})
+		}
+		if len(tools) == 0 {
+			return nil
+		}
+
+		out, err := gocmd.List(ctx, workDir, args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to list updates of modules")
+		}
+		dec := json.NewDecoder(out)
+		for {
+			var mod struct {
+				Path    string
+				Version string
+				Update  *struct{ Version string }
+			}
+			if err := dec.Decode(&mod); err == io.EOF {
+				break
+			} else if err != nil {
+				return errors.Wrap(err, "failed to decode the output of 'go list'")
+			}
+			if mod.Update == nil {
+				continue
+			}
+			outdated = append(outdated, &OutdatedModule{
+				Path:    mod.Path,
+				Version: mod.Version,
+				Latest:  mod.Update.Version,
+				Tools:   tools[mod.Path],
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outdated, nil
+}
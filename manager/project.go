@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/pkg/errors"
+)
+
+// Project represents a gotool.mod which is found by Projects.
+type Project struct {
+	// File is the path of gotool.mod which is relative to the root dir.
+	File string
+	// Manager manages tools of the project.
+	Manager *Manager
+}
+
+// Projects finds all gotool.mod under the root dir, then returns each of them with a manager.
+// The root dir is Dir, or the current dir if Dir is empty.
+// Returned managers share the go command and the tool cacher with m,
+// so identical tools are built only once across projects.
+// Note that Workspace of m is not used by returned managers.
+func (m *Manager) Projects(ctx context.Context) ([]*Project, error) {
+	root := m.Dir
+	if root == "" {
+		var err error
+		root, err = os.Getwd()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the current working dir")
+		}
+	}
+	dirs, err := deptfile.FindAll(root)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get abs path from %s", root)
+	}
+
+	projects := make([]*Project, 0, len(dirs))
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(root, filepath.Join(dir, deptfile.FileName))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the relative path of %s", dir)
+		}
+		projects = append(projects, &Project{
+			File: rel,
+			Manager: &Manager{
+				Dir:         dir,
+				Stdin:       m.Stdin,
+				Stdout:      m.Stdout,
+				Stderr:      m.Stderr,
+				LockTimeout: m.LockTimeout,
+				GoCommand:   m.GoCommand,
+				ToolCacher:  m.ToolCacher,
+				parent:      m,
+			},
+		})
+	}
+	return projects, nil
+}
@@ -0,0 +1,229 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/ktr0731/dept/gocmd"
+	"github.com/ktr0731/dept/logger"
+	"github.com/pkg/errors"
+)
+
+// Remove removes tools which are specified by targets from gotool.mod as follows.
+//
+//   1. load deptfile.
+//   2. check whether the passed tool is managed, or not.
+//   3. generate Go code from updated deptfile.
+//   4. run 'go mod tidy' to remove unnecessary dependencies.
+//   5. check selected versions of the build list are not changed by 4.
+//   6. delete binaries installed by dept and cached binaries of all versions of removed tools.
+//
+// Each target is an import path or an output name of a tool.
+// If Module option is passed, Remove removes all tools of the modules which targets belong to.
+// If some of targets are not managed, Remove returns *ToolNotFoundErr for each target.
+// If 'go mod tidy' changes selected versions of modules which remain in the build list ('go list -m all'),
+// Remove returns *VersionChangedErr
+// without updating gotool.mod unless FullTidy option is passed.
+// Remove returns paths of deleted binaries.
+// Remove uses OutputDir, Module, FullTidy, DryRun, OnChange and Description options.
+// In dry-run mode, Remove doesn't delete any binaries.
+func (m *Manager) Remove(ctx context.Context, targets []string, opts ...RemoveOption) ([]string, error) {
+	o := newOptions(opts)
+	outputDir, err := absOutputDir(o.outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	gocmd := m.gocmd()
+	var removed []*Tool
+	err = m.workspace(false).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		outputDir = resolveOutputDir(projRoot, outputDir)
+
+		var err error
+		removed, err = resolveRemoveTargets(df, targets, o.module)
+		if err != nil {
+			return err
+		}
+
+		removedPaths := make(map[string]bool, len(removed))
+		for _, t := range removed {
+			removedPaths[t.Path] = true
+		}
+		requires := make([]string, 0, len(df.Require))
+		for _, r := range df.Require {
+			forTools(r, func(path string) bool {
+				if !removedPaths[path] {
+					requires = append(requires, path)
+				}
+				return true
+			})
+		}
+
+		fname, err := filegen.GenerateFile(workDir, requires)
+		if err != nil {
+			return errors.Wrap(err, "failed to create a temp file which contains required Go tools in the import statement")
+		}
+		defer os.Remove(fname)
+
+		before, err := buildList(ctx, gocmd, workDir)
+		if err != nil {
+			return err
+		}
+
+		logger.Println("removing unnecessary tools and indirection dependencies")
+		if err := gocmd.ModTidy(ctx, workDir); err != nil {
+			return errors.Wrap(err, "failed to remove the tool from gotool.mod")
+		}
+
+		if o.fullTidy {
+			return nil
+		}
+		after, err := buildList(ctx, gocmd, workDir)
+		if err != nil {
+			return err
+		}
+		if changes := changedVersions(before, after); len(changes) > 0 {
+			return &VersionChangedErr{Changes: changes}
+		}
+		return nil
+	}, o.workspaceOptions("remove "+strings.Join(targets, " "))...)
+	if err != nil {
+		return nil, err
+	}
+	if o.dryRun {
+		return nil, nil
+	}
+	return m.deleteBinaries(ctx, outputDir, removed)
+}
+
+// resolveRemoveTargets finds tools which are specified by targets.
+// If module is true, resolveRemoveTargets returns all tools of the modules which targets belong to.
+func resolveRemoveTargets(df *deptfile.File, targets []string, module bool) ([]*Tool, error) {
+	var tools []*Tool
+	found := map[string]bool{}
+	var merr error
+	for _, target := range targets {
+		repo, _, err := ParsePath(target)
+		if err != nil {
+			return nil, err
+		}
+
+		var matched bool
+		for _, r := range df.Require {
+			var inModule bool
+			forToolsWithOutputName(r, func(path, out string) bool {
+				if path == repo || toolName(path, out) == target {
+					inModule = true
+					if !module && !found[path] {
+						found[path] = true
+						tools = appendTool(tools, df, r, path, out)
+					}
+				}
+				return true
+			})
+			if module && (inModule || r.Path == repo) {
+				inModule = true
+				forToolsWithOutputName(r, func(path, out string) bool {
+					if !found[path] {
+						found[path] = true
+						tools = appendTool(tools, df, r, path, out)
+					}
+					return true
+				})
+			}
+			matched = matched || inModule
+		}
+		if !matched {
+			merr = multierror.Append(merr, &ToolNotFoundErr{Name: target})
+		}
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	return tools, nil
+}
+
+// deleteBinaries deletes installed binaries in outputDir and cached binaries of tools.
+// Binaries in outputDir are deleted only if dept installed them, so the ones which are installed by others are kept.
+// It returns paths of deleted binaries.
+func (m *Manager) deleteBinaries(ctx context.Context, outputDir string, tools []*Tool) ([]string, error) {
+	var deleted []string
+	for _, t := range tools {
+		binPath := filepath.Join(outputDir, t.Name)
+		// Check binaries before removing caches.
+		installed, err := m.isInstalled(ctx, t, binPath)
+		if err != nil {
+			return deleted, err
+		}
+		if !installed {
+			logger.Printf("%s is not installed by dept, skip removing it", binPath)
+			continue
+		}
+		logger.Printf("remove %s", binPath)
+		if err := os.Remove(binPath); err != nil {
+			return deleted, errors.Wrapf(err, "failed to remove %s", binPath)
+		}
+		deleted = append(deleted, binPath)
+	}
+
+	cacher, err := m.toolCacher()
+	if err != nil {
+		return deleted, err
+	}
+	for _, t := range tools {
+		// Remove caches of all versions including old hashes of local dirs.
+		paths, err := cacher.RemoveAll(ctx, t.Path)
+		if err != nil {
+			return deleted, errors.Wrapf(err, "failed to remove caches of %s", t.Path)
+		}
+		deleted = append(deleted, paths...)
+	}
+	return deleted, nil
+}
+
+// buildList returns a map which maps module paths to each selected version in the build list of dir.
+// Not only requirements in go.mod but also modules which are selected indirectly are included,
+// so a module which falls back to a lower version after its requirement is dropped is also detected.
+// The main module is excluded.
+func buildList(ctx context.Context, gocmd gocmd.Command, dir string) (map[string]string, error) {
+	out, err := gocmd.List(ctx, dir, "-m", "all")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the build list")
+	}
+	versions := map[string]string{}
+	sc := bufio.NewScanner(out)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		// The main module has no version.
+		if len(fields) < 2 {
+			continue
+		}
+		versions[fields[0]] = fields[1]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read the build list")
+	}
+	return versions, nil
+}
+
+// changedVersions returns changes of modules which are in both build lists before and after.
+// Modules which are dropped from the build list are no longer used, so they are ignored as well as added ones.
+func changedVersions(before, after map[string]string) []*deptfile.Change {
+	var changes []*deptfile.Change
+	for path, old := range before {
+		if v, ok := after[path]; ok && v != old {
+			changes = append(changes, &deptfile.Change{Path: path, OldVersion: old, NewVersion: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/logger"
+	"github.com/pkg/errors"
+)
+
+// Rename renames the output name of the tool which has oldName to newName.
+// If newName is already used by another tool, Rename returns an error which is caused by deptfile.ErrToolNameConflicted.
+// If oldName is not managed, Rename returns *ToolNotFoundErr.
+//
+// Like Edit, Rename never builds tools.
+// If the binary of the tool in the output dir is installed by dept, Rename also renames it.
+// A binary which has the same name but isn't a copy of the cached build is kept as it is.
+// If the binary is renamed, but a file of the new name already exists, Rename returns an error
+// without updating gotool.mod.
+// Rename uses OutputDir, DryRun and Description options.
+func (m *Manager) Rename(ctx context.Context, oldName, newName string, opts ...RenameOption) error {
+	o := newOptions(opts)
+	outputDir, err := absOutputDir(o.outputDir)
+	if err != nil {
+		return err
+	}
+
+	var oldPath, newPath string
+	var installed bool
+	err = m.edit(o, "rename "+oldName+" "+newName, func(projRoot string, df *deptfile.File) error {
+		outputDir := resolveOutputDir(projRoot, outputDir)
+		t, err := findTool(df, oldName)
+		if err != nil {
+			return err
+		}
+		if err := df.RenameTool(oldName, newName); err != nil {
+			return err
+		}
+		if oldName == newName {
+			return nil
+		}
+
+		oldPath, newPath = filepath.Join(outputDir, oldName), filepath.Join(outputDir, newName)
+		installed, err = m.isInstalled(ctx, t, oldPath)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			return nil
+		}
+		if _, err := os.Stat(newPath); err == nil {
+			return errors.Errorf("failed to rename %s to %s because %s already exists", oldPath, newPath, newPath)
+		}
+		return nil
+	})
+	if err != nil || o.dryRun || oldName == newName {
+		return err
+	}
+	if !installed {
+		logger.Printf("%s is not installed by dept, skip renaming the binary", oldPath)
+		return nil
+	}
+
+	logger.Printf("rename %s to %s", oldPath, newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return errors.Wrapf(err, "failed to rename %s to %s", oldPath, newPath)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"os"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/filegen"
+	"github.com/ktr0731/dept/logger"
+	"github.com/pkg/errors"
+)
+
+// Tidy normalizes and repairs gotool.mod as follows.
+//
+//   1. load deptfile.
+//   2. merge duplicated requires and sort tool paths.
+//   3. generate Go code which imports all managed tools.
+//   4. run 'go mod tidy' to fix indirect requirements and gotool.sum.
+//
+// Tidy returns descriptions of fixes made by 2.
+// Changes made by 4 are passed to OnChange option.
+// If Check option is passed, Tidy doesn't update gotool.mod and returns ErrNotTidy if something would be fixed.
+// Tidy uses Check, DryRun, OnChange and Description options.
+func (m *Manager) Tidy(ctx context.Context, opts ...TidyOption) ([]string, error) {
+	o := newOptions(opts)
+	var changed bool
+	if o.check {
+		o.dryRun = true
+		onChange := o.onChange
+		o.onChange = func(c *deptfile.Changes) {
+			changed = !c.Empty()
+			if onChange != nil {
+				onChange(c)
+			}
+		}
+	}
+
+	gocmd := m.gocmd()
+	var fixes []string
+	err := m.workspace(false).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		fixes = df.Normalize()
+
+		var importPaths []string
+		for _, r := range df.Require {
+			forTools(r, func(path string) bool {
+				importPaths = append(importPaths, path)
+				return true
+			})
+		}
+		fname, err := filegen.GenerateFile(workDir, importPaths)
+		if err != nil {
+			return errors.Wrap(err, "failed to create a temp file which contains required Go tools in the import statement")
+		}
+		defer os.Remove(fname)
+
+		logger.Println("tidying up requirements")
+		if err := gocmd.ModTidy(ctx, workDir); err != nil {
+			return errors.Wrap(err, "failed to tidy up gotool.mod")
+		}
+		return nil
+	}, o.workspaceOptions("tidy")...)
+	if err != nil {
+		return nil, err
+	}
+	if o.check && (changed || len(fixes) > 0) {
+		return fixes, ErrNotTidy
+	}
+	return fixes, nil
+}
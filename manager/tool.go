@@ -0,0 +1,230 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ktr0731/dept/deptfile"
+	"github.com/ktr0731/dept/fileutil"
+	"github.com/ktr0731/dept/logger"
+	"github.com/ktr0731/dept/toolcacher"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Tool represents a tool which is managed by gotool.mod.
+type Tool struct {
+	// Path is the full import path of the tool.
+	Path string
+	// Name is the output name of the tool.
+	Name string
+	// Version is the version of the module which the tool belongs to.
+	Version string
+	// Replace is the replacement of the module like 'path version'.
+	// If the module is replaced by a local dir, it is the absolute path of the dir.
+	// It is empty if the module is not replaced.
+	Replace string
+	// File is the deptfile which has the tool like gotool.local.mod or an included deptfile.
+	// It is relative to the project root, and empty if it is gotool.mod.
+	File string
+}
+
+// cacheVersion returns the version which is used as the cache key of t.
+// If the module is replaced, it also contains the hash of the replacement
+// so that the replaced tool isn't confused with the upstream one.
+// If the module is replaced by a local dir, the version is resolved by c with the hash of the source tree,
+// so the tool is rebuilt when the local code changes.
+func (t *Tool) cacheVersion(ctx context.Context, c toolcacher.Cacher) (string, error) {
+	if t.Replace == "" {
+		return t.Version, nil
+	}
+	if modfile.IsDirectoryPath(t.Replace) {
+		return c.SourceVersion(ctx, t.Path, t.Version, t.Replace)
+	}
+	sum := sha256.Sum256([]byte(t.Replace))
+	return fmt.Sprintf("%s-replaced-%x", t.Version, sum[:6]), nil
+}
+
+// isInstalled returns true if binPath is a copy of a cached binary of t, which means dept installed it.
+// A binary which has the same name but is installed by others, e.g. 'go install', is not touched by dept.
+func (m *Manager) isInstalled(ctx context.Context, t *Tool, binPath string) (bool, error) {
+	if _, err := os.Stat(binPath); err != nil {
+		return false, nil
+	}
+	cacher, err := m.toolCacher()
+	if err != nil {
+		return false, err
+	}
+	cachePaths, err := cacher.List(ctx, t.Path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list caches of %s", t.Path)
+	}
+	for _, p := range cachePaths {
+		same, err := fileutil.SameContent(binPath, p)
+		if err != nil {
+			return false, err
+		}
+		if same {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// List lists up tools.
+// If paths are passed, List lists up only tools which have the passed paths or belong to the passed modules.
+// gotool.local.mod is merged on top of gotool.mod if it exists.
+func (m *Manager) List(ctx context.Context, paths ...string) ([]*Tool, error) {
+	passed := map[string]interface{}{}
+	for _, p := range paths {
+		passed[p] = nil
+	}
+	listAll := len(passed) == 0
+
+	var tools []*Tool
+	err := m.localWorkspace(true).Do(func(projRoot, workDir string, df *deptfile.File) error {
+		tools = make([]*Tool, 0, len(df.Require))
+		for _, r := range df.Require {
+			if !listAll {
+				// If module roots passed, filter by that modules.
+				if _, found := passed[r.Path]; found {
+					forToolsWithOutputName(r, func(path, out string) bool {
+						tools = appendTool(tools, df, r, path, out)
+						return true
+					})
+					continue
+				}
+
+				// If module roots not found, step into each tool.
+			}
+
+			forToolsWithOutputName(r, func(path, out string) bool {
+				if listAll {
+					tools = appendTool(tools, df, r, path, out)
+				} else if _, found := passed[path]; found {
+					tools = appendTool(tools, df, r, path, out)
+				}
+				return true
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tools, nil
+}
+
+func appendTool(tools []*Tool, df *deptfile.File, r *deptfile.Require, path, out string) []*Tool {
+	return append(tools, newTool(df, r, path, toolName(path, out)))
+}
+
+// newTool returns the tool which belongs to r.
+func newTool(df *deptfile.File, r *deptfile.Require, path, name string) *Tool {
+	t := &Tool{Path: path, Name: name, Version: r.Version}
+	if f := df.ToolFile(path); f != deptfile.FileName {
+		t.File = f
+	}
+	if rep := df.LookupReplace(r.Path, r.Version); rep != nil {
+		t.Replace = rep.String()
+		if rep.IsLocal() {
+			t.Replace = rep.Dir()
+		}
+	}
+	return t
+}
+
+// toolName returns the output name of the tool.
+// If out is empty, it means the output name is the same as filepath.Base(path).
+func toolName(path, out string) string {
+	if out != "" {
+		return out
+	}
+	return filepath.Base(path)
+}
+
+// findTool finds the tool which has name as the output name.
+func findTool(df *deptfile.File, name string) (*Tool, error) {
+	for _, r := range df.Require {
+		var t *Tool
+		forToolsWithOutputName(r, func(path, out string) bool {
+			if toolName(path, out) == name {
+				t = newTool(df, r, path, name)
+				return false
+			}
+			return true
+		})
+		if t != nil {
+			return t, nil
+		}
+	}
+	return nil, &ToolNotFoundErr{Name: name}
+}
+
+// ParsePath normalizes the passed path.
+// It trims any schemes like 'https://'.
+// Also, it parse the module version from path.
+// For example,
+//   'https://github.com/ktr0731/itunes-cli/itunes@latest'
+//     repo = 'github.com/ktr0731/itunes-cli/itunes'
+//     ver  = 'latest'
+func ParsePath(path string) (repo, ver string, err error) {
+	var u *url.URL
+	u, err = url.Parse(path)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid Repo passed")
+	}
+
+	path = filepath.Clean(u.Host + u.Path)
+
+	if i := strings.Index(path, "@"); i != -1 {
+		repo = path[:i]
+		ver = path[i+1:]
+	} else {
+		repo = path
+	}
+	return
+}
+
+// forTools iterates r, then pass each tool path to f.
+// Note that, version are ignored.
+func forTools(r *deptfile.Require, f func(path string) bool) {
+	forToolsWithOutputName(r, func(path, _ string) bool {
+		return f(path)
+	})
+}
+
+// forToolsWithOutputName is like forTools, but also pass outputName of each tool.
+// If out is empty, it means out is the same as filepath.Base(path).
+func forToolsWithOutputName(r *deptfile.Require, f func(path, outputName string) bool) {
+	if r == nil {
+		return
+	}
+	for _, t := range r.ToolPaths {
+		p := r.Path
+		if t.Path != "/" {
+			p += t.Path
+		}
+		if ok := f(p, t.Name); !ok {
+			return
+		}
+	}
+}
+
+func resolveOutputDir(projRoot, dir string) string {
+	if dir != "" {
+		return dir
+	}
+	if b := os.Getenv("GOBIN"); b != "" {
+		logger.Printf("output dir = $GOBIN (%s)", b)
+		return b
+	}
+	p := filepath.Join(projRoot, "_tools")
+	logger.Printf("output dir = %s", p)
+	return p
+}
@@ -9,8 +9,12 @@ import (
 )
 
 var (
-	lockCacherMockClear sync.RWMutex
-	lockCacherMockGet   sync.RWMutex
+	lockCacherMockClear         sync.RWMutex
+	lockCacherMockGet           sync.RWMutex
+	lockCacherMockList          sync.RWMutex
+	lockCacherMockRemove        sync.RWMutex
+	lockCacherMockRemoveAll     sync.RWMutex
+	lockCacherMockSourceVersion sync.RWMutex
 )
 
 // CacherMock is a mock implementation of Cacher.
@@ -22,9 +26,21 @@ var (
 //             ClearFunc: func(ctx context.Context) error {
 // 	               panic("mock out the Clear method")
 //             },
-//             GetFunc: func(ctx context.Context, pkgName string, version string) (string, error) {
+//             GetFunc: func(ctx context.Context, dir string, pkgName string, version string) (string, error) {
 // 	               panic("mock out the Get method")
 //             },
+//             ListFunc: func(ctx context.Context, pkgName string) ([]string, error) {
+// 	               panic("mock out the List method")
+//             },
+//             RemoveFunc: func(ctx context.Context, pkgName string, version string) (string, error) {
+// 	               panic("mock out the Remove method")
+//             },
+//             RemoveAllFunc: func(ctx context.Context, pkgName string) ([]string, error) {
+// 	               panic("mock out the RemoveAll method")
+//             },
+//             SourceVersionFunc: func(ctx context.Context, pkgName string, version string, dir string) (string, error) {
+// 	               panic("mock out the SourceVersion method")
+//             },
 //         }
 //
 //         // use mockedCacher in code that requires Cacher
@@ -36,7 +52,19 @@ type CacherMock struct {
 	ClearFunc func(ctx context.Context) error
 
 	// GetFunc mocks the Get method.
-	GetFunc func(ctx context.Context, pkgName string, version string) (string, error)
+	GetFunc func(ctx context.Context, dir string, pkgName string, version string) (string, error)
+
+	// ListFunc mocks the List method.
+	ListFunc func(ctx context.Context, pkgName string) ([]string, error)
+
+	// RemoveFunc mocks the Remove method.
+	RemoveFunc func(ctx context.Context, pkgName string, version string) (string, error)
+
+	// RemoveAllFunc mocks the RemoveAll method.
+	RemoveAllFunc func(ctx context.Context, pkgName string) ([]string, error)
+
+	// SourceVersionFunc mocks the SourceVersion method.
+	SourceVersionFunc func(ctx context.Context, pkgName string, version string, dir string) (string, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -47,12 +75,48 @@ type CacherMock struct {
 		}
 		// Get holds details about calls to the Get method.
 		Get []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dir is the dir argument value.
+			Dir string
+			// PkgName is the pkgName argument value.
+			PkgName string
+			// Version is the version argument value.
+			Version string
+		}
+		// List holds details about calls to the List method.
+		List []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// PkgName is the pkgName argument value.
+			PkgName string
+		}
+		// Remove holds details about calls to the Remove method.
+		Remove []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// PkgName is the pkgName argument value.
+			PkgName string
+			// Version is the version argument value.
+			Version string
+		}
+		// RemoveAll holds details about calls to the RemoveAll method.
+		RemoveAll []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// PkgName is the pkgName argument value.
+			PkgName string
+		}
+		// SourceVersion holds details about calls to the SourceVersion method.
+		SourceVersion []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// PkgName is the pkgName argument value.
 			PkgName string
 			// Version is the version argument value.
 			Version string
+			// Dir is the dir argument value.
+			Dir string
 		}
 	}
 }
@@ -89,23 +153,25 @@ func (mock *CacherMock) ClearCalls() []struct {
 }
 
 // Get calls GetFunc.
-func (mock *CacherMock) Get(ctx context.Context, pkgName string, version string) (string, error) {
+func (mock *CacherMock) Get(ctx context.Context, dir string, pkgName string, version string) (string, error) {
 	if mock.GetFunc == nil {
 		panic("CacherMock.GetFunc: method is nil but Cacher.Get was just called")
 	}
 	callInfo := struct {
 		Ctx     context.Context
+		Dir     string
 		PkgName string
 		Version string
 	}{
 		Ctx:     ctx,
+		Dir:     dir,
 		PkgName: pkgName,
 		Version: version,
 	}
 	lockCacherMockGet.Lock()
 	mock.calls.Get = append(mock.calls.Get, callInfo)
 	lockCacherMockGet.Unlock()
-	return mock.GetFunc(ctx, pkgName, version)
+	return mock.GetFunc(ctx, dir, pkgName, version)
 }
 
 // GetCalls gets all the calls that were made to Get.
@@ -113,11 +179,13 @@ func (mock *CacherMock) Get(ctx context.Context, pkgName string, version string)
 //     len(mockedCacher.GetCalls())
 func (mock *CacherMock) GetCalls() []struct {
 	Ctx     context.Context
+	Dir     string
 	PkgName string
 	Version string
 } {
 	var calls []struct {
 		Ctx     context.Context
+		Dir     string
 		PkgName string
 		Version string
 	}
@@ -126,3 +194,155 @@ func (mock *CacherMock) GetCalls() []struct {
 	lockCacherMockGet.RUnlock()
 	return calls
 }
+
+// List calls ListFunc.
+func (mock *CacherMock) List(ctx context.Context, pkgName string) ([]string, error) {
+	if mock.ListFunc == nil {
+		panic("CacherMock.ListFunc: method is nil but Cacher.List was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		PkgName string
+	}{
+		Ctx:     ctx,
+		PkgName: pkgName,
+	}
+	lockCacherMockList.Lock()
+	mock.calls.List = append(mock.calls.List, callInfo)
+	lockCacherMockList.Unlock()
+	return mock.ListFunc(ctx, pkgName)
+}
+
+// ListCalls gets all the calls that were made to List.
+// Check the length with:
+//     len(mockedCacher.ListCalls())
+func (mock *CacherMock) ListCalls() []struct {
+	Ctx     context.Context
+	PkgName string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		PkgName string
+	}
+	lockCacherMockList.RLock()
+	calls = mock.calls.List
+	lockCacherMockList.RUnlock()
+	return calls
+}
+
+// Remove calls RemoveFunc.
+func (mock *CacherMock) Remove(ctx context.Context, pkgName string, version string) (string, error) {
+	if mock.RemoveFunc == nil {
+		panic("CacherMock.RemoveFunc: method is nil but Cacher.Remove was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		PkgName string
+		Version string
+	}{
+		Ctx:     ctx,
+		PkgName: pkgName,
+		Version: version,
+	}
+	lockCacherMockRemove.Lock()
+	mock.calls.Remove = append(mock.calls.Remove, callInfo)
+	lockCacherMockRemove.Unlock()
+	return mock.RemoveFunc(ctx, pkgName, version)
+}
+
+// RemoveCalls gets all the calls that were made to Remove.
+// Check the length with:
+//     len(mockedCacher.RemoveCalls())
+func (mock *CacherMock) RemoveCalls() []struct {
+	Ctx     context.Context
+	PkgName string
+	Version string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		PkgName string
+		Version string
+	}
+	lockCacherMockRemove.RLock()
+	calls = mock.calls.Remove
+	lockCacherMockRemove.RUnlock()
+	return calls
+}
+
+// RemoveAll calls RemoveAllFunc.
+func (mock *CacherMock) RemoveAll(ctx context.Context, pkgName string) ([]string, error) {
+	if mock.RemoveAllFunc == nil {
+		panic("CacherMock.RemoveAllFunc: method is nil but Cacher.RemoveAll was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		PkgName string
+	}{
+		Ctx:     ctx,
+		PkgName: pkgName,
+	}
+	lockCacherMockRemoveAll.Lock()
+	mock.calls.RemoveAll = append(mock.calls.RemoveAll, callInfo)
+	lockCacherMockRemoveAll.Unlock()
+	return mock.RemoveAllFunc(ctx, pkgName)
+}
+
+// RemoveAllCalls gets all the calls that were made to RemoveAll.
+// Check the length with:
+//     len(mockedCacher.RemoveAllCalls())
+func (mock *CacherMock) RemoveAllCalls() []struct {
+	Ctx     context.Context
+	PkgName string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		PkgName string
+	}
+	lockCacherMockRemoveAll.RLock()
+	calls = mock.calls.RemoveAll
+	lockCacherMockRemoveAll.RUnlock()
+	return calls
+}
+
+// SourceVersion calls SourceVersionFunc.
+func (mock *CacherMock) SourceVersion(ctx context.Context, pkgName string, version string, dir string) (string, error) {
+	if mock.SourceVersionFunc == nil {
+		panic("CacherMock.SourceVersionFunc: method is nil but Cacher.SourceVersion was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		PkgName string
+		Version string
+		Dir     string
+	}{
+		Ctx:     ctx,
+		PkgName: pkgName,
+		Version: version,
+		Dir:     dir,
+	}
+	lockCacherMockSourceVersion.Lock()
+	mock.calls.SourceVersion = append(mock.calls.SourceVersion, callInfo)
+	lockCacherMockSourceVersion.Unlock()
+	return mock.SourceVersionFunc(ctx, pkgName, version, dir)
+}
+
+// SourceVersionCalls gets all the calls that were made to SourceVersion.
+// Check the length with:
+//     len(mockedCacher.SourceVersionCalls())
+func (mock *CacherMock) SourceVersionCalls() []struct {
+	Ctx     context.Context
+	PkgName string
+	Version string
+	Dir     string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		PkgName string
+		Version string
+		Dir     string
+	}
+	lockCacherMockSourceVersion.RLock()
+	calls = mock.calls.SourceVersion
+	lockCacherMockSourceVersion.RUnlock()
+	return calls
+}
@@ -3,17 +3,23 @@
 package toolcacher
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/ktr0731/dept/gocmd"
 	"github.com/ktr0731/dept/logger"
 	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
 )
 
 var (
@@ -22,20 +28,36 @@ var (
 
 type Cacher interface {
 	// Get finds a cached tool path which satisfies the passed pkgName and version.
-	// If it is not cached, Get builds a new one.
-	Get(ctx context.Context, pkgName, version string) (path string, err error)
+	// If it is not cached, Get builds a new one in dir which has go.mod.
+	Get(ctx context.Context, dir, pkgName, version string) (path string, err error)
+	// Remove removes the cached tool which satisfies the passed pkgName and version.
+	// Remove returns the path of the removed cache. If it is not cached, path is empty.
+	Remove(ctx context.Context, pkgName, version string) (path string, err error)
+	// List returns paths of all cached tools which have the passed pkgName regardless of versions.
+	List(ctx context.Context, pkgName string) (paths []string, err error)
+	// RemoveAll removes all cached tools which have the passed pkgName regardless of versions.
+	// RemoveAll returns paths of removed caches.
+	RemoveAll(ctx context.Context, pkgName string) (paths []string, err error)
+	// SourceVersion returns the version of the tool which is built from the local dir.
+	// It contains the hash of the source tree, so the tool is rebuilt when the source is changed.
+	// The last hash is stored with the fingerprint of the source tree, so files are read only when
+	// the fingerprint is changed, and the cache of the last hash is removed because it is never used again.
+	SourceVersion(ctx context.Context, pkgName, version, dir string) (string, error)
 	// Clear removes all cached tools.
 	Clear(ctx context.Context) error
 }
 
 type cacher struct {
-	gocmd        gocmd.Command
-	rootPath     string
-	downloadOnce sync.Once
+	gocmd    gocmd.Command
+	rootPath string
+
+	mu sync.Mutex
+	// downloadOnce runs 'go mod download' once for each dir.
+	downloadOnce map[string]*sync.Once
 }
 
 func New(gocmd gocmd.Command) (Cacher, error) {
-	r, err := gocmd.Env(context.Background(), "GOPATH")
+	r, err := gocmd.Env(context.Background(), "", "GOPATH")
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get $GOPATH")
 	}
@@ -51,8 +73,9 @@ func New(gocmd gocmd.Command) (Cacher, error) {
 		}
 	}
 	return &cacher{
-		gocmd:    gocmd,
-		rootPath: rootPath,
+		gocmd:        gocmd,
+		rootPath:     rootPath,
+		downloadOnce: map[string]*sync.Once{},
 	}, nil
 }
 
@@ -67,7 +90,7 @@ func (c *cacher) find(key string) (string, error) {
 	return key, nil
 }
 
-func (c *cacher) Get(ctx context.Context, pkgName, version string) (string, error) {
+func (c *cacher) Get(ctx context.Context, dir, pkgName, version string) (string, error) {
 	outPath := c.cachePath(pkgName, version)
 	cachePath, err := c.find(outPath)
 	if err == nil {
@@ -79,9 +102,9 @@ func (c *cacher) Get(ctx context.Context, pkgName, version string) (string, erro
 		logger.Printf("cache passed tool: %s %s", outPath, pkgName)
 
 		var err error
-		c.downloadOnce.Do(func() {
+		c.once(dir).Do(func() {
 			logger.Println("downloading modules")
-			if err = c.gocmd.ModDownload(ctx); err != nil {
+			if err = c.gocmd.ModDownload(ctx, dir); err != nil {
 				err = errors.Wrap(err, "failed to download module dependencies")
 			}
 		})
@@ -89,7 +112,7 @@ func (c *cacher) Get(ctx context.Context, pkgName, version string) (string, erro
 			return "", err
 		}
 
-		if err := c.gocmd.Build(ctx, "-o", outPath, pkgName); err != nil {
+		if err := c.gocmd.Build(ctx, dir, "-o", outPath, pkgName); err != nil {
 			return "", errors.Wrapf(err, "failed to cache tool %s to %s", pkgName, outPath)
 		}
 		return outPath, nil
@@ -97,6 +120,160 @@ func (c *cacher) Get(ctx context.Context, pkgName, version string) (string, erro
 	return "", errors.Wrap(err, "failed to find the passed tool")
 }
 
+func (c *cacher) once(dir string) *sync.Once {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.downloadOnce[dir]
+	if !ok {
+		o = &sync.Once{}
+		c.downloadOnce[dir] = o
+	}
+	return o
+}
+
+func (c *cacher) Remove(ctx context.Context, pkgName, version string) (string, error) {
+	cachePath, err := c.find(c.cachePath(pkgName, version))
+	if err == errCacheMiss {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	logger.Printf("remove %s", cachePath)
+	if err := os.Remove(cachePath); err != nil {
+		return "", errors.Wrapf(err, "failed to remove the cache of %s", pkgName)
+	}
+	return cachePath, nil
+}
+
+func (c *cacher) List(ctx context.Context, pkgName string) ([]string, error) {
+	all, err := c.findAll(pkgName)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range all {
+		if !strings.HasSuffix(p, sourceStateExt) {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+func (c *cacher) RemoveAll(ctx context.Context, pkgName string) ([]string, error) {
+	all, err := c.findAll(pkgName)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, p := range all {
+		logger.Printf("remove %s", p)
+		if err := os.Remove(p); err != nil {
+			return removed, errors.Wrapf(err, "failed to remove the cache of %s", pkgName)
+		}
+		if !strings.HasSuffix(p, sourceStateExt) {
+			removed = append(removed, p)
+		}
+	}
+	return removed, nil
+}
+
+// findAll returns paths of all cache files of pkgName including source states.
+func (c *cacher) findAll(pkgName string) ([]string, error) {
+	prefix := escapePkgName(pkgName) + "-"
+	fis, err := ioutil.ReadDir(c.rootPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the cache dir")
+	}
+	var paths []string
+	for _, fi := range fis {
+		name := fi.Name()
+		// Tools which are under pkgName like pkgName/sub also have the prefix,
+		// so check the rest of the name is a version.
+		if strings.HasPrefix(name, prefix) && isCacheVersion(strings.TrimPrefix(name, prefix)) {
+			paths = append(paths, filepath.Join(c.rootPath, name))
+		}
+	}
+	return paths, nil
+}
+
+// sourceStateExt is the extension of files which have the last hash of the source tree.
+const sourceStateExt = ".src"
+
+func (c *cacher) SourceVersion(ctx context.Context, pkgName, version, dir string) (string, error) {
+	statePath := c.cachePath(pkgName, version) + sourceStateExt
+	// The state file has the fingerprint and the hash in the first line,
+	// and //go:embed patterns in the following lines.
+	var lastFP, lastHash string
+	var patterns []string
+	if b, err := ioutil.ReadFile(statePath); err == nil {
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if sp := strings.Fields(lines[0]); len(sp) == 2 && len(sp[1]) >= 12 {
+			lastFP, lastHash, patterns = sp[0], sp[1], lines[1:]
+		}
+	}
+	files, err := sourceFiles(dir, patterns)
+	if err != nil {
+		return "", err
+	}
+	fp, err := fingerprintFiles(dir, files)
+	if err != nil {
+		return "", err
+	}
+	if fp == lastFP {
+		return sourceVersion(version, lastHash), nil
+	}
+
+	// Some files are changed, so patterns may be also changed.
+	patterns, err = embedPatterns(dir)
+	if err != nil {
+		return "", err
+	}
+	files, err = sourceFiles(dir, patterns)
+	if err != nil {
+		return "", err
+	}
+	// Take the fingerprint before hashing, so files which are changed while hashing are detected next time.
+	fp, err = fingerprintFiles(dir, files)
+	if err != nil {
+		return "", err
+	}
+	h, err := hashFiles(dir, files)
+	if err != nil {
+		return "", err
+	}
+	if lastHash != "" && lastHash != h {
+		// ignore errors because the stale cache may be already removed.
+		if p, err := c.Remove(ctx, pkgName, sourceVersion(version, lastHash)); err == nil && p != "" {
+			logger.Printf("removed the stale cache %s", p)
+		}
+	}
+	state := strings.Join(append([]string{fp + " " + h}, patterns...), "\n") + "\n"
+	if err := ioutil.WriteFile(statePath, []byte(state), 0644); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s", statePath)
+	}
+	return sourceVersion(version, h), nil
+}
+
+// sourceVersion returns the version which is built from the source tree which has hash h.
+func sourceVersion(version, h string) string {
+	return fmt.Sprintf("%s-src-%s", version, h[:12])
+}
+
+// isCacheVersion returns true if s is the version part of names of cache files.
+func isCacheVersion(s string) bool {
+	s = strings.TrimSuffix(s, sourceStateExt)
+	for _, sep := range []string{"-src-", "-replaced-"} {
+		if i := strings.LastIndex(s, sep); i != -1 {
+			s = s[:i]
+		}
+	}
+	return semver.IsValid(s)
+}
+
 func (c *cacher) Clear(ctx context.Context) error {
 	logger.Printf("remove %s", c.rootPath)
 	err := os.RemoveAll(c.rootPath)
@@ -112,6 +289,204 @@ func (c *cacher) cachePath(pkgName, version string) string {
 	}
 	return filepath.Join(
 		c.rootPath,
-		fmt.Sprintf("%s-%s", strings.Replace(pkgName, "/", "-", -1), version),
+		fmt.Sprintf("%s-%s", escapePkgName(pkgName), version),
 	)
 }
+
+func escapePkgName(pkgName string) string {
+	return strings.Replace(pkgName, "/", "-", -1)
+}
+
+// HashDir returns the hash of the source tree in dir.
+// Only files which affect the build are hashed, i.e. source files of packages, go.mod, go.sum and embedded files.
+// Like the go command, dirs which begin with '.' or '_', vendor, testdata and nested modules are ignored.
+// Other files like binaries in the output dir are also ignored.
+// HashDir is used as a part of the cache key of tools which are built from local dirs.
+func HashDir(dir string) (string, error) {
+	patterns, err := embedPatterns(dir)
+	if err != nil {
+		return "", err
+	}
+	files, err := sourceFiles(dir, patterns)
+	if err != nil {
+		return "", err
+	}
+	return hashFiles(dir, files)
+}
+
+func hashFiles(dir string, files []string) (string, error) {
+	h := sha256.New()
+	for _, rel := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to hash the source tree in %s", dir)
+		}
+		fmt.Fprintf(h, "%s %x\n", rel, sha256.Sum256(b))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// fingerprintFiles returns the hash of paths, sizes and modification times of files in dir.
+// Unlike hashFiles, it doesn't read files.
+func fingerprintFiles(dir string, files []string) (string, error) {
+	h := sha256.New()
+	for _, rel := range files {
+		fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get the fingerprint of the source tree in %s", dir)
+		}
+		fmt.Fprintf(h, "%s %d %d\n", rel, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// sourceExts is extensions of files which are compiled by the go command.
+var sourceExts = map[string]bool{
+	".go": true, ".s": true, ".S": true, ".sx": true, ".syso": true,
+	".c": true, ".cc": true, ".cpp": true, ".cxx": true, ".m": true,
+	".h": true, ".hh": true, ".hpp": true, ".hxx": true,
+	".f": true, ".F": true, ".for": true, ".f90": true,
+	".swig": true, ".swigcxx": true,
+}
+
+// sourceFiles returns sorted slash-separated paths which are relative to dir of files which affect the build
+// of the module in dir. Embedded files are resolved by patterns which are returned by embedPatterns.
+func sourceFiles(dir string, patterns []string) ([]string, error) {
+	found := map[string]bool{}
+	err := walkPackages(dir, func(rel string) {
+		name := path.Base(rel)
+		if rel == "go.mod" || rel == "go.sum" || (sourceExts[path.Ext(name)] && !strings.HasSuffix(name, "_test.go")) {
+			found[rel] = true
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list source files in %s", dir)
+	}
+
+	for _, pattern := range patterns {
+		// Invalid patterns are reported by the go command.
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		for _, m := range matches {
+			err := filepath.Walk(m, func(p string, info os.FileInfo, err error) error {
+				if err != nil || !info.Mode().IsRegular() {
+					return err
+				}
+				rel, err := filepath.Rel(dir, p)
+				if err != nil {
+					return err
+				}
+				found[filepath.ToSlash(rel)] = true
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list embedded files in %s", dir)
+			}
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for f := range found {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// embedPatterns returns patterns of //go:embed directives in Go files of the module in dir.
+// Each pattern is joined with the slash-separated package dir which is relative to dir.
+func embedPatterns(dir string) ([]string, error) {
+	var goFiles []string
+	err := walkPackages(dir, func(rel string) {
+		if strings.HasSuffix(rel, ".go") && !strings.HasSuffix(rel, "_test.go") {
+			goFiles = append(goFiles, rel)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list source files in %s", dir)
+	}
+
+	var patterns []string
+	for _, rel := range goFiles {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", rel)
+		}
+		if !bytes.Contains(b, []byte("//go:embed")) {
+			continue
+		}
+		for _, l := range strings.Split(string(b), "\n") {
+			l = strings.TrimSpace(l)
+			if !strings.HasPrefix(l, "//go:embed ") && !strings.HasPrefix(l, "//go:embed\t") {
+				continue
+			}
+			for _, p := range splitEmbedPatterns(strings.TrimPrefix(l, "//go:embed")) {
+				patterns = append(patterns, path.Join(path.Dir(rel), strings.TrimPrefix(p, "all:")))
+			}
+		}
+	}
+	return patterns, nil
+}
+
+// splitEmbedPatterns splits s which is the argument of a //go:embed directive into patterns.
+// Like the go command, each pattern may be quoted by '"' or '`'.
+func splitEmbedPatterns(s string) []string {
+	var patterns []string
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return patterns
+		}
+		var p string
+		switch s[0] {
+		case '"', '`':
+			i := strings.IndexByte(s[1:], s[0])
+			if i == -1 {
+				return patterns
+			}
+			p, s = s[:i+2], s[i+2:]
+			if uq, err := strconv.Unquote(p); err == nil {
+				p = uq
+			}
+		default:
+			i := strings.IndexAny(s, " \t")
+			if i == -1 {
+				i = len(s)
+			}
+			p, s = s[:i], s[i:]
+		}
+		patterns = append(patterns, p)
+	}
+}
+
+// walkPackages calls f with the slash-separated relative path of each regular file in dirs of packages of
+// the module in dir.
+func walkPackages(dir string, f func(rel string)) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if p == dir {
+				return nil
+			}
+			name := info.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" {
+				return filepath.SkipDir
+			}
+			// Nested modules are not a part of the module.
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f(filepath.ToSlash(rel))
+		return nil
+	})
+}
@@ -22,13 +22,13 @@ func setup(t *testing.T) (toolcacher.Cacher, *gocmd.CommandMock, func()) {
 	}
 
 	gocmd := &gocmd.CommandMock{
-		EnvFunc: func(ctx context.Context, args ...string) (io.Reader, error) {
+		EnvFunc: func(ctx context.Context, _ string, args ...string) (io.Reader, error) {
 			return strings.NewReader(dir), nil
 		},
-		BuildFunc: func(ctx context.Context, args ...string) error {
+		BuildFunc: func(ctx context.Context, dir string, args ...string) error {
 			return nil
 		},
-		ModDownloadFunc: func(ctx context.Context) error {
+		ModDownloadFunc: func(ctx context.Context, dir string) error {
 			return nil
 		},
 	}
@@ -54,7 +54,7 @@ func TestCacher(t *testing.T) {
 
 		pkgName := "github.com/hoge/fuga/foo"
 		version := "v0.1.0"
-		cachePath, err := tc.Get(context.Background(), pkgName, version)
+		cachePath, err := tc.Get(context.Background(), "", pkgName, version)
 		if err != nil {
 			t.Fatalf("Get must not return any errors, but got '%s'", err)
 		}
@@ -76,7 +76,7 @@ func TestCacher(t *testing.T) {
 		}
 		defer f.Close()
 
-		cachePath2, err := tc.Get(context.Background(), pkgName, version)
+		cachePath2, err := tc.Get(context.Background(), "", pkgName, version)
 		if err != nil {
 			t.Fatalf("Get must not return any errors, but got %s", err)
 		}
@@ -112,16 +112,188 @@ func TestCacher(t *testing.T) {
 					}
 				}()
 
-				_, _ = tc.Get(context.Background(), "", "")
+				_, _ = tc.Get(context.Background(), "", "", "")
 			})
 		}
 	})
 
+	t.Run("Remove removes the cached tool", func(t *testing.T) {
+		tc, gocmd, cleanup := setup(t)
+		defer cleanup()
+
+		pkgName := "github.com/hoge/fuga/bar"
+		version := "v0.1.0"
+		removed, err := tc.Remove(context.Background(), pkgName, version)
+		if err != nil {
+			t.Fatalf("Remove must not return any errors, but got '%s'", err)
+		}
+		if removed != "" {
+			t.Errorf("Remove must return an empty path if the tool is not cached, but got '%s'", removed)
+		}
+
+		if _, err := tc.Get(context.Background(), "", pkgName, version); err != nil {
+			t.Fatalf("Get must not return any errors, but got '%s'", err)
+		}
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		outputPath := fs.String("o", "", "")
+		fs.Parse(gocmd.BuildCalls()[0].Args)
+		if err := ioutil.WriteFile(*outputPath, nil, 0755); err != nil {
+			t.Fatalf("failed to create a pseudo binary file: %s", err)
+		}
+
+		removed, err = tc.Remove(context.Background(), pkgName, version)
+		if err != nil {
+			t.Fatalf("Remove must not return any errors, but got '%s'", err)
+		}
+		if removed != *outputPath {
+			t.Errorf("Remove must return '%s', but got '%s'", *outputPath, removed)
+		}
+		if _, err := os.Stat(*outputPath); !os.IsNotExist(err) {
+			t.Errorf("Remove must remove %s, but got err = %v", *outputPath, err)
+		}
+	})
+
+	t.Run("List and RemoveAll find caches of all versions of the tool", func(t *testing.T) {
+		tc, gocmd, cleanup := setup(t)
+		defer cleanup()
+
+		srcDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(srcDir)
+		if err := ioutil.WriteFile(filepath.Join(srcDir, "main.go"), []byte("package main"), 0644); err != nil {
+			t.Fatalf("failed to write main.go: %s", err)
+		}
+
+		pkgName := "github.com/hoge/fuga/baz"
+		srcVersion, err := tc.SourceVersion(context.Background(), pkgName, "v0.0.0", srcDir)
+		if err != nil {
+			t.Fatalf("SourceVersion must not return any errors, but got '%s'", err)
+		}
+		var paths []string
+		for _, c := range []struct{ pkgName, version string }{
+			{pkgName, "v0.1.0"},
+			{pkgName, "v0.2.0"},
+			{pkgName, srcVersion},
+			{pkgName + "/sub", "v0.1.0"},
+			{pkgName + "/v2", "v2.0.0"},
+		} {
+			if _, err := tc.Get(context.Background(), "", c.pkgName, c.version); err != nil {
+				t.Fatalf("Get must not return any errors, but got '%s'", err)
+			}
+			fs := flag.NewFlagSet("test", flag.ExitOnError)
+			outputPath := fs.String("o", "", "")
+			fs.Parse(gocmd.BuildCalls()[len(gocmd.BuildCalls())-1].Args)
+			if err := ioutil.WriteFile(*outputPath, nil, 0755); err != nil {
+				t.Fatalf("failed to create a pseudo binary file: %s", err)
+			}
+			paths = append(paths, *outputPath)
+		}
+
+		listed, err := tc.List(context.Background(), pkgName)
+		if err != nil {
+			t.Fatalf("List must not return any errors, but got '%s'", err)
+		}
+		if len(listed) != 3 {
+			t.Errorf("List must return 3 caches, but got %v", listed)
+		}
+
+		removed, err := tc.RemoveAll(context.Background(), pkgName)
+		if err != nil {
+			t.Fatalf("RemoveAll must not return any errors, but got '%s'", err)
+		}
+		if len(removed) != 3 {
+			t.Errorf("RemoveAll must remove 3 caches, but got %v", removed)
+		}
+		for _, p := range paths[:3] {
+			if _, err := os.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("RemoveAll must remove %s, but got err = %v", p, err)
+			}
+		}
+		for _, p := range paths[3:] {
+			if _, err := os.Stat(p); err != nil {
+				t.Errorf("RemoveAll must not remove caches of other tools, but got err = %v", err)
+			}
+		}
+		state := strings.TrimSuffix(paths[0], "v0.1.0") + "v0.0.0.src"
+		if _, err := os.Stat(state); !os.IsNotExist(err) {
+			t.Errorf("RemoveAll must remove %s, but got err = %v", state, err)
+		}
+	})
+
+	t.Run("SourceVersion changes with the source and evicts the stale cache", func(t *testing.T) {
+		tc, gocmd, cleanup := setup(t)
+		defer cleanup()
+
+		srcDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(srcDir)
+		write := func(name, content string) {
+			p := filepath.Join(srcDir, name)
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatalf("failed to create a dir: %s", err)
+			}
+			if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %s", name, err)
+			}
+		}
+		sourceVersion := func() string {
+			v, err := tc.SourceVersion(context.Background(), "github.com/hoge/fuga/gen", "v0.0.0", srcDir)
+			if err != nil {
+				t.Fatalf("SourceVersion must not return any errors, but got '%s'", err)
+			}
+			return v
+		}
+
+		write("main.go", "package main\n\n//go:embed static\nvar static embed.FS\n")
+		write(filepath.Join("static", "index.html"), "index")
+		v1 := sourceVersion()
+		if _, err := tc.Get(context.Background(), "", "github.com/hoge/fuga/gen", v1); err != nil {
+			t.Fatalf("Get must not return any errors, but got '%s'", err)
+		}
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		outputPath := fs.String("o", "", "")
+		fs.Parse(gocmd.BuildCalls()[0].Args)
+		if err := ioutil.WriteFile(*outputPath, nil, 0755); err != nil {
+			t.Fatalf("failed to create a pseudo binary file: %s", err)
+		}
+		if v := sourceVersion(); v != v1 {
+			t.Errorf("SourceVersion must return the same version if the source is not changed, but got %s and %s", v1, v)
+		}
+
+		// The output dir and the lock file of dept in the module root don't affect the build.
+		write(filepath.Join("bin", "gen"), "binary")
+		write("gotool.mod.lock", "1234")
+		if v := sourceVersion(); v != v1 {
+			t.Errorf("SourceVersion must ignore files which don't affect the build, but got %s and %s", v1, v)
+		}
+		if _, err := os.Stat(*outputPath); err != nil {
+			t.Errorf("the cache must be kept: %s", err)
+		}
+
+		write(filepath.Join("static", "index.html"), "new index")
+		v2 := sourceVersion()
+		if v2 == v1 {
+			t.Errorf("SourceVersion must return another version if an embedded file is changed, but got %s", v2)
+		}
+		if _, err := os.Stat(*outputPath); !os.IsNotExist(err) {
+			t.Errorf("SourceVersion must remove the stale cache %s, but got err = %v", *outputPath, err)
+		}
+
+		write("main.go", "package main\n\nfunc main() {}\n")
+		if v := sourceVersion(); v == v2 {
+			t.Errorf("SourceVersion must return another version if the source is changed, but got %s", v)
+		}
+	})
+
 	t.Run("Clear removes the cache dir", func(t *testing.T) {
 		tc, gocmd, cleanup := setup(t)
 		defer cleanup()
 
-		e, _ := gocmd.Env(context.Background())
+		e, _ := gocmd.Env(context.Background(), "")
 		b, _ := ioutil.ReadAll(e)
 		gopath := string(b)
 
@@ -136,3 +308,72 @@ func TestCacher(t *testing.T) {
 		}
 	})
 }
+
+func TestHashDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create a dir: %s", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	hash := func() string {
+		h, err := toolcacher.HashDir(dir)
+		if err != nil {
+			t.Fatalf("HashDir must not return an error, but got '%s'", err)
+		}
+		return h
+	}
+
+	write("main.go", "package main")
+	h := hash()
+	if h != hash() {
+		t.Error("HashDir must return the same hash for the same source tree")
+	}
+
+	write(filepath.Join(".git", "HEAD"), "ref: refs/heads/master")
+	write(filepath.Join("_tools", "foo"), "foo")
+	if h != hash() {
+		t.Error("HashDir must ignore dirs which begin with '.' or '_'")
+	}
+
+	write(filepath.Join("vendor", "example.com", "foo", "foo.go"), "package foo")
+	write(filepath.Join("testdata", "data.go"), "package data")
+	write(filepath.Join("nested", "go.mod"), "module nested")
+	write(filepath.Join("nested", "nested.go"), "package nested")
+	if h != hash() {
+		t.Error("HashDir must ignore vendor, testdata and nested modules")
+	}
+
+	write(filepath.Join("bin", "tool"), "binary")
+	write("main_test.go", "package main")
+	write("README.md", "# README")
+	if h != hash() {
+		t.Error("HashDir must ignore files which don't affect the build")
+	}
+
+	write(filepath.Join("sub", "sub.go"), "package sub")
+	if h == hash() {
+		t.Error("HashDir must return another hash if the source tree is changed")
+	}
+
+	write(filepath.Join("sub", "sub.go"), "package sub\n\n//go:embed \"a b.txt\" data/*.json\nvar data embed.FS\n")
+	h = hash()
+	write(filepath.Join("sub", "a b.txt"), "a")
+	if h == hash() {
+		t.Error("HashDir must hash embedded files")
+	}
+	h = hash()
+	write(filepath.Join("sub", "data", "x.json"), "{}")
+	if h == hash() {
+		t.Error("HashDir must hash files which match embed patterns")
+	}
+}